@@ -11,12 +11,18 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/graph"
+	"yavam/pkg/jobs"
 	"yavam/pkg/manager"
 	"yavam/pkg/models"
+	"yavam/pkg/scans"
+	"yavam/pkg/search"
 	"yavam/pkg/services/auth"
 	"yavam/pkg/services/config"
 	"yavam/pkg/updater"
 	"yavam/pkg/utils"
+	"yavam/pkg/watcher"
 
 	"yavam/pkg/server"
 
@@ -50,10 +56,17 @@ type App struct {
 	trayRunning         bool
 	pendingFactoryReset bool // Flag to trigger wipe on restart
 
-	// Scan Cancellation
-	scanMu     sync.Mutex
-	scanCancel context.CancelFunc
-	scanWg     sync.WaitGroup
+	// Live library watcher, nil while watching is disabled. Library changes arrive on the event bus,
+	// so it is guarded as well.
+	watcherMu sync.Mutex
+	watcher   *watcher.Watcher
+
+	// Desktop subscription to the shared scan of the library shown in the UI
+	scanMu  sync.Mutex
+	scanSub *scans.Subscription
+
+	// Removes the event bus subscription registered at startup
+	unsubscribeEvents func()
 }
 
 // NewApp creates a new App application struct
@@ -105,12 +118,21 @@ func (a *App) startup(ctx context.Context) {
 		// But in prod we might just log and fallback to memory?
 		// Let's print for now
 		log.Printf("[App] Failed to initialize auth service: %v\n", authErr)
+	} else {
+		cfg := a.manager.GetConfig()
+		a.auth.SetSessionPolicy(auth.SessionPolicyFromDays(cfg.SessionMaxAgeDays, cfg.SessionIdleDays))
 	}
 
 	a.server = server.NewServer(ctx, a.manager, a.auth, subAssets, a.GetAppVersion(), func() {
 		runtime.WindowShow(ctx)
 	})
 
+	// Keep UI and web clients current without full rescans
+	a.startWatcher()
+
+	// Every change and job update for the desktop UI (web clients get the same through SSE)
+	a.unsubscribeEvents = a.manager.Events().Subscribe(a.onEvent)
+
 	// Check Server Config
 	cfg := a.manager.GetConfig()
 	if cfg.ServerEnabled {
@@ -142,6 +164,14 @@ func (a *App) GetPackageContents(pkgPath string) ([]models.PackageContent, error
 	return a.manager.GetPackageContents(pkgPath)
 }
 
+// GetPackageDetails returns the contents of a package and the package references found in its content JSON
+func (a *App) GetPackageDetails(pkgPath string) (*models.PackageDetails, error) {
+	if err := a.manager.ValidatePath(pkgPath); err != nil {
+		return nil, err
+	}
+	return a.manager.GetPackageDetails(pkgPath)
+}
+
 // GetPackageThumbnail returns the Base64 encoded thumbnail for a package
 func (a *App) GetPackageThumbnail(pkgPath string) (string, error) {
 	if err := a.manager.ValidatePath(pkgPath); err != nil {
@@ -175,14 +205,21 @@ func (a *App) DeleteFileToRecycleBin(path string) error {
 // CopyPackagesToLibrary copies a list of package files to a destination library
 // Returns list of collided filenames (if overwrite=false) or error
 func (a *App) CopyPackagesToLibrary(filePaths []string, destLibPath string, overwrite bool) ([]string, error) {
-	return a.manager.CopyPackagesToLibrary(filePaths, destLibPath, overwrite, func(current, total int, filename string, status string) {
-		runtime.EventsEmit(a.ctx, "install-progress", map[string]interface{}{
-			"current":  current,
-			"total":    total,
-			"filename": filename,
-			"status":   status,
+	var collisions []string
+	_, err := a.manager.Jobs().Run(a.ctx, jobs.Options{Type: jobs.TypeInstall, Target: destLibPath}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		var err error
+		collisions, err = a.manager.CopyPackagesToLibrary(ctx, filePaths, destLibPath, overwrite, func(current, total int, filename string, status string) {
+			report(current, total, filename)
+			runtime.EventsEmit(a.ctx, "install-progress", map[string]interface{}{
+				"current":  current,
+				"total":    total,
+				"filename": filename,
+				"status":   status,
+			})
 		})
+		return map[string]interface{}{"collisions": collisions}, err
 	})
+	return collisions, err
 }
 
 // CheckCollisions checks if files already exist in the destination library without copying
@@ -204,14 +241,70 @@ func (a *App) CutFileToClipboard(path string) {
 	}
 }
 
-// DownloadPackage copies a file to the target directory (or default Downloads)
+// DownloadPackage copies a file to the target directory (or default Downloads).
+// It is the single-package form of DownloadBundle and runs through the same export job.
 func (a *App) DownloadPackage(pkgPath string, customPath string) error {
+	_, err := a.DownloadBundle(models.BundleRequest{Paths: []string{pkgPath}}, customPath, false)
+	return err
+}
+
+// DownloadBundle exports several packages (explicit files, a filter over a library or a dependency closure)
+// into the target directory (or default Downloads), either as loose files or as one zip. Returns what was written.
+// A separate binding because Wails methods cannot be overloaded and the UI calls DownloadPackage(path, folder).
+// Runs as a cancellable export job; progress is reported through "install-progress" events.
+func (a *App) DownloadBundle(req models.BundleRequest, customPath string, asZip bool) (string, error) {
+	targetDir := customPath
+	if targetDir == "" {
+		home, _ := os.UserHomeDir()
+		targetDir = filepath.Join(home, "Downloads")
+	}
+	status := "exporting"
+	if asZip {
+		status = "zipping"
+	}
+	var target string
+	_, err := a.manager.Jobs().Run(a.ctx, jobs.Options{Type: jobs.TypeExport, Target: targetDir, Cancellable: true}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		var err error
+		target, err = a.manager.ExportBundle(ctx, req, targetDir, asZip, func(current, total int, filename string) {
+			report(current, total, filename)
+			runtime.EventsEmit(a.ctx, "install-progress", map[string]interface{}{
+				"current":  current,
+				"total":    total,
+				"filename": filename,
+				"status":   status,
+			})
+		})
+		return target, err
+	})
+	return target, err
+}
+
+// ExportWithDependencies copies (or zips) a package and its dependency closure to the target directory
+// (or default Downloads). Progress is reported through "install-progress" events.
+func (a *App) ExportWithDependencies(libraryPath string, pkgPath string, customPath string, asZip bool, overwrite bool) (*models.ClosureExport, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
 	targetDir := customPath
 	if targetDir == "" {
 		home, _ := os.UserHomeDir()
 		targetDir = filepath.Join(home, "Downloads")
 	}
-	return a.manager.DownloadPackage(pkgPath, targetDir)
+	var result *models.ClosureExport
+	_, err := a.manager.Jobs().Run(a.ctx, jobs.Options{Type: jobs.TypeExport, Target: pkgPath, Cancellable: true}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		var err error
+		result, err = a.manager.ExportWithDependencies(ctx, libraryPath, pkgPath, targetDir, asZip, overwrite, func(current, total int, filename string, status string) {
+			report(current, total, filename)
+			runtime.EventsEmit(a.ctx, "install-progress", map[string]interface{}{
+				"current":  current,
+				"total":    total,
+				"filename": filename,
+				"status":   status,
+			})
+		})
+		return result, err
+	})
+	return result, err
 }
 
 // GetUserDownloadsDir returns the default user downloads directory
@@ -268,43 +361,54 @@ func (a *App) GetFileDetails(paths []string) ([]models.FileDetail, error) {
 	return a.manager.GetFileDetails(paths)
 }
 
-// CancelScan signals the running scan to stop
-// and waits for it to finish
+// CancelScan stops following the running scan. The scan itself is only cancelled
+// when no web client is following it either.
 func (a *App) CancelScan() {
 	a.scanMu.Lock()
-	if a.scanCancel != nil {
-		a.scanCancel()
-	}
+	sub := a.scanSub
+	a.scanSub = nil
 	a.scanMu.Unlock()
-	a.scanWg.Wait()
+	if sub != nil {
+		sub.Unsubscribe()
+	}
 }
 
-// ScanPackages triggers the scan process
+// CancelLibraryScan cancels the scan of a library for every client, desktop and web
+func (a *App) CancelLibraryScan(vamPath string) {
+	a.manager.Scans().Cancel(vamPath)
+}
+
+// ScanPackages triggers the scan process. A scan of the same library that is already
+// running (e.g. started by a web client) is shared instead of restarted.
 func (a *App) ScanPackages(vamPath string) error {
 	if vamPath == "" || vamPath == "." {
 		// Nothing to scan
 		return nil
 	}
 
-	// Cancel previous scan and wait
-	a.CancelScan()
+	sub := a.manager.Scans().Subscribe(vamPath, func(pkg models.VarPackage) {
+		runtime.EventsEmit(a.ctx, "package:scanned", pkg)
+	}, func(current, total int) {
+		runtime.EventsEmit(a.ctx, "scan:progress", map[string]int{"current": current, "total": total})
+	})
 
+	// Stop following the previous library only after subscribing, so rescanning the same library joins its scan
 	a.scanMu.Lock()
-	// Create new context wrapped around app context
-	ctx, cancel := context.WithCancel(a.ctx)
-	a.scanCancel = cancel
+	prev := a.scanSub
+	a.scanSub = sub
 	a.scanMu.Unlock()
+	if prev != nil {
+		prev.Unsubscribe()
+	}
 
-	a.scanWg.Add(1)
 	go func() {
-		defer a.scanWg.Done()
-		defer cancel()
+		err := sub.Err()
 
-		err := a.manager.ScanAndAnalyze(ctx, vamPath, func(pkg models.VarPackage) {
-			runtime.EventsEmit(a.ctx, "package:scanned", pkg)
-		}, func(current, total int) {
-			runtime.EventsEmit(a.ctx, "scan:progress", map[string]int{"current": current, "total": total})
-		})
+		a.scanMu.Lock()
+		if a.scanSub == sub {
+			a.scanSub = nil
+		}
+		a.scanMu.Unlock()
 
 		if err != nil {
 			if err != context.Canceled {
@@ -318,6 +422,132 @@ func (a *App) ScanPackages(vamPath string) error {
 	return nil
 }
 
+// QuarantinePackage moves a package into the quarantine folder of its library
+func (a *App) QuarantinePackage(pkgPath string, libraryPath string, reason string) (*models.QuarantineEntry, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.Quarantine(pkgPath, libraryPath, reason)
+}
+
+// RestoreQuarantined moves a quarantined package back to its original location
+func (a *App) RestoreQuarantined(libraryPath string, id string) (string, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return "", err
+	}
+	return a.manager.Restore(libraryPath, id)
+}
+
+// GetQuarantine lists the quarantined packages of a library
+func (a *App) GetQuarantine(libraryPath string) ([]models.QuarantineEntry, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.ListQuarantine(libraryPath)
+}
+
+// QuarantineCorrupt quarantines every package of the library flagged as corrupt
+func (a *App) QuarantineCorrupt(libraryPath string) ([]models.QuarantineEntry, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.QuarantineCorrupt(a.ctx, libraryPath)
+}
+
+// SalvagePackage rebuilds a damaged package into the target directory (or default Downloads)
+func (a *App) SalvagePackage(pkgPath string, customPath string) (*utils.SalvageReport, error) {
+	if err := a.manager.ValidatePath(pkgPath); err != nil {
+		return nil, err
+	}
+	targetDir := customPath
+	if targetDir == "" {
+		home, _ := os.UserHomeDir()
+		targetDir = filepath.Join(home, "Downloads")
+	}
+	return a.manager.SalvagePackage(pkgPath, targetDir)
+}
+
+// SearchPackages runs a search query (e.g. "creator:foo size>500MB") over a library, sorted and paged
+func (a *App) SearchPackages(vamPath string, query string, sortMode string, offset int, limit int) (*search.Result, error) {
+	if err := a.manager.ValidatePath(vamPath); err != nil {
+		return nil, err
+	}
+	return a.manager.Search(a.ctx, vamPath, query, search.Options{Sort: sortMode, Offset: offset, Limit: limit})
+}
+
+// SearchText finds packages and items inside them by description, tags and file names.
+// An empty vamPath searches every configured library.
+func (a *App) SearchText(query string, vamPath string, limit int) ([]fulltext.Hit, error) {
+	return a.manager.SearchText(query, vamPath, limit)
+}
+
+// CancelIntegrityCheck stops the running integrity check of vamPath (every library when empty)
+// and waits for it to finish
+func (a *App) CancelIntegrityCheck(vamPath string) {
+	a.manager.CancelIntegrityCheck(vamPath)
+}
+
+// StartIntegrityCheck verifies the CRC of every entry of every package in the background.
+// Progress is reported through "integrity:progress", the failed packages through "integrity:complete".
+// Returns the job ID (see ListJobs).
+func (a *App) StartIntegrityCheck(vamPath string) (string, error) {
+	if err := a.manager.ValidatePath(vamPath); err != nil {
+		return "", err
+	}
+
+	job := a.manager.StartIntegrityCheck(vamPath, func(current, total int, p models.VarPackage) {
+		runtime.EventsEmit(a.ctx, "integrity:progress", map[string]interface{}{
+			"current":   current,
+			"total":     total,
+			"filePath":  p.FilePath,
+			"integrity": p.Integrity,
+		})
+	})
+	go func() {
+		result, err := a.manager.Jobs().Wait(job.ID)
+		if err != nil {
+			return
+		}
+		switch result.Status {
+		case jobs.StatusCompleted:
+			runtime.EventsEmit(a.ctx, "integrity:complete", result.Result)
+		case jobs.StatusFailed:
+			runtime.EventsEmit(a.ctx, "integrity:error", result.Error)
+		}
+	}()
+
+	return job.ID, nil
+}
+
+// ListJobs returns the running and recently finished background jobs, newest first
+func (a *App) ListJobs() []jobs.Job {
+	return a.manager.Jobs().List()
+}
+
+// GetJob returns a single job
+func (a *App) GetJob(id string) (jobs.Job, error) {
+	job, ok := a.manager.Jobs().Get(id)
+	if !ok {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+// CancelJob cancels a running job, or removes a finished one from the list
+func (a *App) CancelJob(id string) (jobs.Job, error) {
+	return a.manager.Jobs().Delete(id)
+}
+
+// RebuildPackageIndex discards the cached scan results of a library and rescans it from scratch
+func (a *App) RebuildPackageIndex(vamPath string) error {
+	if err := a.manager.ValidatePath(vamPath); err != nil {
+		return err
+	}
+	a.CancelLibraryScan(vamPath)
+	a.manager.InvalidateIndex(vamPath)
+	return a.ScanPackages(vamPath)
+}
+
 // GetFilters returns the list of unique tags/creators found
 func (a *App) GetFilters(vamPath string) ([]string, error) {
 	var pkgs []models.VarPackage
@@ -370,9 +600,16 @@ func (a *App) InstallFiles(files []string, vamPath string) ([]string, error) {
 		return nil, err
 	}
 	fmt.Printf("Backend received files to install: %v\n", files)
-	return a.manager.InstallPackage(files, vamPath, func(current, total int) {
-		runtime.EventsEmit(a.ctx, "scan:progress", map[string]int{"current": current, "total": total})
+	var installed []string
+	_, err := a.manager.Jobs().Run(a.ctx, jobs.Options{Type: jobs.TypeInstall, Target: vamPath}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		var err error
+		installed, err = a.manager.InstallPackage(ctx, files, vamPath, func(current, total int) {
+			report(current, total, "")
+			runtime.EventsEmit(a.ctx, "scan:progress", map[string]int{"current": current, "total": total})
+		})
+		return installed, err
 	})
+	return installed, err
 }
 
 // SelectDirectory opens a native dialog to select a folder
@@ -392,12 +629,9 @@ func (a *App) GetConfiguredLibraries() []string {
 	return a.manager.GetLibraries()
 }
 
+// The watcher follows library changes through the event bus (see onEvent)
 func (a *App) AddConfiguredLibrary(path string) error {
-	err := a.manager.AddLibrary(path)
-	if err != nil {
-		return err
-	}
-	return nil
+	return a.manager.AddLibrary(path)
 }
 
 func (a *App) RemoveConfiguredLibrary(path string) error {
@@ -436,6 +670,8 @@ func (a *App) Shutdown() {
 		a.server.Stop()
 		log.Println("[App] Server stopped.")
 	}
+	// Stop the integrity check first so its partial results are saved with the index
+	a.CancelIntegrityCheck("")
 	if a.manager != nil {
 		log.Println("[App] Closing manager...")
 		a.manager.Close()
@@ -443,6 +679,10 @@ func (a *App) Shutdown() {
 	}
 	// Cancel any running scans
 	a.CancelScan()
+	a.stopWatcher()
+	if a.unsubscribeEvents != nil {
+		a.unsubscribeEvents()
+	}
 	log.Println("[App] Shutdown complete.")
 }
 
@@ -556,7 +796,63 @@ func (a *App) onTrayReady() {
 
 // ResolveConflicts handles deduplication and cleanup of conflicting packages
 func (a *App) ResolveConflicts(keepPath string, others []string, libraryPath string) (*models.ResolveConflictResult, error) {
-	return a.manager.ResolveConflicts(keepPath, others, libraryPath)
+	var result *models.ResolveConflictResult
+	_, err := a.manager.Jobs().Run(a.ctx, jobs.Options{Type: jobs.TypeResolve, Target: keepPath}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		var err error
+		result, err = a.manager.ResolveConflicts(keepPath, others, libraryPath)
+		return result, err
+	})
+	return result, err
+}
+
+// CheckDependencies classifies every dependency (satisfied-exact, satisfied-by-newer, satisfied-by-older-only, missing)
+func (a *App) CheckDependencies(pkgs []models.VarPackage) []models.VarPackage {
+	return a.manager.CheckDependencies(pkgs)
+}
+
+// GetDuplicateReport lists byte-identical package files across all configured libraries
+func (a *App) GetDuplicateReport() ([]models.DuplicateGroup, error) {
+	return a.manager.FindDuplicates(a.ctx)
+}
+
+// GetPackageUsedBy lists the packages of a library that directly depend on pkgPath
+func (a *App) GetPackageUsedBy(libraryPath string, pkgPath string) ([]graph.NodeRef, error) {
+	if err := a.manager.ValidatePath(pkgPath); err != nil {
+		return nil, err
+	}
+	return a.manager.UsedBy(a.ctx, libraryPath, pkgPath)
+}
+
+// GetDependencyClosure returns every package pkgPath needs, transitively
+func (a *App) GetDependencyClosure(libraryPath string, pkgPath string) (*graph.Closure, error) {
+	if err := a.manager.ValidatePath(pkgPath); err != nil {
+		return nil, err
+	}
+	return a.manager.DependencyClosure(a.ctx, libraryPath, pkgPath)
+}
+
+// GetGraphRoots lists the packages of a library that nothing depends on
+func (a *App) GetGraphRoots(libraryPath string) ([]graph.NodeRef, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.GraphRoots(a.ctx, libraryPath)
+}
+
+// GetOrphanPackages lists unreferenced dependency-only packages of a library
+func (a *App) GetOrphanPackages(libraryPath string) ([]graph.NodeRef, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.GraphOrphans(a.ctx, libraryPath)
+}
+
+// GetDependencyCycles lists groups of packages that depend on each other
+func (a *App) GetDependencyCycles(libraryPath string) ([][]graph.NodeRef, error) {
+	if err := a.manager.ValidatePath(libraryPath); err != nil {
+		return nil, err
+	}
+	return a.manager.DependencyCycles(a.ctx, libraryPath)
 }
 
 func (a *App) onTrayExit() {
@@ -613,6 +909,21 @@ func (a *App) SetPublicAccess(enabled bool) error {
 	})
 }
 
+// SetSessionLifetime sets how many days web sessions last after login and without use (0 for no limit)
+func (a *App) SetSessionLifetime(maxAgeDays, idleDays int) error {
+	if maxAgeDays < 0 || idleDays < 0 {
+		return fmt.Errorf("session lifetimes cannot be negative")
+	}
+	err := a.manager.UpdateConfig(func(cfg *config.Config) {
+		cfg.SessionMaxAgeDays = maxAgeDays
+		cfg.SessionIdleDays = idleDays
+	})
+	if err == nil && a.auth != nil {
+		a.auth.SetSessionPolicy(auth.SessionPolicyFromDays(maxAgeDays, idleDays))
+	}
+	return err
+}
+
 // SetServerEnabled toggles the HTTP Server on startup
 func (a *App) SetServerEnabled(enabled bool) error {
 	return a.manager.UpdateConfig(func(cfg *config.Config) {
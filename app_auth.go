@@ -1,6 +1,9 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"yavam/pkg/services/auth"
+)
 
 // UpdatePassword allows the user to change the admin password
 func (a *App) UpdatePassword(newPassword string) error {
@@ -9,3 +12,67 @@ func (a *App) UpdatePassword(newPassword string) error {
 	}
 	return a.auth.SetPassword(newPassword)
 }
+
+// ListUsers returns the web accounts (viewer, uploader, admin)
+func (a *App) ListUsers() ([]auth.Account, error) {
+	if a.auth == nil {
+		return nil, fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.ListUsers()
+}
+
+// CreateUser adds a web account with its own password and role
+func (a *App) CreateUser(username, password, role string) error {
+	if a.auth == nil {
+		return fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.CreateUser(username, password, role)
+}
+
+// DeleteUser removes a web account and signs out its sessions
+func (a *App) DeleteUser(username string) error {
+	if a.auth == nil {
+		return fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.DeleteUser(username)
+}
+
+// SetUserRole changes the role of a web account
+func (a *App) SetUserRole(username, role string) error {
+	if a.auth == nil {
+		return fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.SetUserRole(username, role)
+}
+
+// SetUserPassword replaces the password of a web account
+func (a *App) SetUserPassword(username, password string) error {
+	if a.auth == nil {
+		return fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.SetUserPassword(username, password)
+}
+
+// BeginTOTPEnrollment starts two-factor setup for a web account; show the URI as a QR code
+func (a *App) BeginTOTPEnrollment(username string) (*auth.TOTPEnrollment, error) {
+	if a.auth == nil {
+		return nil, fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.BeginTOTPEnrollment(username)
+}
+
+// ConfirmTOTPEnrollment enables two-factor login with a code from the authenticator app and returns the recovery codes
+func (a *App) ConfirmTOTPEnrollment(username, code string) ([]string, error) {
+	if a.auth == nil {
+		return nil, fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.ConfirmTOTPEnrollment(username, code)
+}
+
+// DisableTOTP turns two-factor login off for a web account
+func (a *App) DisableTOTP(username string) error {
+	if a.auth == nil {
+		return fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.DisableTOTP(username)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"time"
 	"yavam/pkg/services/auth"
 )
 
@@ -19,3 +21,25 @@ func (a *App) RevokeSession(id string) error {
 	}
 	return a.auth.RevokeSession(id)
 }
+
+// CreateAPIToken creates a scoped token of the admin account for scripts. expiresInDays <= 0 never expires.
+// The token is only shown once.
+func (a *App) CreateAPIToken(name string, scopes []string, expiresInDays int) (string, error) {
+	if a.auth == nil {
+		return "", fmt.Errorf("auth service not initialized")
+	}
+	var expiresAt time.Time
+	if expiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, expiresInDays)
+	}
+	token, _, err := a.auth.CreateAPIToken(auth.DefaultAdmin, name, scopes, expiresAt)
+	return token, err
+}
+
+// ListAPITokens returns the API tokens of all accounts
+func (a *App) ListAPITokens() ([]auth.User, error) {
+	if a.auth == nil {
+		return nil, fmt.Errorf("auth service not initialized")
+	}
+	return a.auth.ListAPITokens("")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"time"
+	"yavam/pkg/events"
+	"yavam/pkg/services/config"
+	"yavam/pkg/watcher"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// startWatcher begins watching every configured library for added, removed and changed packages
+func (a *App) startWatcher() {
+	cfg := a.manager.GetConfig()
+	if !cfg.WatchLibraries {
+		return
+	}
+
+	opts := watcher.DefaultOptions()
+	opts.ForcePolling = cfg.WatchForcePolling
+	if cfg.WatchPollInterval > 0 {
+		opts.PollInterval = time.Duration(cfg.WatchPollInterval) * time.Second
+	}
+
+	a.watcherMu.Lock()
+	defer a.watcherMu.Unlock()
+	if a.watcher != nil {
+		return
+	}
+	a.watcher = watcher.New(opts, a.onPackageEvent)
+	// Returns right away, the initial snapshots are taken in the background
+	a.watcher.SetLibraries(cfg.Libraries)
+	log.Printf("[App] Watching %d libraries for changes\n", len(cfg.Libraries))
+}
+
+// refreshWatcher syncs the watched paths with the configured libraries
+func (a *App) refreshWatcher() {
+	a.watcherMu.Lock()
+	defer a.watcherMu.Unlock()
+	if a.watcher != nil {
+		a.watcher.SetLibraries(a.manager.GetLibraries())
+	}
+}
+
+func (a *App) stopWatcher() {
+	a.watcherMu.Lock()
+	w := a.watcher
+	a.watcher = nil
+	a.watcherMu.Unlock()
+
+	if w != nil {
+		w.Close()
+	}
+}
+
+// onEvent forwards every event published on the bus to the desktop UI
+func (a *App) onEvent(e events.Event) {
+	runtime.EventsEmit(a.ctx, e.Name(), e)
+	if _, ok := e.(events.LibrariesChanged); ok {
+		// Libraries may also be added or removed from the web UI. Bus subscribers must not block.
+		go a.refreshWatcher()
+	}
+}
+
+// onPackageEvent publishes watcher events, so the desktop UI and web clients see them
+func (a *App) onPackageEvent(e watcher.Event) {
+	if e.Type != watcher.EventRemoved {
+		pkg, err := a.manager.GetPackage(e.Path)
+		if err != nil {
+			// Vanished again before we could read it, the removal is reported separately
+			return
+		}
+		e.Package = &pkg
+	}
+
+	a.manager.Events().Publish(e)
+}
+
+// SetLibraryWatching enables or disables live watching of the configured libraries
+func (a *App) SetLibraryWatching(enabled bool) error {
+	err := a.manager.UpdateConfig(func(cfg *config.Config) {
+		cfg.WatchLibraries = enabled
+	})
+	if err != nil {
+		return err
+	}
+
+	a.stopWatcher()
+	if enabled {
+		a.startWatcher()
+	}
+	return nil
+}
@@ -2,7 +2,7 @@ import { useState, useEffect } from 'react';
 import { useNavigate } from 'react-router-dom';
 import { motion, AnimatePresence } from 'framer-motion';
 import { Lock, LogIn, AlertCircle, Timer, X } from 'lucide-react';
-import { login, AuthError } from '../../services/auth';
+import { login, completeTwoFactorLogin, AuthError } from '../../services/auth';
 
 interface LoginModalProps {
     isOpen: boolean;
@@ -14,6 +14,8 @@ interface LoginModalProps {
 export default function LoginModal({ isOpen, onClose, force = false, message }: LoginModalProps) {
     const navigate = useNavigate();
     const [password, setPassword] = useState('');
+    const [code, setCode] = useState('');
+    const [challenge, setChallenge] = useState<string | null>(null); // Set once the password was accepted and a code is needed
     const [loading, setLoading] = useState(false);
     const [error, setError] = useState<{ message: string, code?: string } | null>(null);
     const [cooldown, setCooldown] = useState(0);
@@ -22,6 +24,8 @@ export default function LoginModal({ isOpen, onClose, force = false, message }:
     useEffect(() => {
         if (isOpen) {
             setPassword('');
+            setCode('');
+            setChallenge(null);
             setError(null);
         }
     }, [isOpen]);
@@ -42,11 +46,17 @@ export default function LoginModal({ isOpen, onClose, force = false, message }:
         setError(null);
 
         try {
-            const token = await login(password);
+            const token = challenge ? await completeTwoFactorLogin(challenge, code) : await login(password);
             onClose(token); // Success! Pass token to avoid race condition
             navigate('/');
         } catch (err: any) {
-            if (err instanceof AuthError) {
+            if (err instanceof AuthError && err.code === 'TWO_FACTOR_REQUIRED') {
+                setChallenge(err.challenge || null);
+            } else if (err instanceof AuthError) {
+                if (challenge && err.message !== 'Incorrect code.') {
+                    setChallenge(null); // Attempt expired, start over with the password
+                    setCode('');
+                }
                 setError({ message: err.message, code: err.code });
                 if (err.code === 'RATE_LIMIT') {
                     setCooldown(30);
@@ -106,18 +116,35 @@ export default function LoginModal({ isOpen, onClose, force = false, message }:
                             {/* Form */}
                             <div className="p-8">
                                 <form onSubmit={handleSubmit} className="space-y-6">
-                                    <div>
-                                        <label className="block text-sm font-medium text-gray-400 mb-2">Access Password</label>
-                                        <input
-                                            type="password"
-                                            value={password}
-                                            onChange={(e) => setPassword(e.target.value)}
-                                            placeholder="Enter password..."
-                                            className="w-full bg-[#111] border border-[#333] rounded-lg px-4 py-3 text-white placeholder-gray-500 focus:outline-none focus:border-blue-500 transition-colors disabled:opacity-50"
-                                            autoFocus
-                                            disabled={loading || cooldown > 0}
-                                        />
-                                    </div>
+                                    {challenge ? (
+                                        <div>
+                                            <label className="block text-sm font-medium text-gray-400 mb-2">Authenticator Code</label>
+                                            <input
+                                                type="text"
+                                                inputMode="numeric"
+                                                autoComplete="one-time-code"
+                                                value={code}
+                                                onChange={(e) => setCode(e.target.value)}
+                                                placeholder="6-digit code or recovery code"
+                                                className="w-full bg-[#111] border border-[#333] rounded-lg px-4 py-3 text-white placeholder-gray-500 focus:outline-none focus:border-blue-500 transition-colors disabled:opacity-50"
+                                                autoFocus
+                                                disabled={loading || cooldown > 0}
+                                            />
+                                        </div>
+                                    ) : (
+                                        <div>
+                                            <label className="block text-sm font-medium text-gray-400 mb-2">Access Password</label>
+                                            <input
+                                                type="password"
+                                                value={password}
+                                                onChange={(e) => setPassword(e.target.value)}
+                                                placeholder="Enter password..."
+                                                className="w-full bg-[#111] border border-[#333] rounded-lg px-4 py-3 text-white placeholder-gray-500 focus:outline-none focus:border-blue-500 transition-colors disabled:opacity-50"
+                                                autoFocus
+                                                disabled={loading || cooldown > 0}
+                                            />
+                                        </div>
+                                    )}
 
                                     {error && (
                                         <div className={`flex items-start gap-3 text-sm p-4 rounded-lg ${error.code === 'RATE_LIMIT' ? 'bg-orange-950/30 border border-orange-900/50 text-orange-400' : 'bg-red-950/30 border border-red-900/50 text-red-400'}`}>
@@ -133,7 +160,7 @@ export default function LoginModal({ isOpen, onClose, force = false, message }:
 
                                     <button
                                         type="submit"
-                                        disabled={loading || !password || cooldown > 0}
+                                        disabled={loading || !(challenge ? code : password) || cooldown > 0}
                                         className={`w-full font-medium py-3 rounded-lg flex items-center justify-center gap-2 transition-all shadow-lg 
                                             ${cooldown > 0
                                                 ? 'bg-gray-700 text-gray-400 cursor-not-allowed'
@@ -49,7 +49,7 @@ const PackageCard = memo(({ pkg, onContextMenu, onSelect, isSelected, isAnchor,
             // Web Mode: Use API URL directly
             if (pkg.hasThumbnail && !pkg.thumbnailBase64) {
                 const token = localStorage.getItem('yavam_auth_token');
-                setAsyncThumb(`/api/thumbnail?filePath=${encodeURIComponent(pkg.filePath)}&token=${token || ''}`);
+                setAsyncThumb(`/api/thumbnail?filePath=${encodeURIComponent(pkg.filePath)}&size=320&token=${token || ''}`);
             }
             return;
         }
@@ -278,6 +278,11 @@ const NetworkTab = ({
                                                 <div className="text-xs text-gray-500">
                                                     Connected: {new Date(session.createdAt).toLocaleString()}
                                                 </div>
+                                                {session.lastSeen && (
+                                                    <div className="text-xs text-gray-500">
+                                                        Last seen: {new Date(session.lastSeen).toLocaleString()}{session.ipAddress ? ` from ${session.ipAddress}` : ""}
+                                                    </div>
+                                                )}
                                             </div>
                                         </div>
 
@@ -24,6 +24,7 @@ export const usePackages = (activeLibraryPath: string) => {
     const scanAbortController = useRef<AbortController | null>(null);
     const [isCancelling, setIsCancelling] = useState(false);
     const knownPathsRef = useRef(new Set<string>()); // Sync tracker for buffer
+    const webEtagRef = useRef<string | null>(null); // Version of the list shown in Web Mode
 
     // Cancel Scan Function (Moved Up)
     const cancelScan = useCallback(async (options: { resetLoading?: boolean } = {}) => {
@@ -143,7 +144,8 @@ export const usePackages = (activeLibraryPath: string) => {
         pkgs.forEach(p => {
             if (p.isCorrupt) return;
             if (!p.meta || !p.meta.creator || !p.meta.packageName) return;
-            const key = `${p.meta.creator}.${p.meta.packageName}.${p.meta.version}.${p.size}`;
+            // Content hash when available: same name and size is not proof of identical bytes
+            const key = p.hash || `${p.meta.creator}.${p.meta.packageName}.${p.meta.version}.${p.size}`;
             exactDupesMap.set(key, (exactDupesMap.get(key) || 0) + 1);
             if (p.isEnabled) {
                 enabledDupesMap.set(key, (enabledDupesMap.get(key) || 0) + 1);
@@ -169,7 +171,7 @@ export const usePackages = (activeLibraryPath: string) => {
             }
 
             if (p.meta && p.meta.creator && p.meta.packageName) {
-                const exactKey = `${p.meta.creator}.${p.meta.packageName}.${p.meta.version}.${p.size}`;
+                const exactKey = p.hash || `${p.meta.creator}.${p.meta.packageName}.${p.meta.version}.${p.size}`;
                 if ((exactDupesMap.get(exactKey) || 0) > 1) {
                     isExactDuplicate = true;
                     if (!obsoletedBy) obsoletedBy = "Identical copy exists in library";
@@ -237,6 +239,34 @@ export const usePackages = (activeLibraryPath: string) => {
         });
     }, []);
 
+    // Web Mode: loads the server's snapshot of a library. With revalidate the current list is kept
+    // unless the server has a newer version (If-None-Match / 304).
+    const fetchWebPackages = useCallback(async (libraryPath: string, sessionId: number, revalidate: boolean, signal?: AbortSignal) => {
+        const headers: Record<string, string> = {};
+        if (revalidate && webEtagRef.current) headers['If-None-Match'] = webEtagRef.current;
+
+        const res = await fetchWithAuth(`/api/packages?path=${encodeURIComponent(libraryPath)}`, { headers, signal });
+        if (scanSessionId.current !== sessionId) return;
+        if (res.status === 304) {
+            setLoading(false);
+            return;
+        }
+        if (!res.ok) throw new Error("Failed to fetch");
+
+        const list: VarPackage[] = await res.json();
+        if (scanSessionId.current !== sessionId) return;
+        webEtagRef.current = res.headers.get('ETag');
+
+        const pkgs = list.map(p => ({ ...p, isEnabled: p.filePath.endsWith(".var") }));
+        knownPathsRef.current = new Set(pkgs.map(p => p.filePath.replace(/\\/g, '/').toLowerCase()));
+        const analyzed = analyzePackages(pkgs);
+        setPackages(analyzed);
+        const tags = new Set<string>();
+        analyzed.forEach(p => p.tags?.forEach(t => tags.add(t)));
+        setAvailableTags(Array.from(tags).sort());
+        setLoading(false);
+    }, [analyzePackages]);
+
     const scanPackages = useCallback(async () => {
         if (!activeLibraryPath) {
             setPackages([]);
@@ -389,31 +419,26 @@ export const usePackages = (activeLibraryPath: string) => {
             const controller = new AbortController();
             scanAbortController.current = controller;
 
-            try {
-                // Fire and forget fetch? No, we await it to catch initial errors.
-                // But the events drive the UI.
-                const res = await fetchWithAuth(`/api/packages?path=${encodeURIComponent(activeLibraryPath)}&_t=${Date.now()}`, {
-                    signal: controller.signal
-                });
-                if (!res.ok) throw new Error("Failed to fetch");
-
-                // We typically get the full list at the end of fetch too.
-                // If we rely on events, we might duplicate?
-                // The 'uniqueBatch' check in flushBuffer prevents duplicates.
-                // The 'scan:complete' event handles finalization.
-                // We should probably IGNORE the fetch body if events are working, 
-                // OR use it as a verification/fallback.
-                // For now, let's just let the Events handle it to be consistent with Desktop.
-                // We just await the REQUEST initiation.
-                // Actually, fetch waits for Handler to finish. 
-                // The Handler finishes when Scan finishes.
-                // So this await blocks until 100%.
-                // But we get events during the wait!
-
-                // Optional: sync at end
-                // const pkgs = await res.json();
-                // setPackages(prev => ... merge ...);
+            // The server keeps a snapshot per library and announces new versions with packages:updated
+            // @ts-ignore
+            window.runtime.EventsOff("packages:updated");
+            // @ts-ignore
+            window.runtime.EventsOn("packages:updated", (data: any) => {
+                if (scanSessionId.current !== currentId) return;
+                if (data?.path && data.path.replace(/\\/g, '/').toLowerCase() !== activeLibraryPath.replace(/\\/g, '/').toLowerCase()) return;
+                fetchWebPackages(activeLibraryPath, currentId, true).catch(e => console.error(e));
+            });
+            // Events were lost while disconnected (or the server restarted), so the list may be outdated
+            // @ts-ignore
+            window.runtime.EventsOff("resync:required");
+            // @ts-ignore
+            window.runtime.EventsOn("resync:required", () => {
+                if (scanSessionId.current !== currentId) return;
+                fetchWebPackages(activeLibraryPath, currentId, true).catch(e => console.error(e));
+            });
 
+            try {
+                await fetchWebPackages(activeLibraryPath, currentId, false, controller.signal);
             } catch (e: any) {
                 if (e.name !== 'AbortError') {
                     console.error(e);
@@ -421,7 +446,7 @@ export const usePackages = (activeLibraryPath: string) => {
                 }
             }
         }
-    }, [activeLibraryPath, analyzePackages, cancelScan]); // Added cancelScan dependency
+    }, [activeLibraryPath, analyzePackages, cancelScan, fetchWebPackages]); // Added cancelScan dependency
 
 
 
@@ -433,6 +458,9 @@ export const usePackages = (activeLibraryPath: string) => {
                 window.runtime.EventsOff("package:scanned");
                 window.runtime.EventsOff("scan:progress");
                 window.runtime.EventsOff("scan:complete");
+            } else if (window.runtime) {
+                window.runtime.EventsOff("packages:updated");
+                window.runtime.EventsOff("resync:required");
             }
         };
     }, []);
@@ -30,7 +30,7 @@ export function logout() {
 }
 
 export class AuthError extends Error {
-    constructor(public code: 'RATE_LIMIT' | 'INVALID_CREDENTIALS' | 'SERVER_ERROR' | 'NETWORK_ERROR', message: string, public retryAfter?: number) {
+    constructor(public code: 'RATE_LIMIT' | 'INVALID_CREDENTIALS' | 'TWO_FACTOR_REQUIRED' | 'SERVER_ERROR' | 'NETWORK_ERROR', message: string, public retryAfter?: number, public challenge?: string) {
         super(message);
         this.name = 'AuthError';
     }
@@ -54,22 +54,51 @@ export async function login(password: string): Promise<string> {
 
         if (!loginRes.ok) {
             if (loginRes.status === 401) {
+                const body = await loginRes.json().catch(() => ({}));
+                if (body.twoFactorRequired) {
+                    // Password was right, completeTwoFactorLogin finishes with the code
+                    throw new AuthError('TWO_FACTOR_REQUIRED', 'Enter the code from your authenticator app.', undefined, body.challenge);
+                }
                 throw new AuthError('INVALID_CREDENTIALS', 'Incorrect password.');
             }
             throw new AuthError('SERVER_ERROR', `Login failed: ${loginRes.statusText}`);
         }
 
         const { token } = await loginRes.json();
+        storeToken(token);
+        return token;
+
+    } catch (err: any) {
+        if (err instanceof AuthError) throw err;
+        console.error("Login failed:", err);
+        throw new AuthError('NETWORK_ERROR', 'Unable to reach the server. Check your connection.');
+    }
+}
 
-        // Store Token
-        cachedToken = token;
-        localStorage.setItem(AUTH_KEY, token);
+// Second login step for accounts with two-factor authentication. code is a TOTP or recovery code.
+export async function completeTwoFactorLogin(challenge: string, code: string): Promise<string> {
+    try {
+        const res = await fetch('/api/auth/login/totp', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ challenge, code })
+        });
 
-        // Dispatch Login Event so other parts of the app (like Wails Polyfill) react
-        window.dispatchEvent(new Event('auth:login'));
+        if (res.status === 429) {
+            throw new AuthError('RATE_LIMIT', 'Too many login attempts. Please wait a moment.');
+        }
+        if (!res.ok) {
+            const body = await res.json().catch(() => ({}));
+            if (res.status === 401) {
+                // Anything but a wrong code means the attempt expired and the password is needed again
+                throw new AuthError('INVALID_CREDENTIALS', body.message && body.message !== 'Invalid code' ? body.message : 'Incorrect code.');
+            }
+            throw new AuthError('SERVER_ERROR', `Login failed: ${res.statusText}`);
+        }
 
+        const { token } = await res.json();
+        storeToken(token);
         return token;
-
     } catch (err: any) {
         if (err instanceof AuthError) throw err;
         console.error("Login failed:", err);
@@ -77,6 +106,14 @@ export async function login(password: string): Promise<string> {
     }
 }
 
+function storeToken(token: string) {
+    cachedToken = token;
+    localStorage.setItem(AUTH_KEY, token);
+
+    // Dispatch Login Event so other parts of the app (like Wails Polyfill) react
+    window.dispatchEvent(new Event('auth:login'));
+}
+
 // Helper functions for device identification
 function getBrowserName() {
     const ua = navigator.userAgent;
@@ -13,6 +13,7 @@ const listeners = new Map<string, ((data: any) => void)[]>();
 let eventSource: EventSource | null = null;
 let reconnectTimer: any = null;
 let isRevoked = false; // Prevent reconnect loop if logged out
+let lastEventId = ''; // Resume point for reconnects; the server replays what we missed (or sends resync:required)
 
 function disconnectSSE() {
     if (eventSource) {
@@ -30,13 +31,18 @@ function connectSSE() {
 
     console.log("[Polyfill] Connecting to SSE...");
     const token = localStorage.getItem('yavam_auth_token');
-    const url = token ? `/api/events?token=${encodeURIComponent(token)}` : '/api/events';
-    eventSource = new EventSource(url);
+    const params = new URLSearchParams();
+    if (token) params.set('token', token);
+    // A new EventSource does not send Last-Event-ID by itself, so pass it explicitly
+    if (lastEventId) params.set('lastEventId', lastEventId);
+    const query = params.toString();
+    eventSource = new EventSource(query ? `/api/events?${query}` : '/api/events');
 
     eventSource.onmessage = (event) => {
         try {
             // Keep-alive check
             if (event.data === ": keep-alive") return;
+            if (event.lastEventId) lastEventId = event.lastEventId;
 
             const msg = JSON.parse(event.data);
             if (msg && msg.event) {
@@ -1,3 +1,13 @@
+export interface DependencyStatus {
+    id: string;
+    status: 'satisfied-exact' | 'satisfied-by-newer' | 'satisfied-by-older-only' | 'missing';
+    resolvedId?: string;
+    resolvedPath?: string;
+    licenseType?: string;
+    transitive?: boolean; // Declared by another dependency's meta.json entry
+    via?: string;
+}
+
 export interface VarPackage {
     filePath: string;
     fileName: string;
@@ -14,13 +24,24 @@ export interface VarPackage {
     isEnabled: boolean;
     hasThumbnail: boolean;
     missingDeps: string[];
+    dependencyStatus?: DependencyStatus[]; // Server-side resolution (latest / minN / exact)
+    missingTransitiveDeps?: string[];
     isDuplicate: boolean;
     isExactDuplicate: boolean;
     type?: string;
     categories: string[];
     tags?: string[];
     isCorrupt?: boolean;
+    hash?: string; // SHA-256 of the file contents
+    integrity?: IntegrityResult; // Last deep CRC check, absent if never verified
     isOrphan?: boolean;
     referencedBy?: string[];
     obsoletedBy?: string; // Diagnostic info: "vX.Y (filename)"
 }
+
+export interface IntegrityResult {
+    status: 'ok' | 'truncated' | 'bad-crc' | 'bad-header';
+    entry?: string; // First failing entry
+    error?: string;
+    checkedAt: string;
+}
@@ -4,6 +4,7 @@ go 1.22.0
 
 require (
 	github.com/energye/systray v1.0.2
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/wailsapp/wails/v2 v2.11.0
 	golang.org/x/crypto v0.33.0
 	golang.org/x/text v0.22.0
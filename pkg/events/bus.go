@@ -0,0 +1,62 @@
+package events
+
+import "sync"
+
+// Event is a typed notification published on the Bus. Name is the event name the Wails runtime
+// and the SSE stream deliver it under; the value itself is the JSON payload.
+type Event interface {
+	Name() string
+}
+
+// Change is an Event that modified files on disk. Paths lists the affected packages, folders or
+// libraries so caches of the libraries containing them can be refreshed.
+type Change interface {
+	Event
+	Paths() []string
+}
+
+// Bus fans out events to every subscriber (the desktop UI, the web server, ...), so a change made
+// by one client reaches all of them
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[int]func(Event)
+	nextID int
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn for every published event. fn runs on the publishing goroutine and
+// must not block. The returned function removes the subscription.
+func (b *Bus) Subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish delivers e to every subscriber. Publishing on a nil bus is a no-op, so services
+// can be used without one (e.g. in tests).
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := make([]func(Event), 0, len(b.subs))
+	for _, fn := range b.subs {
+		subs = append(subs, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(e)
+	}
+}
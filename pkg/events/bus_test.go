@@ -0,0 +1,27 @@
+package events
+
+import "testing"
+
+func TestBus_DeliversToEverySubscriber(t *testing.T) {
+	b := NewBus()
+
+	var desktop, web []string
+	b.Subscribe(func(e Event) { desktop = append(desktop, e.Name()) })
+	unsubscribe := b.Subscribe(func(e Event) { web = append(web, e.Name()) })
+
+	b.Publish(PackageToggled{Path: "/lib/A.var", NewPath: "/lib/A.var.disabled"})
+	unsubscribe()
+	b.Publish(PackageDeleted{Path: "/lib/B.var"})
+
+	if len(desktop) != 2 || desktop[0] != "package:toggled" || desktop[1] != "package:deleted" {
+		t.Errorf("Unexpected desktop events %v", desktop)
+	}
+	if len(web) != 1 {
+		t.Errorf("Expected no events after unsubscribing, got %v", web)
+	}
+}
+
+func TestBus_NilIsNoop(t *testing.T) {
+	var b *Bus
+	b.Publish(PackageDeleted{Path: "/lib/B.var"})
+}
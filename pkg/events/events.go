@@ -0,0 +1,86 @@
+package events
+
+import "yavam/pkg/models"
+
+// PackageToggled is published when a package was enabled or disabled (renamed to/from .var.disabled)
+type PackageToggled struct {
+	Path    string `json:"path"`
+	NewPath string `json:"newPath"`
+	Enabled bool   `json:"enabled"`
+}
+
+func (PackageToggled) Name() string      { return "package:toggled" }
+func (e PackageToggled) Paths() []string { return []string{e.Path, e.NewPath} }
+
+// PackagesInstalled is published when files were copied into a folder (install, upload, export, salvage)
+type PackagesInstalled struct {
+	Folder string   `json:"folder"`
+	Files  []string `json:"files"`
+}
+
+func (PackagesInstalled) Name() string      { return "packages:installed" }
+func (e PackagesInstalled) Paths() []string { return []string{e.Folder} }
+
+// PackageDeleted is published when a package was moved to the recycle bin
+type PackageDeleted struct {
+	Path string `json:"path"`
+}
+
+func (PackageDeleted) Name() string      { return "package:deleted" }
+func (e PackageDeleted) Paths() []string { return []string{e.Path} }
+
+// ConflictsResolved is published after duplicates of a package were merged or disabled
+type ConflictsResolved struct {
+	LibraryPath string                        `json:"libraryPath"`
+	KeepPath    string                        `json:"keepPath"`
+	Others      []string                      `json:"others"`
+	Result      *models.ResolveConflictResult `json:"result"`
+}
+
+func (ConflictsResolved) Name() string { return "conflicts:resolved" }
+func (e ConflictsResolved) Paths() []string {
+	return append([]string{e.LibraryPath, e.KeepPath}, e.Others...)
+}
+
+// PackageQuarantined is published when a package was moved into the quarantine folder of its library
+type PackageQuarantined struct {
+	Entry models.QuarantineEntry `json:"entry"`
+}
+
+func (PackageQuarantined) Name() string      { return "package:quarantined" }
+func (e PackageQuarantined) Paths() []string { return []string{e.Entry.LibraryPath} }
+
+// PackageRestored is published when a quarantined package was moved back
+type PackageRestored struct {
+	LibraryPath string `json:"libraryPath"`
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+}
+
+func (PackageRestored) Name() string      { return "package:restored" }
+func (e PackageRestored) Paths() []string { return []string{e.LibraryPath} }
+
+// IndexInvalidated is published when the cached parse results of a library were dropped
+type IndexInvalidated struct {
+	LibraryPath string `json:"libraryPath"`
+	Removed     int    `json:"removed"`
+}
+
+func (IndexInvalidated) Name() string      { return "index:invalidated" }
+func (e IndexInvalidated) Paths() []string { return []string{e.LibraryPath} }
+
+// IntegrityChecked is published when an integrity check finished and stored its verdicts
+type IntegrityChecked struct {
+	LibraryPath string              `json:"libraryPath"`
+	Failed      []models.VarPackage `json:"failed"`
+}
+
+func (IntegrityChecked) Name() string      { return "integrity:checked" }
+func (e IntegrityChecked) Paths() []string { return []string{e.LibraryPath} }
+
+// LibrariesChanged is published when the list of configured libraries changed
+type LibrariesChanged struct {
+	Libraries []string `json:"libraries"`
+}
+
+func (LibrariesChanged) Name() string { return "libraries:changed" }
@@ -0,0 +1,131 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+	"yavam/pkg/models"
+)
+
+// Field weights: a word in a name says more about a package than the same word in its description
+const (
+	weightName        = 3
+	weightCreator     = 2
+	weightTag         = 2
+	weightDescription = 1
+	weightType        = 1
+	weightParent      = 1 // Package name and creator on item documents
+)
+
+// stopwords are dropped from queries only; indexing them costs little and keeps phrases intact
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "in": true, "on": true,
+	"for": true, "with": true, "to": true, "by": true, "or": true, "is": true,
+}
+
+// Documents builds the searchable documents of a package: one for the package and one per content item
+func Documents(p models.VarPackage, items []models.ContentItem) []*Document {
+	pkgID := strings.TrimSuffix(strings.TrimSuffix(p.FileName, ".disabled"), ".var")
+
+	pkgDoc := &Document{PackagePath: p.FilePath, PackageID: pkgID, Terms: make(map[string]float64)}
+	addTerms(pkgDoc.Terms, p.Meta.PackageName, weightName)
+	addTerms(pkgDoc.Terms, p.Meta.Creator, weightCreator)
+	for _, tag := range append(append([]string{}, p.Meta.Tags...), p.Tags...) {
+		addTerms(pkgDoc.Terms, tag, weightTag)
+	}
+	addTerms(pkgDoc.Terms, p.Meta.Description, weightDescription)
+
+	docs := []*Document{pkgDoc}
+	for _, item := range items {
+		d := &Document{
+			PackagePath: p.FilePath,
+			PackageID:   pkgID,
+			ItemPath:    item.Path,
+			ItemName:    item.Name,
+			ItemType:    item.Type,
+			Terms:       make(map[string]float64),
+		}
+		addTerms(d.Terms, item.Name, weightName)
+		for _, tag := range item.Tags {
+			addTerms(d.Terms, tag, weightTag)
+		}
+		addTerms(d.Terms, item.Type, weightType)
+		addTerms(d.Terms, p.Meta.PackageName, weightParent)
+		addTerms(d.Terms, p.Meta.Creator, weightParent)
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+// addTerms adds each token of text with weight w; repeated tokens accumulate (term frequency)
+func addTerms(terms map[string]float64, text string, w float64) {
+	for _, tok := range Tokens(text) {
+		terms[tok] += w
+	}
+}
+
+// Tokens splits text into lowercase index terms. Words are split on punctuation, camelCase and
+// letter/digit boundaries ("BlueDress_v2" -> "bluedress", "blue", "dress", "v2");
+// the unsplit word is kept too so exact names still match. Tokens shorter than 2 runes are dropped.
+func Tokens(text string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	add := func(tok string) {
+		tok = strings.ToLower(tok)
+		if len([]rune(tok)) < 2 || seen[tok] {
+			return
+		}
+		seen[tok] = true
+		out = append(out, tok)
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		add(word)
+		parts := splitWord(word)
+		if len(parts) > 1 {
+			for _, p := range parts {
+				add(p)
+			}
+		}
+	}
+	return out
+}
+
+// splitWord breaks a word at lower->upper case changes and letter/digit changes
+func splitWord(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := (unicode.IsLower(prev) && unicode.IsUpper(cur)) ||
+			(unicode.IsLetter(prev) && unicode.IsDigit(cur)) ||
+			(unicode.IsDigit(prev) && unicode.IsLetter(cur)) ||
+			// "HTMLParser": split before the last upper of an upper run followed by lower
+			(i+1 < len(runes) && unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(runes[i+1]))
+		if boundary {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+// QueryTokens tokenizes a search query like indexed text but only keeps whole words, minus stopwords
+func QueryTokens(query string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if len([]rune(w)) < 2 || stopwords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+	}
+	return out
+}
@@ -0,0 +1,305 @@
+package fulltext
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"yavam/pkg/index"
+)
+
+// formatVersion is bumped whenever tokenizing or weighting changes so old indexes are rebuilt
+const formatVersion = 1
+
+// prefixFactor scales matches where a query word is only the beginning of an indexed word
+const prefixFactor = 0.5
+
+// Document is one searchable unit: a package itself (empty ItemPath) or an item inside it
+type Document struct {
+	PackagePath string             `json:"packagePath"`
+	PackageID   string             `json:"packageId"`
+	ItemPath    string             `json:"itemPath,omitempty"`
+	ItemName    string             `json:"itemName,omitempty"`
+	ItemType    string             `json:"itemType,omitempty"`
+	Terms       map[string]float64 `json:"terms"` // Token -> weight
+}
+
+// Hit is a ranked search result
+type Hit struct {
+	PackagePath string   `json:"packagePath"`
+	PackageID   string   `json:"packageId"`
+	ItemPath    string   `json:"itemPath,omitempty"` // Empty when the package itself matched
+	ItemName    string   `json:"itemName,omitempty"`
+	ItemType    string   `json:"itemType,omitempty"`
+	Score       float64  `json:"score"`
+	Matched     []string `json:"matched"` // Query words found in this document
+}
+
+type indexFile struct {
+	Version  int                    `json:"version"`
+	Packages map[string][]*Document `json:"packages"`
+}
+
+// Index is an inverted index over package and item text, persisted as JSON in the data directory.
+// Packages are keyed like the package index (index.Key) and replaced as a whole when they change.
+type Index struct {
+	mu       sync.RWMutex
+	path     string
+	packages map[string][]*Document
+	postings map[string]map[*Document]float64
+	dirty    bool
+}
+
+// NewIndex opens the full-text index stored at path; a missing or outdated file starts empty
+func NewIndex(path string) *Index {
+	idx := &Index{
+		path:     path,
+		packages: make(map[string][]*Document),
+		postings: make(map[string]map[*Document]float64),
+	}
+	if err := idx.Load(); err != nil {
+		fmt.Printf("[FullText] Starting with empty index: %v\n", err)
+	}
+	return idx
+}
+
+// Load replaces the in-memory index with the file contents
+func (i *Index) Load() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	data, err := os.ReadFile(i.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var f indexFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	i.packages = make(map[string][]*Document)
+	i.postings = make(map[string]map[*Document]float64)
+	if f.Version != formatVersion {
+		i.dirty = true
+		return nil
+	}
+	for key, docs := range f.Packages {
+		i.addLocked(key, docs)
+	}
+	i.dirty = false
+	return nil
+}
+
+// Save writes the index atomically if it changed
+func (i *Index) Save() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !i.dirty {
+		return nil
+	}
+	data, err := json.Marshal(indexFile{Version: formatVersion, Packages: i.packages})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(i.path), 0755); err != nil {
+		return err
+	}
+	tmp := i.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, i.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	i.dirty = false
+	return nil
+}
+
+// Has reports whether a package is indexed
+func (i *Index) Has(pkgPath string) bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	_, ok := i.packages[index.Key(pkgPath)]
+	return ok
+}
+
+// Put replaces the documents of a package
+func (i *Index) Put(pkgPath string, docs []*Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	key := index.Key(pkgPath)
+	i.removeLocked(key)
+	i.addLocked(key, docs)
+	i.dirty = true
+}
+
+// Remove drops a package
+func (i *Index) Remove(pkgPath string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.removeLocked(index.Key(pkgPath)) {
+		i.dirty = true
+	}
+}
+
+// Move re-keys a package after a rename (enable/disable) without re-reading it
+func (i *Index) Move(oldPath, newPath string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	docs, ok := i.packages[index.Key(oldPath)]
+	if !ok {
+		return
+	}
+	delete(i.packages, index.Key(oldPath))
+	for _, d := range docs {
+		d.PackagePath = newPath
+	}
+	i.packages[index.Key(newPath)] = docs
+	i.dirty = true
+}
+
+// Prune removes packages below root that the latest scan did not see
+func (i *Index) Prune(root string, seen map[string]bool) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	prefix := index.Key(root) + string(filepath.Separator)
+	removed := 0
+	for key := range i.packages {
+		if strings.HasPrefix(key, prefix) && !seen[key] {
+			i.removeLocked(key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		i.dirty = true
+	}
+	return removed
+}
+
+// Len returns the number of indexed packages
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.packages)
+}
+
+func (i *Index) addLocked(key string, docs []*Document) {
+	i.packages[key] = docs
+	for _, d := range docs {
+		for term, w := range d.Terms {
+			if i.postings[term] == nil {
+				i.postings[term] = make(map[*Document]float64)
+			}
+			i.postings[term][d] = w
+		}
+	}
+}
+
+func (i *Index) removeLocked(key string) bool {
+	docs, ok := i.packages[key]
+	if !ok {
+		return false
+	}
+	for _, d := range docs {
+		for term := range d.Terms {
+			delete(i.postings[term], d)
+			if len(i.postings[term]) == 0 {
+				delete(i.postings, term)
+			}
+		}
+	}
+	delete(i.packages, key)
+	return true
+}
+
+// Search ranks documents by how many query words they contain, then by weighted tf-idf.
+// Query words also match indexed words they are a prefix of ("brai" finds "braid"), at a lower weight.
+// accept (optional) filters by package path; limit <= 0 returns every hit.
+func (i *Index) Search(query string, limit int, accept func(pkgPath string) bool) []Hit {
+	words := QueryTokens(query)
+	if len(words) == 0 {
+		return []Hit{}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	totalDocs := 0
+	for _, docs := range i.packages {
+		totalDocs += len(docs)
+	}
+
+	type match struct {
+		score   float64
+		matched []string
+	}
+	results := make(map[*Document]*match)
+	for _, word := range words {
+		best := make(map[*Document]float64) // Best match of this word per document
+		for term, docs := range i.postings {
+			factor := 0.0
+			switch {
+			case term == word:
+				factor = 1
+			case len(word) >= 3 && strings.HasPrefix(term, word):
+				factor = prefixFactor
+			default:
+				continue
+			}
+			idf := math.Log(1 + float64(totalDocs)/float64(len(docs)))
+			for d, w := range docs {
+				if s := w * idf * factor; s > best[d] {
+					best[d] = s
+				}
+			}
+		}
+		for d, s := range best {
+			m := results[d]
+			if m == nil {
+				m = &match{}
+				results[d] = m
+			}
+			m.score += s
+			m.matched = append(m.matched, word)
+		}
+	}
+
+	hits := make([]Hit, 0, len(results))
+	for d, m := range results {
+		if accept != nil && !accept(d.PackagePath) {
+			continue
+		}
+		hits = append(hits, Hit{
+			PackagePath: d.PackagePath,
+			PackageID:   d.PackageID,
+			ItemPath:    d.ItemPath,
+			ItemName:    d.ItemName,
+			ItemType:    d.ItemType,
+			Score:       math.Round(m.score*1000) / 1000,
+			Matched:     m.matched,
+		})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if len(hits[a].Matched) != len(hits[b].Matched) {
+			return len(hits[a].Matched) > len(hits[b].Matched)
+		}
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		if hits[a].PackagePath != hits[b].PackagePath {
+			return hits[a].PackagePath < hits[b].PackagePath
+		}
+		return hits[a].ItemPath < hits[b].ItemPath
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
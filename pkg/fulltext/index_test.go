@@ -0,0 +1,100 @@
+package fulltext
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"yavam/pkg/models"
+)
+
+func testPackage(path string, meta models.MetaJSON) models.VarPackage {
+	return models.VarPackage{FilePath: path, FileName: filepath.Base(path), Meta: meta}
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("BlueDress_v2 HTMLParser, a x")
+	want := []string{"bluedress", "blue", "dress", "v2", "htmlparser", "html", "parser"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens = %v, want %v", got, want)
+	}
+	if q := QueryTokens("the Red dress and RED"); !reflect.DeepEqual(q, []string{"red", "dress"}) {
+		t.Errorf("QueryTokens = %v", q)
+	}
+}
+
+func TestSearch_RanksAndPointsAtItems(t *testing.T) {
+	idx := NewIndex(filepath.Join(t.TempDir(), "ft.json"))
+	lib := t.TempDir()
+
+	hairPath := filepath.Join(lib, "Anna.HairPack.1.var")
+	idx.Put(hairPath, Documents(testPackage(hairPath, models.MetaJSON{
+		Creator: "Anna", PackageName: "HairPack", Description: "Long hair styles",
+	}), []models.ContentItem{
+		{Path: "Custom/Hair/Female/Anna/LongBraid.vam", Name: "LongBraid", Type: "Hair", Tags: []string{"long", "braid"}},
+		{Path: "Custom/Hair/Female/Anna/Bob.vam", Name: "Bob", Type: "Hair"},
+	}))
+	scenePath := filepath.Join(lib, "Ben.Beach.1.var")
+	idx.Put(scenePath, Documents(testPackage(scenePath, models.MetaJSON{
+		Creator: "Ben", PackageName: "Beach", Description: "Sunset scene with a long pier",
+	}), []models.ContentItem{
+		{Path: "Saves/scene/Sunset.json", Name: "Sunset", Type: "Scene"},
+	}))
+
+	hits := idx.Search("long braid", 0, nil)
+	if len(hits) == 0 {
+		t.Fatal("Expected hits")
+	}
+	top := hits[0]
+	if top.PackagePath != hairPath || top.ItemPath != "Custom/Hair/Female/Anna/LongBraid.vam" || top.ItemType != "Hair" {
+		t.Errorf("Expected the braid item first, got %+v", top)
+	}
+	if top.PackageID != "Anna.HairPack.1" || len(top.Matched) != 2 {
+		t.Errorf("Unexpected top hit %+v", top)
+	}
+
+	// Prefix matches
+	if hits := idx.Search("suns", 0, nil); len(hits) != 2 || hits[0].PackagePath != scenePath {
+		t.Errorf("Expected prefix hits in the beach package, got %+v", hits)
+	}
+
+	// Filter and limit
+	hits = idx.Search("long", 1, func(p string) bool { return p == scenePath })
+	if len(hits) != 1 || hits[0].PackagePath != scenePath {
+		t.Errorf("Expected one filtered hit, got %+v", hits)
+	}
+
+	if hits := idx.Search("the a", 0, nil); len(hits) != 0 {
+		t.Errorf("Stopword query should be empty, got %d hits", len(hits))
+	}
+}
+
+func TestIndex_MovePrunePersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ft.json")
+	lib := t.TempDir()
+	idx := NewIndex(file)
+
+	keep := filepath.Join(lib, "A.Keep.1.var")
+	drop := filepath.Join(lib, "A.Drop.1.var")
+	idx.Put(keep, Documents(testPackage(keep, models.MetaJSON{PackageName: "Keep", Tags: []string{"castle"}}), nil))
+	idx.Put(drop, Documents(testPackage(drop, models.MetaJSON{PackageName: "Drop", Tags: []string{"castle"}}), nil))
+
+	disabled := keep + ".disabled"
+	idx.Move(keep, disabled)
+	if idx.Has(keep) || !idx.Has(disabled) {
+		t.Fatal("Move did not re-key the package")
+	}
+
+	if n := idx.Prune(lib, map[string]bool{}); n != 2 {
+		t.Fatalf("Expected 2 pruned, got %d", n)
+	}
+	idx.Put(disabled, Documents(testPackage(disabled, models.MetaJSON{PackageName: "Keep", Tags: []string{"castle"}}), nil))
+	if err := idx.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewIndex(file)
+	hits := reloaded.Search("castle", 0, nil)
+	if len(hits) != 1 || hits[0].PackagePath != disabled {
+		t.Errorf("Expected persisted hit for the disabled package, got %+v", hits)
+	}
+}
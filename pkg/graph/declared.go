@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// DeclaredDependency is a requirement found anywhere in a package's meta.json dependency tree
+type DeclaredDependency struct {
+	ID          string `json:"id"`
+	LicenseType string `json:"licenseType,omitempty"`
+	Depth       int    `json:"depth"`         // 1 for direct dependencies
+	Via         string `json:"via,omitempty"` // Declaring parent for transitive dependencies
+}
+
+// Declared flattens the nested dependency tree of meta.json, breadth first.
+// Each ID is reported once, at its shallowest depth.
+func Declared(meta models.MetaJSON) []DeclaredDependency {
+	type item struct {
+		id, via string
+		dep     models.Dependency
+		depth   int
+	}
+
+	var queue []item
+	for _, id := range sortedIDs(meta.Dependencies) {
+		queue = append(queue, item{id: id, dep: meta.Dependencies[id], depth: 1})
+	}
+
+	seen := make(map[string]bool)
+	var result []DeclaredDependency
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		key := strings.ToLower(it.id)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, DeclaredDependency{ID: it.id, LicenseType: it.dep.LicenseType, Depth: it.depth, Via: it.via})
+
+		for _, id := range sortedIDs(it.dep.Dependencies) {
+			queue = append(queue, item{id: id, via: it.id, dep: it.dep.Dependencies[id], depth: it.depth + 1})
+		}
+	}
+	return result
+}
+
+func sortedIDs(deps map[string]models.Dependency) []string {
+	ids := make([]string, 0, len(deps))
+	for id := range deps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"testing"
+	"yavam/pkg/models"
+)
+
+func nestedMeta() models.MetaJSON {
+	return models.MetaJSON{
+		Creator: "Me", PackageName: "Scene", Version: "1",
+		Dependencies: map[string]models.Dependency{
+			"A.Look.1": {LicenseType: "FC", Dependencies: map[string]models.Dependency{
+				"B.Morphs.latest": {LicenseType: "PC", Dependencies: map[string]models.Dependency{
+					"C.Textures.2": {LicenseType: "CC BY"},
+				}},
+			}},
+			"C.Textures.2": {LicenseType: "CC BY"},
+		},
+	}
+}
+
+func TestDeclared_FlattensShallowestFirst(t *testing.T) {
+	got := Declared(nestedMeta())
+	want := []DeclaredDependency{
+		{ID: "A.Look.1", LicenseType: "FC", Depth: 1},
+		{ID: "C.Textures.2", LicenseType: "CC BY", Depth: 1},
+		{ID: "B.Morphs.latest", LicenseType: "PC", Depth: 2, Via: "A.Look.1"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Declared() = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Declared()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGraph_ClosureReportsMissingTransitively(t *testing.T) {
+	scene := models.VarPackage{FilePath: "scene.var", Type: "Scene", Meta: nestedMeta()}
+	textures := pkg("textures.var", "C", "Textures", "2", "Asset")
+
+	// A.Look.1 is not installed, so its requirement B.Morphs is only known from the scene's meta.json
+	c, ok := Build([]models.VarPackage{scene, textures}).Closure("scene.var")
+	if !ok {
+		t.Fatal("Expected closure")
+	}
+	if !equal(c.Missing, []string{"A.Look.1", "B.Morphs.latest"}) {
+		t.Errorf("Missing = %v", c.Missing)
+	}
+	if got := ids(c.Packages); !equal(got, []string{"C.Textures.2"}) {
+		t.Errorf("Packages = %v", got)
+	}
+}
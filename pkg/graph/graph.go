@@ -0,0 +1,299 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// standaloneTypes are package types a user loads directly in VaM.
+// Unreferenced packages of any other type only exist to be depended upon, which makes them orphans.
+var standaloneTypes = map[string]bool{
+	"Scene":       true,
+	"Look":        true,
+	"Clothing":    true,
+	"Hair":        true,
+	"Environment": true,
+}
+
+// NodeRef identifies a package in API responses
+type NodeRef struct {
+	ID        string   `json:"id"`        // Creator.Package.Version as declared in meta.json
+	FilePaths []string `json:"filePaths"` // Every file providing this ID (copies, enabled and disabled)
+	Type      string   `json:"type"`
+}
+
+// Closure is the transitive dependency set of a package
+type Closure struct {
+	Root     NodeRef   `json:"root"`
+	Packages []NodeRef `json:"packages"` // Resolved dependencies, excluding the root
+	Missing  []string  `json:"missing"`  // Unresolved IDs anywhere in the closure, including the declared meta.json tree
+}
+
+type node struct {
+	ref      NodeRef
+	key      string
+	deps     []string
+	declared []DeclaredDependency // Full meta.json tree, including requirements of uninstalled packages
+}
+
+// Graph holds forward and reverse dependency edges between the packages of a library.
+// Edges are keyed by lowercase package ID; several files may provide the same ID.
+// Only satisfied dependencies (see Resolver) become edges, everything else is unresolved.
+type Graph struct {
+	nodes      map[string]*node
+	byPath     map[string]string // file path -> key
+	forward    map[string][]string
+	reverse    map[string]map[string]bool
+	unresolved map[string][]string // key -> dependency IDs without a provider
+	resolver   *Resolver
+}
+
+// Build creates the graph for a set of packages. Corrupt packages and packages without an ID are ignored.
+func Build(pkgs []models.VarPackage) *Graph {
+	g := &Graph{
+		nodes:      make(map[string]*node),
+		byPath:     make(map[string]string),
+		forward:    make(map[string][]string),
+		reverse:    make(map[string]map[string]bool),
+		unresolved: make(map[string][]string),
+	}
+
+	for _, p := range pkgs {
+		if p.IsCorrupt || p.Meta.Creator == "" || p.Meta.PackageName == "" {
+			continue
+		}
+		id := p.Meta.Creator + "." + p.Meta.PackageName + "." + p.Meta.Version
+		key := strings.ToLower(id)
+		g.byPath[p.FilePath] = key
+
+		if n, ok := g.nodes[key]; ok {
+			n.ref.FilePaths = append(n.ref.FilePaths, p.FilePath)
+			// Dependencies of every copy count (copies normally declare the same ones)
+			n.deps = append(n.deps, dependencyIDs(p)...)
+			continue
+		}
+
+		g.nodes[key] = &node{
+			ref:      NodeRef{ID: id, FilePaths: []string{p.FilePath}, Type: p.Type},
+			key:      key,
+			deps:     dependencyIDs(p),
+			declared: Declared(p.Meta),
+		}
+	}
+
+	// Even disabled packages count as consumers: removing what they use would break them when re-enabled
+	g.resolver = NewResolver(pkgs)
+	for key, n := range g.nodes {
+		seen := make(map[string]bool)
+		for _, dep := range n.deps {
+			res := g.resolver.Resolve(dep)
+			target := strings.ToLower(res.ID)
+			if !res.Satisfied() {
+				if !seen[strings.ToLower(dep)] {
+					g.unresolved[key] = append(g.unresolved[key], dep)
+					seen[strings.ToLower(dep)] = true
+				}
+				continue
+			}
+			if target == key || seen[target] {
+				continue
+			}
+			seen[target] = true
+			g.forward[key] = append(g.forward[key], target)
+			if g.reverse[target] == nil {
+				g.reverse[target] = make(map[string]bool)
+			}
+			g.reverse[target][key] = true
+		}
+		sort.Strings(g.forward[key])
+		sort.Strings(g.unresolved[key])
+	}
+	return g
+}
+
+// Lookup returns the node providing a package file
+func (g *Graph) Lookup(pkgPath string) (NodeRef, bool) {
+	key, ok := g.byPath[pkgPath]
+	if !ok {
+		return NodeRef{}, false
+	}
+	return g.nodes[key].ref, true
+}
+
+// UsedBy lists the packages that directly depend on the package at pkgPath
+func (g *Graph) UsedBy(pkgPath string) []NodeRef {
+	key, ok := g.byPath[pkgPath]
+	if !ok {
+		return []NodeRef{}
+	}
+	return g.refs(keys(g.reverse[key]))
+}
+
+// DependsOn lists the resolved direct dependencies of the package at pkgPath
+func (g *Graph) DependsOn(pkgPath string) []NodeRef {
+	key, ok := g.byPath[pkgPath]
+	if !ok {
+		return []NodeRef{}
+	}
+	return g.refs(g.forward[key])
+}
+
+// Roots lists packages no other package depends on
+func (g *Graph) Roots() []NodeRef {
+	var roots []string
+	for key := range g.nodes {
+		if len(g.reverse[key]) == 0 {
+			roots = append(roots, key)
+		}
+	}
+	return g.refs(roots)
+}
+
+// Orphans lists unreferenced packages that are not standalone content (morphs, textures, scripts...).
+// These usually were dependencies of something that has since been removed.
+func (g *Graph) Orphans() []NodeRef {
+	var orphans []string
+	for key, n := range g.nodes {
+		if len(g.reverse[key]) == 0 && !standaloneTypes[n.ref.Type] {
+			orphans = append(orphans, key)
+		}
+	}
+	return g.refs(orphans)
+}
+
+// Closure returns every package reachable from pkgPath through dependencies
+func (g *Graph) Closure(pkgPath string) (*Closure, bool) {
+	rootKey, ok := g.byPath[pkgPath]
+	if !ok {
+		return nil, false
+	}
+
+	visited := map[string]bool{rootKey: true}
+	missing := make(map[string]bool)
+	queue := []string{rootKey}
+	var reached []string
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.unresolved[key] {
+			missing[dep] = true
+		}
+		// Requirements declared below a missing package are needed as well ("missing transitively")
+		for _, d := range g.nodes[key].declared {
+			if d.Depth > 1 && !g.resolver.Resolve(d.ID).Satisfied() {
+				missing[d.ID] = true
+			}
+		}
+		for _, next := range g.forward[key] {
+			if !visited[next] {
+				visited[next] = true
+				reached = append(reached, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	missingList := keys(missing)
+	sort.Strings(missingList)
+	return &Closure{
+		Root:     g.nodes[rootKey].ref,
+		Packages: g.refs(reached),
+		Missing:  missingList,
+	}, true
+}
+
+// Cycles returns groups of packages that (transitively) depend on each other, using Tarjan's algorithm
+func (g *Graph) Cycles() [][]NodeRef {
+	index := 0
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]NodeRef
+
+	var strongConnect func(key string)
+	strongConnect = func(key string) {
+		indices[key] = index
+		lowlink[key] = index
+		index++
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, next := range g.forward[key] {
+			if _, seen := indices[next]; !seen {
+				strongConnect(next)
+				lowlink[key] = min(lowlink[key], lowlink[next])
+			} else if onStack[next] {
+				lowlink[key] = min(lowlink[key], indices[next])
+			}
+		}
+
+		if lowlink[key] == indices[key] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == key {
+					break
+				}
+			}
+			// Self references are dropped while building, so only multi-package components are cycles
+			if len(component) > 1 {
+				cycles = append(cycles, g.refs(component))
+			}
+		}
+	}
+
+	// Deterministic order for stable API output
+	all := keys(g.nodes)
+	sort.Strings(all)
+	for _, key := range all {
+		if _, seen := indices[key]; !seen {
+			strongConnect(key)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0].ID < cycles[j][0].ID })
+	if cycles == nil {
+		cycles = [][]NodeRef{}
+	}
+	return cycles
+}
+
+// refs converts keys to sorted node references
+func (g *Graph) refs(keyList []string) []NodeRef {
+	result := make([]NodeRef, 0, len(keyList))
+	for _, key := range keyList {
+		if n, ok := g.nodes[key]; ok {
+			result = append(result, n.ref)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].ID) < strings.ToLower(result[j].ID)
+	})
+	return result
+}
+
+func dependencyIDs(p models.VarPackage) []string {
+	return sortedIDs(p.Meta.Dependencies)
+}
+
+func parseVersion(v string) int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
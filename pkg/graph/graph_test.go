@@ -0,0 +1,109 @@
+package graph
+
+import (
+	"testing"
+	"yavam/pkg/models"
+)
+
+func pkg(path, creator, name, version, typ string, deps ...string) models.VarPackage {
+	d := make(map[string]models.Dependency)
+	for _, dep := range deps {
+		d[dep] = models.Dependency{}
+	}
+	return models.VarPackage{
+		FilePath: path,
+		Type:     typ,
+		Meta:     models.MetaJSON{Creator: creator, PackageName: name, Version: version, Dependencies: d},
+	}
+}
+
+func ids(refs []NodeRef) []string {
+	var result []string
+	for _, r := range refs {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testLibrary() []models.VarPackage {
+	return []models.VarPackage{
+		pkg("scene.var", "A", "Scene", "1", "Scene", "B.Look.1", "C.Morphs.latest"),
+		pkg("look.var", "B", "Look", "1", "Look", "C.Morphs.2", "D.Missing.1"),
+		pkg("morphs1.var", "C", "Morphs", "1", "Morph"),
+		pkg("morphs2.var", "C", "Morphs", "2", "Morph"),
+		pkg("unused.var", "E", "Textures", "1", "Asset"),
+		pkg("cycle1.var", "F", "One", "1", "Script", "F.Two.1"),
+		pkg("cycle2.var", "F", "Two", "1", "Script", "F.One.1"),
+		{FilePath: "corrupt.var", IsCorrupt: true},
+	}
+}
+
+func TestGraph_UsedByAndRoots(t *testing.T) {
+	g := Build(testLibrary())
+
+	if got := ids(g.UsedBy("morphs2.var")); !equal(got, []string{"A.Scene.1", "B.Look.1"}) {
+		t.Errorf("UsedBy(morphs2) = %v", got)
+	}
+	if got := ids(g.UsedBy("morphs1.var")); len(got) != 0 {
+		t.Errorf("Old version should be unused (latest resolves to 2), got %v", got)
+	}
+	if got := ids(g.Roots()); !equal(got, []string{"A.Scene.1", "C.Morphs.1", "E.Textures.1"}) {
+		t.Errorf("Roots() = %v", got)
+	}
+	// The scene is standalone content, the others only exist as dependencies
+	if got := ids(g.Orphans()); !equal(got, []string{"C.Morphs.1", "E.Textures.1"}) {
+		t.Errorf("Orphans() = %v", got)
+	}
+}
+
+func TestGraph_Closure(t *testing.T) {
+	g := Build(testLibrary())
+
+	c, ok := g.Closure("scene.var")
+	if !ok {
+		t.Fatal("Expected closure for scene")
+	}
+	if got := ids(c.Packages); !equal(got, []string{"B.Look.1", "C.Morphs.2"}) {
+		t.Errorf("Closure packages = %v", got)
+	}
+	if !equal(c.Missing, []string{"D.Missing.1"}) {
+		t.Errorf("Expected transitive missing dependency, got %v", c.Missing)
+	}
+	if _, ok := g.Closure("corrupt.var"); ok {
+		t.Error("Corrupt packages must not be part of the graph")
+	}
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := Build(testLibrary())
+
+	cycles := g.Cycles()
+	if len(cycles) != 1 {
+		t.Fatalf("Expected 1 cycle, got %d", len(cycles))
+	}
+	if got := ids(cycles[0]); !equal(got, []string{"F.One.1", "F.Two.1"}) {
+		t.Errorf("Cycle = %v", got)
+	}
+}
+
+func TestGraph_CopiesShareNode(t *testing.T) {
+	pkgs := append(testLibrary(), pkg("sub/morphs2.var", "C", "Morphs", "2", "Morph"))
+	g := Build(pkgs)
+
+	ref, ok := g.Lookup("sub/morphs2.var")
+	if !ok || len(ref.FilePaths) != 2 {
+		t.Errorf("Expected both copies on one node, got %+v", ref)
+	}
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// ReferenceKind is the version form used by a dependency ID
+type ReferenceKind int
+
+const (
+	RefExact  ReferenceKind = iota // Creator.Package.12
+	RefLatest                      // Creator.Package.latest
+	RefMin                         // Creator.Package.min12
+)
+
+// Reference is a parsed dependency ID
+type Reference struct {
+	Base    string // lowercase Creator.Package
+	Kind    ReferenceKind
+	Version int // Requested (exact) or minimum (min) version, unused for latest
+}
+
+// ParseReference splits a VaM dependency ID into base and version constraint.
+// The version is always the last segment; package names may contain dots.
+func ParseReference(dep string) (Reference, bool) {
+	lower := strings.ToLower(dep)
+	i := strings.LastIndex(lower, ".")
+	if i <= 0 || i == len(lower)-1 {
+		return Reference{}, false
+	}
+	base, ver := lower[:i], lower[i+1:]
+	if !strings.Contains(base, ".") {
+		return Reference{}, false // Needs both creator and package name
+	}
+
+	switch {
+	case ver == "latest":
+		return Reference{Base: base, Kind: RefLatest}, true
+	case strings.HasPrefix(ver, "min"):
+		n, err := strconv.Atoi(ver[3:])
+		if err != nil {
+			return Reference{}, false
+		}
+		return Reference{Base: base, Kind: RefMin, Version: n}, true
+	default:
+		n, err := strconv.Atoi(ver)
+		if err != nil {
+			return Reference{}, false
+		}
+		return Reference{Base: base, Kind: RefExact, Version: n}, true
+	}
+}
+
+// Resolution is the outcome of resolving one dependency
+type Resolution struct {
+	Status string
+	// Provider that satisfies (or, for older-only, would come closest to satisfying) the dependency
+	ID   string
+	Path string
+}
+
+// Satisfied reports whether VaM will load a provider for the dependency
+func (r Resolution) Satisfied() bool {
+	return r.Status == models.DependencySatisfiedExact || r.Status == models.DependencySatisfiedByNewer
+}
+
+type candidate struct {
+	id      string
+	path    string
+	version int
+}
+
+// Resolver answers dependency lookups against a set of installed packages
+type Resolver struct {
+	versions map[string][]candidate // base -> candidates, newest first
+	exact    map[string]candidate   // lowercase full ID -> candidate (covers non-numeric versions)
+}
+
+// NewResolver indexes the providers. Corrupt packages and packages without an ID never satisfy anything.
+func NewResolver(pkgs []models.VarPackage) *Resolver {
+	r := &Resolver{
+		versions: make(map[string][]candidate),
+		exact:    make(map[string]candidate),
+	}
+	for _, p := range pkgs {
+		if p.IsCorrupt || p.Meta.Creator == "" || p.Meta.PackageName == "" {
+			continue
+		}
+		id := p.Meta.Creator + "." + p.Meta.PackageName + "." + p.Meta.Version
+		c := candidate{id: id, path: p.FilePath, version: parseVersion(p.Meta.Version)}
+		if _, ok := r.exact[strings.ToLower(id)]; !ok {
+			r.exact[strings.ToLower(id)] = c
+		}
+		if c.version >= 0 {
+			base := strings.ToLower(p.Meta.Creator + "." + p.Meta.PackageName)
+			r.versions[base] = append(r.versions[base], c)
+		}
+	}
+	for _, list := range r.versions {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].version > list[j].version })
+	}
+	return r
+}
+
+// Resolve classifies a dependency ID:
+//   - latest: satisfied-exact by the newest version
+//   - minN: satisfied-exact by N, satisfied-by-newer by anything above, older-only below
+//   - N: satisfied-exact by N; VaM falls back to a newer version, older ones are not used
+func (r *Resolver) Resolve(dep string) Resolution {
+	if c, ok := r.exact[strings.ToLower(dep)]; ok {
+		return Resolution{Status: models.DependencySatisfiedExact, ID: c.id, Path: c.path}
+	}
+
+	ref, ok := ParseReference(dep)
+	if !ok {
+		return Resolution{Status: models.DependencyMissing}
+	}
+	versions := r.versions[ref.Base]
+	if len(versions) == 0 {
+		return Resolution{Status: models.DependencyMissing}
+	}
+	newest := versions[0]
+
+	switch ref.Kind {
+	case RefLatest:
+		return Resolution{Status: models.DependencySatisfiedExact, ID: newest.id, Path: newest.path}
+	case RefMin:
+		for _, c := range versions {
+			if c.version == ref.Version {
+				return Resolution{Status: models.DependencySatisfiedExact, ID: c.id, Path: c.path}
+			}
+		}
+	}
+	// Exact versions are handled by the lookup above, here only newer or older ones can exist
+	if newest.version > ref.Version {
+		return Resolution{Status: models.DependencySatisfiedByNewer, ID: newest.id, Path: newest.path}
+	}
+	return Resolution{Status: models.DependencySatisfiedByOlderOnly, ID: newest.id, Path: newest.path}
+}
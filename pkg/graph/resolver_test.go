@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"testing"
+	"yavam/pkg/models"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		dep  string
+		want Reference
+		ok   bool
+	}{
+		{"Creator.Pkg.12", Reference{Base: "creator.pkg", Kind: RefExact, Version: 12}, true},
+		{"Creator.Pkg.latest", Reference{Base: "creator.pkg", Kind: RefLatest}, true},
+		{"Creator.Pkg.min3", Reference{Base: "creator.pkg", Kind: RefMin, Version: 3}, true},
+		{"Creator.Pkg.With.Dots.2", Reference{Base: "creator.pkg.with.dots", Kind: RefExact, Version: 2}, true},
+		{"Creator.Pkg.beta", Reference{}, false},
+		{"Pkg.1", Reference{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseReference(tt.dep)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, %v; want %+v, %v", tt.dep, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolver_Classification(t *testing.T) {
+	r := NewResolver([]models.VarPackage{
+		pkg("a3.var", "A", "Pkg", "3", "Morph"),
+		pkg("a5.var", "A", "Pkg", "5", "Morph"),
+		{FilePath: "b9.var", IsCorrupt: true, Meta: models.MetaJSON{Creator: "B", PackageName: "Pkg", Version: "9"}},
+	})
+
+	tests := []struct {
+		dep, status, id string
+	}{
+		{"A.Pkg.3", models.DependencySatisfiedExact, "A.Pkg.3"},
+		{"a.pkg.5", models.DependencySatisfiedExact, "A.Pkg.5"},
+		{"A.Pkg.latest", models.DependencySatisfiedExact, "A.Pkg.5"},
+		{"A.Pkg.4", models.DependencySatisfiedByNewer, "A.Pkg.5"},
+		{"A.Pkg.min3", models.DependencySatisfiedExact, "A.Pkg.3"},
+		{"A.Pkg.min4", models.DependencySatisfiedByNewer, "A.Pkg.5"},
+		{"A.Pkg.min12", models.DependencySatisfiedByOlderOnly, "A.Pkg.5"},
+		{"A.Pkg.7", models.DependencySatisfiedByOlderOnly, "A.Pkg.5"},
+		{"B.Pkg.9", models.DependencyMissing, ""}, // Corrupt providers don't count
+		{"C.Other.latest", models.DependencyMissing, ""},
+	}
+	for _, tt := range tests {
+		res := r.Resolve(tt.dep)
+		if res.Status != tt.status || res.ID != tt.id {
+			t.Errorf("Resolve(%q) = %s/%s; want %s/%s", tt.dep, res.Status, res.ID, tt.status, tt.id)
+		}
+	}
+}
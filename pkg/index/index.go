@@ -0,0 +1,219 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"yavam/pkg/models"
+)
+
+// formatVersion is bumped whenever the cached package layout changes so old indexes are discarded
+const formatVersion = 3
+
+// Entry is the cached parse result of a single .var file
+type Entry struct {
+	Size    int64             `json:"size"`
+	ModTime int64             `json:"modTime"` // UnixNano
+	Package models.VarPackage `json:"package"`
+}
+
+type indexFile struct {
+	Version int               `json:"version"`
+	Entries map[string]*Entry `json:"entries"`
+}
+
+// PackageIndex is a persistent cache of parsed packages keyed by path, size and modification time.
+// Scans consult it so that only new or modified files have to be opened.
+type PackageIndex struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]*Entry
+	dirty   bool
+}
+
+// NewPackageIndex opens the index stored at path.
+// A missing or unreadable index is not fatal; the index simply starts empty.
+func NewPackageIndex(path string) *PackageIndex {
+	idx := &PackageIndex{
+		path:    path,
+		entries: make(map[string]*Entry),
+	}
+	if err := idx.Load(); err != nil {
+		fmt.Printf("[Index] Starting with empty index: %v\n", err)
+	}
+	return idx
+}
+
+// Key normalizes a path the same way Manager.ValidatePath compares them (case-insensitive).
+// Callers use it to build the "seen" set passed to Prune.
+func Key(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// Load replaces the in-memory entries with the contents of the index file
+func (i *PackageIndex) Load() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	data, err := os.ReadFile(i.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // First run
+		}
+		return err
+	}
+
+	var f indexFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if f.Version != formatVersion {
+		// Outdated layout, force a full re-parse
+		i.entries = make(map[string]*Entry)
+		i.dirty = true
+		return nil
+	}
+	if f.Entries == nil {
+		f.Entries = make(map[string]*Entry)
+	}
+	i.entries = f.Entries
+	i.dirty = false
+	return nil
+}
+
+// Save writes the index to disk if it changed since the last save.
+// The file is replaced atomically so a crash never leaves a half-written index behind.
+func (i *PackageIndex) Save() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(indexFile{Version: formatVersion, Entries: i.entries})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(i.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := i.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, i.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	i.dirty = false
+	return nil
+}
+
+// Lookup returns the cached package for path if the file is unchanged (same size and mtime)
+func (i *PackageIndex) Lookup(path string, size int64, modTime time.Time) (models.VarPackage, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	e, ok := i.entries[Key(path)]
+	if !ok || e.Size != size || e.ModTime != modTime.UnixNano() {
+		return models.VarPackage{}, false
+	}
+	return e.Package, true
+}
+
+// Put stores the parse result for path
+func (i *PackageIndex) Put(path string, size int64, modTime time.Time, pkg models.VarPackage) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.entries[Key(path)] = &Entry{
+		Size:    size,
+		ModTime: modTime.UnixNano(),
+		Package: pkg,
+	}
+	i.dirty = true
+}
+
+// Move re-keys an entry after a rename (e.g. enabling/disabling a package) so it is not re-parsed
+func (i *PackageIndex) Move(oldPath, newPath string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	e, ok := i.entries[Key(oldPath)]
+	if !ok {
+		return
+	}
+	delete(i.entries, Key(oldPath))
+	e.Package.FilePath = newPath
+	e.Package.FileName = filepath.Base(newPath)
+	i.entries[Key(newPath)] = e
+	i.dirty = true
+}
+
+// Invalidate drops the entry for a single file
+func (i *PackageIndex) Invalidate(path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if _, ok := i.entries[Key(path)]; ok {
+		delete(i.entries, Key(path))
+		i.dirty = true
+	}
+}
+
+// InvalidateRoot drops every entry below root and returns how many were removed.
+// An empty root clears the whole index.
+func (i *PackageIndex) InvalidateRoot(root string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	removed := 0
+	for k := range i.entries {
+		if root == "" || isUnder(k, root) {
+			delete(i.entries, k)
+			removed++
+		}
+	}
+	if removed > 0 {
+		i.dirty = true
+	}
+	return removed
+}
+
+// Prune removes entries below root whose files were not seen by the latest scan (deleted or renamed)
+func (i *PackageIndex) Prune(root string, seen map[string]bool) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	removed := 0
+	for k := range i.entries {
+		if isUnder(k, root) && !seen[k] {
+			delete(i.entries, k)
+			removed++
+		}
+	}
+	if removed > 0 {
+		i.dirty = true
+	}
+	return removed
+}
+
+// Len returns the number of cached packages
+func (i *PackageIndex) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+func isUnder(k string, root string) bool {
+	r := Key(root)
+	return k == r || strings.HasPrefix(k, r+string(os.PathSeparator))
+}
@@ -0,0 +1,116 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"yavam/pkg/models"
+)
+
+func TestIndex_AddAndLookup(t *testing.T) {
+	idx := NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	path := filepath.Join("lib", "Creator.Pkg.1.var")
+	mtime := time.Unix(1700000000, 0)
+
+	if _, ok := idx.Lookup(path, 100, mtime); ok {
+		t.Fatal("Expected miss on empty index")
+	}
+
+	idx.Put(path, 100, mtime, models.VarPackage{FilePath: path, Type: "Look"})
+
+	pkg, ok := idx.Lookup(path, 100, mtime)
+	if !ok {
+		t.Fatal("Expected hit after Put")
+	}
+	if pkg.Type != "Look" {
+		t.Errorf("Expected cached type Look, got %s", pkg.Type)
+	}
+}
+
+func TestIndex_ModifiedFileMisses(t *testing.T) {
+	idx := NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	path := filepath.Join("lib", "Creator.Pkg.1.var")
+	mtime := time.Unix(1700000000, 0)
+	idx.Put(path, 100, mtime, models.VarPackage{FilePath: path})
+
+	if _, ok := idx.Lookup(path, 101, mtime); ok {
+		t.Error("Expected miss when size changed")
+	}
+	if _, ok := idx.Lookup(path, 100, mtime.Add(time.Second)); ok {
+		t.Error("Expected miss when mtime changed")
+	}
+}
+
+func TestIndex_Rename(t *testing.T) {
+	idx := NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	oldPath := filepath.Join("lib", "Creator.Pkg.1.var")
+	newPath := oldPath + ".disabled"
+	mtime := time.Unix(1700000000, 0)
+	idx.Put(oldPath, 100, mtime, models.VarPackage{FilePath: oldPath, Type: "Scene"})
+
+	idx.Move(oldPath, newPath)
+
+	if _, ok := idx.Lookup(oldPath, 100, mtime); ok {
+		t.Error("Old path should no longer be indexed")
+	}
+	pkg, ok := idx.Lookup(newPath, 100, mtime)
+	if !ok {
+		t.Fatal("Expected entry under new path")
+	}
+	if pkg.FilePath != newPath || pkg.FileName != filepath.Base(newPath) {
+		t.Errorf("Entry not re-keyed: %s / %s", pkg.FilePath, pkg.FileName)
+	}
+}
+
+func TestIndex_PruneDeleted(t *testing.T) {
+	idx := NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	lib := filepath.Join("root", "lib")
+	other := filepath.Join("root", "other")
+	kept := filepath.Join(lib, "A.Kept.1.var")
+	deleted := filepath.Join(lib, "A.Deleted.1.var")
+	outside := filepath.Join(other, "A.Outside.1.var")
+	mtime := time.Unix(1700000000, 0)
+
+	for _, p := range []string{kept, deleted, outside} {
+		idx.Put(p, 1, mtime, models.VarPackage{FilePath: p})
+	}
+
+	removed := idx.Prune(lib, map[string]bool{Key(kept): true})
+	if removed != 1 {
+		t.Errorf("Expected 1 pruned entry, got %d", removed)
+	}
+	if _, ok := idx.Lookup(deleted, 1, mtime); ok {
+		t.Error("Deleted file should have been pruned")
+	}
+	if _, ok := idx.Lookup(outside, 1, mtime); !ok {
+		t.Error("Entries of other libraries must survive pruning")
+	}
+}
+
+func TestIndex_Persistence(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "cache", "index.json")
+	path := filepath.Join("lib", "Creator.Pkg.1.var")
+	mtime := time.Unix(1700000000, 42)
+
+	idx1 := NewPackageIndex(indexPath)
+	idx1.Put(path, 100, mtime, models.VarPackage{FilePath: path, Categories: []string{"Hair"}})
+	if err := idx1.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	idx2 := NewPackageIndex(indexPath)
+	pkg, ok := idx2.Lookup(path, 100, mtime)
+	if !ok {
+		t.Fatal("Entry lost after reload")
+	}
+	if len(pkg.Categories) != 1 || pkg.Categories[0] != "Hair" {
+		t.Errorf("Unexpected categories after reload: %v", pkg.Categories)
+	}
+
+	if n := idx2.InvalidateRoot("lib"); n != 1 {
+		t.Errorf("Expected 1 invalidated entry, got %d", n)
+	}
+	if idx2.Len() != 0 {
+		t.Errorf("Expected empty index after invalidation, got %d", idx2.Len())
+	}
+}
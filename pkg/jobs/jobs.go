@@ -0,0 +1,360 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job states
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job types used by the application
+const (
+	TypeScan      = "scan"
+	TypeInstall   = "install"
+	TypeResolve   = "resolve"
+	TypeIntegrity = "integrity"
+	TypeExport    = "export"
+)
+
+// MaxFinished is how many finished jobs are kept for clients to pick up results
+const MaxFinished = 100
+
+// progressInterval throttles progress notifications; state changes are always reported
+const progressInterval = 250 * time.Millisecond
+
+var (
+	ErrNotFound       = errors.New("job not found")
+	ErrNotCancellable = errors.New("job cannot be cancelled")
+)
+
+// Progress of a running job. Total is 0 when unknown.
+type Progress struct {
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+	Message string `json:"message,omitempty"`
+}
+
+// Job is a snapshot of a long running operation
+type Job struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	Target      string      `json:"target,omitempty"` // Library or file the job works on
+	Status      string      `json:"status"`
+	Progress    Progress    `json:"progress"`
+	Result      interface{} `json:"result,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Cancellable bool        `json:"cancellable"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	FinishedAt  *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// Name is the event name job updates are delivered under (see events.Event)
+func (j Job) Name() string {
+	return "job:update"
+}
+
+// Finished reports whether the job reached a final state
+func (j Job) Finished() bool {
+	return j.Status != StatusRunning
+}
+
+// Func is the work of a job. report updates the progress; ctx is cancelled by Cancel
+// (only honored when the job was started as cancellable).
+type Func func(ctx context.Context, report func(current, total int, message string)) (interface{}, error)
+
+// Options describe a job when it is started
+type Options struct {
+	Type        string
+	Target      string
+	Cancellable bool
+	// Replace cancels a running job of the same Type and Target, and waits for it, before this one
+	// runs. The check happens under the same lock as the registration, so of two concurrent starts
+	// one always replaces the other.
+	Replace bool
+}
+
+type entry struct {
+	job        Job
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	lastNotify time.Time
+}
+
+// Manager tracks every long running operation so the desktop and web UIs can list, follow and cancel them
+type Manager struct {
+	mu        sync.Mutex
+	jobs      map[string]*entry
+	listeners map[int]func(Job)
+	nextID    int
+	wg        sync.WaitGroup
+}
+
+// NewManager creates an empty job manager
+func NewManager() *Manager {
+	return &Manager{
+		jobs:      make(map[string]*entry),
+		listeners: make(map[int]func(Job)),
+	}
+}
+
+// Subscribe registers fn for every job change (start, throttled progress, finish).
+// fn is called from job goroutines. The returned function removes the listener.
+func (m *Manager) Subscribe(fn func(Job)) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextID
+	m.nextID++
+	m.listeners[id] = fn
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.listeners, id)
+	}
+}
+
+// Start runs fn in the background and returns the job right away
+func (m *Manager) Start(opts Options, fn Func) Job {
+	e, replaced := m.register(context.Background(), opts)
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		waitReplaced(replaced)
+		m.execute(e, fn)
+	}()
+	return e.job
+}
+
+// Run executes fn as a job on the calling goroutine, for operations that already block their caller
+// (e.g. an HTTP request). Cancelling ctx cancels the job as well.
+func (m *Manager) Run(ctx context.Context, opts Options, fn Func) (Job, error) {
+	e, replaced := m.register(ctx, opts)
+	waitReplaced(replaced)
+	return m.execute(e, fn)
+}
+
+// waitReplaced cancels the jobs a new one replaces and waits until they ended
+func waitReplaced(replaced []*entry) {
+	for _, old := range replaced {
+		old.cancel()
+		<-old.done
+	}
+}
+
+// register records a new running job. With opts.Replace it also returns the running jobs of the same
+// type and target, which the caller cancels before running the new one.
+func (m *Manager) register(parent context.Context, opts Options) (*entry, []*entry) {
+	ctx, cancel := context.WithCancel(parent)
+	e := &entry{
+		job: Job{
+			ID:          newID(),
+			Type:        opts.Type,
+			Target:      opts.Target,
+			Status:      StatusRunning,
+			Cancellable: opts.Cancellable,
+			CreatedAt:   time.Now(),
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	var replaced []*entry
+	m.mu.Lock()
+	if opts.Replace {
+		for _, old := range m.jobs {
+			if old.job.Type == opts.Type && old.job.Target == opts.Target && !old.job.Finished() {
+				replaced = append(replaced, old)
+			}
+		}
+	}
+	m.jobs[e.job.ID] = e
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	m.notify(e, true)
+	return e, replaced
+}
+
+// execute runs fn and records the outcome, returning the final state and fn's error
+func (m *Manager) execute(e *entry, fn Func) (Job, error) {
+	defer e.cancel()
+	result, err := fn(e.ctx, func(current, total int, message string) {
+		m.mu.Lock()
+		e.job.Progress = Progress{Current: current, Total: total, Message: message}
+		m.mu.Unlock()
+		m.notify(e, false)
+	})
+
+	m.mu.Lock()
+	now := time.Now()
+	e.job.FinishedAt = &now
+	switch {
+	case err == nil:
+		e.job.Status = StatusCompleted
+		e.job.Result = result
+	case errors.Is(err, context.Canceled) || e.ctx.Err() != nil:
+		e.job.Status = StatusCancelled
+		e.job.Error = err.Error()
+		e.job.Result = result
+	default:
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+		e.job.Result = result
+	}
+	job := e.job
+	m.mu.Unlock()
+	close(e.done)
+	m.notify(e, true)
+	return job, err
+}
+
+// notify sends the current state to every listener; progress updates are throttled
+func (m *Manager) notify(e *entry, force bool) {
+	m.mu.Lock()
+	now := time.Now()
+	if !force && now.Sub(e.lastNotify) < progressInterval {
+		m.mu.Unlock()
+		return
+	}
+	e.lastNotify = now
+	job := e.job
+	listeners := make([]func(Job), 0, len(m.listeners))
+	for _, fn := range m.listeners {
+		listeners = append(listeners, fn)
+	}
+	m.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(job)
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond MaxFinished (m.mu held)
+func (m *Manager) pruneLocked() {
+	var finished []*entry
+	for _, e := range m.jobs {
+		if e.job.Finished() {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= MaxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].job.CreatedAt.Before(finished[j].job.CreatedAt) })
+	for _, e := range finished[:len(finished)-MaxFinished] {
+		delete(m.jobs, e.job.ID)
+	}
+}
+
+// Get returns a job by ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// List returns every known job, newest first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Job, 0, len(m.jobs))
+	for _, e := range m.jobs {
+		list = append(list, e.job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Running returns the running jobs of a type
+func (m *Manager) Running(jobType string) []Job {
+	var running []Job
+	for _, j := range m.List() {
+		if j.Type == jobType && !j.Finished() {
+			running = append(running, j)
+		}
+	}
+	return running
+}
+
+// Cancel stops a running job and waits for it to end
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if !e.job.Cancellable {
+		job, _ := m.Get(id)
+		if job.Finished() {
+			return job, nil
+		}
+		return job, ErrNotCancellable
+	}
+	e.cancel()
+	<-e.done
+	job, _ := m.Get(id)
+	return job, nil
+}
+
+// Delete cancels a running job (it stays listed as cancelled) or forgets a finished one
+func (m *Manager) Delete(id string) (Job, error) {
+	job, ok := m.Get(id)
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if !job.Finished() {
+		return m.Cancel(id)
+	}
+	m.mu.Lock()
+	delete(m.jobs, id)
+	m.mu.Unlock()
+	return job, nil
+}
+
+// Wait blocks until a job finished
+func (m *Manager) Wait(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	<-e.done
+	job, _ := m.Get(id)
+	return job, nil
+}
+
+// CancelAll cancels every cancellable job and waits for background jobs to end
+func (m *Manager) CancelAll() {
+	m.mu.Lock()
+	for _, e := range m.jobs {
+		if e.job.Cancellable {
+			e.cancel()
+		}
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
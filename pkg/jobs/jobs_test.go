@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManager_StartCompletes(t *testing.T) {
+	m := NewManager()
+
+	var mu sync.Mutex
+	var states []string
+	unsubscribe := m.Subscribe(func(j Job) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, j.Status)
+	})
+	defer unsubscribe()
+
+	job := m.Start(Options{Type: TypeInstall, Target: "/lib"}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		report(1, 1, "A.var")
+		return 42, nil
+	})
+	if job.Status != StatusRunning || job.ID == "" {
+		t.Fatalf("Unexpected initial state %+v", job)
+	}
+
+	done, err := m.Wait(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != StatusCompleted || done.Result != 42 || done.FinishedAt == nil {
+		t.Errorf("Unexpected final state %+v", done)
+	}
+	if done.Progress.Current != 1 || done.Progress.Message != "A.var" {
+		t.Errorf("Progress not recorded: %+v", done.Progress)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(states) < 2 || states[0] != StatusRunning || states[len(states)-1] != StatusCompleted {
+		t.Errorf("Expected running ... completed notifications, got %v", states)
+	}
+}
+
+func blockUntilCancelled(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestManager_Cancel(t *testing.T) {
+	m := NewManager()
+
+	job := m.Start(Options{Type: TypeExport, Cancellable: true}, blockUntilCancelled)
+	cancelled, err := m.Cancel(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != StatusCancelled {
+		t.Errorf("Expected cancelled, got %s", cancelled.Status)
+	}
+
+	// Not cancellable jobs keep running
+	release := make(chan struct{})
+	fixed := m.Start(Options{Type: TypeInstall}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	if _, err := m.Cancel(fixed.ID); err != ErrNotCancellable {
+		t.Errorf("Expected ErrNotCancellable, got %v", err)
+	}
+	close(release)
+	if done, _ := m.Wait(fixed.ID); done.Status != StatusCompleted {
+		t.Errorf("Expected completed, got %s", done.Status)
+	}
+
+	if _, err := m.Cancel("missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestManager_Replace(t *testing.T) {
+	m := NewManager()
+	libA := m.Start(Options{Type: TypeIntegrity, Target: "/a", Cancellable: true, Replace: true}, blockUntilCancelled)
+	libB := m.Start(Options{Type: TypeIntegrity, Target: "/b", Cancellable: true, Replace: true}, blockUntilCancelled)
+	defer m.CancelAll()
+
+	// Another target is left alone
+	time.Sleep(20 * time.Millisecond)
+	if job, _ := m.Get(libA.ID); job.Finished() {
+		t.Fatal("A job for another target must not be replaced")
+	}
+
+	// The same target replaces the running job, concurrent starts never run side by side
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	exclusive := func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil, nil
+	}
+	var started []Job
+	var wg sync.WaitGroup
+	var startMu sync.Mutex
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job := m.Start(Options{Type: TypeIntegrity, Target: "/b", Cancellable: true, Replace: true}, exclusive)
+			startMu.Lock()
+			started = append(started, job)
+			startMu.Unlock()
+		}()
+	}
+	wg.Wait()
+	for _, job := range started {
+		m.Wait(job.ID)
+	}
+
+	if job, _ := m.Wait(libB.ID); job.Status != StatusCancelled {
+		t.Errorf("Expected the replaced job to be cancelled, got %s", job.Status)
+	}
+	if job, _ := m.Get(libA.ID); job.Finished() {
+		t.Error("A job for another target must keep running")
+	}
+	if maxActive > 1 {
+		t.Errorf("Expected replacing jobs to run one at a time, %d ran at once", maxActive)
+	}
+}
+
+func TestManager_Delete(t *testing.T) {
+	m := NewManager()
+
+	running := m.Start(Options{Type: TypeScan, Cancellable: true}, blockUntilCancelled)
+	job, err := m.Delete(running.ID)
+	if err != nil || job.Status != StatusCancelled {
+		t.Fatalf("Delete of a running job = %s, %v", job.Status, err)
+	}
+	// A cancelled job stays listed until deleted again
+	if _, ok := m.Get(running.ID); !ok {
+		t.Fatal("Cancelled job disappeared")
+	}
+	if _, err := m.Delete(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get(running.ID); ok {
+		t.Error("Finished job was not removed")
+	}
+	if len(m.List()) != 0 {
+		t.Errorf("Expected empty list, got %v", m.List())
+	}
+}
+
+func TestManager_RunReturnsError(t *testing.T) {
+	m := NewManager()
+	boom := errors.New("boom")
+
+	job, err := m.Run(context.Background(), Options{Type: TypeResolve}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		return nil, boom
+	})
+	if err != boom {
+		t.Errorf("Expected the original error, got %v", err)
+	}
+	if job.Status != StatusFailed || job.Error != "boom" {
+		t.Errorf("Unexpected state %+v", job)
+	}
+}
+
+func TestManager_PrunesFinishedJobs(t *testing.T) {
+	m := NewManager()
+	noop := func(ctx context.Context, report func(int, int, string)) (interface{}, error) { return nil, nil }
+
+	first, _ := m.Run(context.Background(), Options{Type: TypeInstall}, noop)
+	for i := 0; i < MaxFinished; i++ {
+		time.Sleep(time.Microsecond) // Distinct creation times
+		m.Run(context.Background(), Options{Type: TypeInstall}, noop)
+	}
+	// Pruning happens when the next job registers
+	m.Run(context.Background(), Options{Type: TypeInstall}, noop)
+
+	if _, ok := m.Get(first.ID); ok {
+		t.Error("Oldest finished job was not pruned")
+	}
+	if n := len(m.List()); n > MaxFinished+1 {
+		t.Errorf("Expected at most %d jobs, got %d", MaxFinished+1, n)
+	}
+}
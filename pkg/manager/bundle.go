@@ -0,0 +1,272 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"yavam/pkg/graph"
+	"yavam/pkg/models"
+	"yavam/pkg/scanner"
+	"yavam/pkg/utils"
+)
+
+// PackageSource lists the analyzed packages of a library, for filters and dependency closures.
+// The web server passes its cached snapshot; nil scans the library.
+type PackageSource func(ctx context.Context, libraryPath string) ([]models.VarPackage, error)
+
+// ResolveBundle turns a bulk download request into the list of package files to export.
+// Every file is checked with ValidatePath, a single disallowed path rejects the whole request.
+// Bundles are flat, so two different files with the same name are rejected as well.
+func (m *Manager) ResolveBundle(ctx context.Context, req models.BundleRequest, source PackageSource) ([]string, error) {
+	paths := append([]string{}, req.Paths...)
+
+	if req.Filter != nil || req.ClosureOf != "" {
+		if req.Library == "" {
+			return nil, fmt.Errorf("a library is required for filters and dependency closures")
+		}
+		if err := m.ValidatePath(req.Library); err != nil {
+			return nil, err
+		}
+		if req.ClosureOf != "" {
+			if err := m.ValidatePath(req.ClosureOf); err != nil {
+				return nil, err
+			}
+		}
+
+		if source == nil {
+			source = m.scanPackages
+		}
+		pkgs, err := source(ctx, req.Library)
+		if err != nil {
+			return nil, err
+		}
+		if req.Filter != nil {
+			for _, p := range pkgs {
+				if matchesFilter(p, *req.Filter) {
+					paths = append(paths, p.FilePath)
+				}
+			}
+		}
+		if req.ClosureOf != "" {
+			closure, ok := graph.Build(pkgs).Closure(req.ClosureOf)
+			if !ok {
+				return nil, fmt.Errorf("package not found in library: %s", req.ClosureOf)
+			}
+			paths = append(paths, req.ClosureOf)
+			for _, dep := range closure.Packages {
+				paths = append(paths, preferredPath(dep.FilePaths))
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	names := make(map[string]string)
+	var result []string
+	for _, p := range paths {
+		clean := filepath.Clean(p)
+		if seen[strings.ToLower(clean)] {
+			continue
+		}
+		seen[strings.ToLower(clean)] = true
+
+		name := strings.ToLower(filepath.Base(clean))
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("%s and %s have the same name, a bundle can only contain one of them", other, clean)
+		}
+		names[name] = clean
+
+		if err := m.ValidatePath(clean); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(clean)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() || !scanner.IsPackageFile(info.Name()) {
+			return nil, fmt.Errorf("not a package file: %s", clean)
+		}
+		result = append(result, clean)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no packages selected")
+	}
+	return result, nil
+}
+
+// scanPackages is the PackageSource used without a cached snapshot
+func (m *Manager) scanPackages(ctx context.Context, libraryPath string) ([]models.VarPackage, error) {
+	var pkgs []models.VarPackage
+	err := m.ScanAndAnalyze(ctx, libraryPath, func(p models.VarPackage) {
+		pkgs = append(pkgs, p)
+	}, nil)
+	return pkgs, err
+}
+
+// WriteBundle streams the files as a zip (store mode) to w, stopping when ctx is cancelled
+func (m *Manager) WriteBundle(ctx context.Context, w io.Writer, paths []string) error {
+	return utils.StreamZip(ctx, w, paths, nil)
+}
+
+// ExportBundle copies the resolved packages into destDir, or writes them into a single zip there.
+// onProgress (optional) is called after each file. Cancelling ctx stops between reads and removes the
+// partial file. Returns the folder or zip file written.
+func (m *Manager) ExportBundle(ctx context.Context, req models.BundleRequest, destDir string, asZip bool, onProgress func(current, total int, filename string)) (string, error) {
+	paths, err := m.ResolveBundle(ctx, req, nil)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(destDir); os.IsNotExist(err) {
+		return "", fmt.Errorf("destination directory does not exist: %s", destDir)
+	}
+
+	if asZip {
+		return writeBundleFile(ctx, destDir, paths, onProgress)
+	}
+
+	for i, p := range paths {
+		if err := m.copyPackage(ctx, p, destDir); err != nil {
+			return "", err
+		}
+		if onProgress != nil {
+			onProgress(i+1, len(paths), filepath.Base(p))
+		}
+	}
+	return destDir, nil
+}
+
+// ExportWithDependencies copies a package and its resolved dependency closure into destDir, or zips them there.
+// Copies go through CopyPackagesToLibrary, so existing files are reported as collisions unless overwrite is set.
+// Missing dependencies do not stop the export; they are reported (and announced with status "missing").
+func (m *Manager) ExportWithDependencies(ctx context.Context, libraryPath string, pkgPath string, destDir string, asZip bool, overwrite bool, onProgress func(current, total int, filename string, status string)) (*models.ClosureExport, error) {
+	if err := m.ValidatePath(pkgPath); err != nil {
+		return nil, err
+	}
+	closure, err := m.DependencyClosure(ctx, libraryPath, pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(destDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("destination directory does not exist: %s", destDir)
+	}
+
+	files := []string{pkgPath}
+	for _, dep := range closure.Packages {
+		files = append(files, preferredPath(dep.FilePaths))
+	}
+	result := &models.ClosureExport{
+		Root:       closure.Root.ID,
+		Files:      files,
+		Missing:    closure.Missing,
+		Collisions: []string{},
+	}
+	if onProgress != nil {
+		for _, id := range closure.Missing {
+			onProgress(0, 0, id, "missing")
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("[Manager] Exporting %s with %d dependencies (%d missing) to %s\n", closure.Root.ID, len(closure.Packages), len(closure.Missing), destDir)
+
+	if asZip {
+		var zipProgress func(cur, tot int, name string)
+		if onProgress != nil {
+			zipProgress = func(cur, tot int, name string) { onProgress(cur, tot, name, "zipping") }
+		}
+		target, err := writeBundleFile(ctx, destDir, files, zipProgress)
+		if err != nil {
+			return nil, err
+		}
+		result.Target = target
+		return result, nil
+	}
+
+	collisions, err := m.CopyPackagesToLibrary(ctx, files, destDir, overwrite, onProgress)
+	if collisions != nil {
+		result.Collisions = collisions
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Target = destDir
+	return result, nil
+}
+
+// writeBundleFile writes the files into a new timestamped zip inside destDir, removing it again on
+// failure or cancellation
+func writeBundleFile(ctx context.Context, destDir string, paths []string, onProgress func(current, total int, name string)) (string, error) {
+	target := filepath.Join(destDir, fmt.Sprintf("yavam_bundle_%s.zip", time.Now().Format("20060102_150405")))
+	f, err := os.Create(target)
+	if err != nil {
+		return "", err
+	}
+	if err := utils.StreamZip(ctx, f, paths, onProgress); err != nil {
+		f.Close()
+		os.Remove(target)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(target)
+		return "", err
+	}
+	return target, nil
+}
+
+// matchesFilter applies a saved library filter to one package
+func matchesFilter(p models.VarPackage, f models.PackageFilter) bool {
+	if f.Type != "" && !strings.EqualFold(p.Type, f.Type) {
+		found := false
+		for _, c := range p.Categories {
+			if strings.EqualFold(c, f.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Creator != "" && !strings.EqualFold(p.Meta.Creator, f.Creator) {
+		return false
+	}
+	if f.Tag != "" {
+		found := false
+		for _, t := range p.Tags {
+			if strings.EqualFold(t, f.Tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(p.FileName), strings.ToLower(f.Search)) {
+		return false
+	}
+	if f.Enabled != nil && p.IsEnabled != *f.Enabled {
+		return false
+	}
+	return true
+}
+
+// preferredPath picks the enabled copy of a package when several files provide it
+func preferredPath(paths []string) string {
+	for _, p := range paths {
+		if !strings.HasSuffix(strings.ToLower(p), ".disabled") {
+			return p
+		}
+	}
+	return paths[0]
+}
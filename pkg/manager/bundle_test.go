@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/models"
+	"yavam/pkg/services/config"
+	"yavam/pkg/services/library"
+	"yavam/pkg/services/system"
+)
+
+// MockSystemService reports plenty of free space so installs are not refused
+type MockSystemService struct {
+	system.SystemService
+}
+
+func (m *MockSystemService) GetDiskSpace(path string) (system.DiskSpaceInfo, error) {
+	return system.DiskSpaceInfo{Free: 1000000, Total: 2000000, TotalFree: 1000000}, nil
+}
+
+func TestResolveBundle(t *testing.T) {
+	lib := t.TempDir()
+	outside := t.TempDir()
+
+	pkgA := filepath.Join(lib, "Creator.A.1.var")
+	pkgB := filepath.Join(lib, "Creator.B.1.var.disabled")
+	notPkg := filepath.Join(lib, "notes.txt")
+	foreign := filepath.Join(outside, "Creator.C.1.var")
+	for _, p := range []string{pkgA, pkgB, notPkg, foreign} {
+		os.WriteFile(p, []byte("data"), 0644)
+	}
+
+	m := NewManager(nil, nil, &MockConfigService{cfg: &config.Config{Libraries: []string{lib}}})
+	ctx := context.Background()
+
+	paths, err := m.ResolveBundle(ctx, models.BundleRequest{Paths: []string{pkgA, pkgB, pkgA}}, nil)
+	if err != nil {
+		t.Fatalf("ResolveBundle failed: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("Expected 2 deduplicated paths, got %v", paths)
+	}
+
+	if _, err := m.ResolveBundle(ctx, models.BundleRequest{Paths: []string{pkgA, foreign}}, nil); err == nil {
+		t.Error("Expected a path outside the libraries to reject the bundle")
+	}
+	if _, err := m.ResolveBundle(ctx, models.BundleRequest{Paths: []string{notPkg}}, nil); err == nil {
+		t.Error("Expected non-package files to be rejected")
+	}
+	if _, err := m.ResolveBundle(ctx, models.BundleRequest{}, nil); err == nil {
+		t.Error("Expected an empty bundle to be rejected")
+	}
+	if _, err := m.ResolveBundle(ctx, models.BundleRequest{Filter: &models.PackageFilter{}}, nil); err == nil {
+		t.Error("Expected a filter without library to be rejected")
+	}
+
+	// Two files with the same name would overwrite each other in the bundle
+	sameName := filepath.Join(lib, "Sub", "Creator.A.1.var")
+	os.MkdirAll(filepath.Dir(sameName), 0755)
+	os.WriteFile(sameName, []byte("other build"), 0644)
+	if _, err := m.ResolveBundle(ctx, models.BundleRequest{Paths: []string{pkgA, sameName}}, nil); err == nil {
+		t.Error("Expected two files with the same name to be rejected")
+	}
+
+	// Filters read the packages from the given source instead of scanning
+	enabled := true
+	source := func(_ context.Context, library string) ([]models.VarPackage, error) {
+		return []models.VarPackage{{FilePath: pkgA, IsEnabled: true}, {FilePath: pkgB}}, nil
+	}
+	paths, err = m.ResolveBundle(ctx, models.BundleRequest{Library: lib, Filter: &models.PackageFilter{Enabled: &enabled}}, source)
+	if err != nil || len(paths) != 1 || paths[0] != pkgA {
+		t.Errorf("Expected the enabled package from the source, got %v (%v)", paths, err)
+	}
+}
+
+func TestMatchesFilter(t *testing.T) {
+	enabled := true
+	p := models.VarPackage{
+		FileName:  "Creator.Scene.1.var",
+		Type:      "Scene",
+		Tags:      []string{"Outdoor"},
+		IsEnabled: true,
+		Meta:      models.MetaJSON{Creator: "Creator"},
+	}
+
+	tests := []struct {
+		name   string
+		filter models.PackageFilter
+		want   bool
+	}{
+		{"Empty", models.PackageFilter{}, true},
+		{"Type", models.PackageFilter{Type: "scene"}, true},
+		{"Wrong Type", models.PackageFilter{Type: "Look"}, false},
+		{"Creator", models.PackageFilter{Creator: "creator"}, true},
+		{"Tag", models.PackageFilter{Tag: "outdoor"}, true},
+		{"Missing Tag", models.PackageFilter{Tag: "Indoor"}, false},
+		{"Search", models.PackageFilter{Search: "scene.1"}, true},
+		{"Enabled", models.PackageFilter{Enabled: &enabled}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(p, tt.filter); got != tt.want {
+				t.Errorf("matchesFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeVar(t *testing.T, path string, meta string) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, err := w.Create("meta.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(meta))
+	w.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportWithDependencies(t *testing.T) {
+	lib := t.TempDir()
+	dest := t.TempDir()
+
+	scene := filepath.Join(lib, "Creator.Scene.1.var")
+	writeVar(t, scene, `{"creatorName":"Creator","packageName":"Scene","dependencies":{"Creator.Look.latest":{"dependencies":{"Other.Morphs.2":{}}},"Gone.Pack.1":{}}}`)
+	writeVar(t, filepath.Join(lib, "Creator.Look.3.var"), `{"creatorName":"Creator","packageName":"Look","dependencies":{"Other.Morphs.2":{}}}`)
+	writeVar(t, filepath.Join(lib, "Other.Morphs.2.var"), `{"creatorName":"Other","packageName":"Morphs"}`)
+	writeVar(t, filepath.Join(lib, "Unrelated.Pack.1.var"), `{"creatorName":"Unrelated","packageName":"Pack"}`)
+	// Already at the destination: reported as collision, not overwritten
+	os.WriteFile(filepath.Join(dest, "Other.Morphs.2.var"), []byte("keep"), 0644)
+
+	sys := &MockSystemService{}
+	m := NewManager(sys, library.NewLibraryService(sys, nil), &MockConfigService{cfg: &config.Config{Libraries: []string{lib, dest}}})
+
+	var statuses []string
+	result, err := m.ExportWithDependencies(context.Background(), lib, scene, dest, false, false, func(_, _ int, filename string, status string) {
+		statuses = append(statuses, filename+":"+status)
+	})
+	if err != nil {
+		t.Fatalf("ExportWithDependencies failed: %v", err)
+	}
+
+	if len(result.Files) != 3 || result.Files[0] != scene {
+		t.Errorf("Expected scene plus 2 dependencies, got %v", result.Files)
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != "Gone.Pack.1" {
+		t.Errorf("Expected Gone.Pack.1 missing, got %v", result.Missing)
+	}
+	if len(result.Collisions) != 1 {
+		t.Errorf("Expected 1 collision, got %v", result.Collisions)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "Creator.Look.3.var")); err != nil {
+		t.Error("Dependency was not copied")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "Unrelated.Pack.1.var")); err == nil {
+		t.Error("Unrelated package must not be exported")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "Other.Morphs.2.var")); string(data) != "keep" {
+		t.Error("Existing file was overwritten")
+	}
+	if len(statuses) == 0 || statuses[0] != "Gone.Pack.1:missing" {
+		t.Errorf("Expected missing dependency to be announced first, got %v", statuses)
+	}
+
+	zipResult, err := m.ExportWithDependencies(context.Background(), lib, scene, dest, true, false, nil)
+	if err != nil {
+		t.Fatalf("Zip export failed: %v", err)
+	}
+	r, err := zip.OpenReader(zipResult.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if len(r.File) != 3 {
+		t.Errorf("Expected 3 packages in zip, got %d", len(r.File))
+	}
+}
+
+func TestExportWithDependencies_Cancelled(t *testing.T) {
+	lib := t.TempDir()
+	scene := filepath.Join(lib, "Creator.Scene.1.var")
+	writeVar(t, scene, `{"creatorName":"Creator","packageName":"Scene","dependencies":{"Creator.Look.1":{},"Other.Morphs.2":{}}}`)
+	writeVar(t, filepath.Join(lib, "Creator.Look.1.var"), `{"creatorName":"Creator","packageName":"Look"}`)
+	writeVar(t, filepath.Join(lib, "Other.Morphs.2.var"), `{"creatorName":"Other","packageName":"Morphs"}`)
+
+	sys := &MockSystemService{}
+	m := NewManager(sys, library.NewLibraryService(sys, nil), &MockConfigService{cfg: &config.Config{Libraries: []string{lib}}})
+
+	for _, asZip := range []bool{false, true} {
+		dest := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		// Cancelled while the first file is reported, as DELETE /api/jobs/{id} would
+		_, err := m.ExportWithDependencies(ctx, lib, scene, dest, asZip, false, func(current, _ int, _ string, _ string) {
+			if current == 1 {
+				cancel()
+			}
+		})
+		cancel()
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("zip=%v: expected context.Canceled, got %v", asZip, err)
+		}
+		entries, _ := os.ReadDir(dest)
+		if asZip && len(entries) != 0 {
+			t.Errorf("Expected the partial zip to be removed, found %d files", len(entries))
+		}
+		if !asZip && len(entries) != 1 {
+			t.Errorf("Expected the copy to stop after the first file, found %d files", len(entries))
+		}
+	}
+}
+
+func TestExportBundle(t *testing.T) {
+	lib := t.TempDir()
+	pkgA := filepath.Join(lib, "Creator.A.1.var")
+	pkgB := filepath.Join(lib, "Creator.B.1.var")
+	os.WriteFile(pkgA, []byte("a"), 0644)
+	os.WriteFile(pkgB, []byte("b"), 0644)
+	m := NewManager(nil, nil, &MockConfigService{cfg: &config.Config{Libraries: []string{lib}}})
+	req := models.BundleRequest{Paths: []string{pkgA, pkgB}}
+
+	dest := t.TempDir()
+	var reported []string
+	if _, err := m.ExportBundle(context.Background(), req, dest, false, func(current, total int, filename string) {
+		reported = append(reported, filename)
+	}); err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	if len(reported) != 2 {
+		t.Errorf("Expected progress for both files, got %v", reported)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "Creator.B.1.var")); string(data) != "b" {
+		t.Error("Package was not copied")
+	}
+
+	// Cancelled before the first file: nothing is left behind
+	dest = t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.ExportBundle(ctx, req, dest, false, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+		t.Errorf("Expected no files after cancelling, found %d", len(entries))
+	}
+}
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"fmt"
+	"yavam/pkg/events"
 	"yavam/pkg/services/config"
 )
 
@@ -32,7 +33,7 @@ func (m *Manager) SetLibraries(libs []string) error {
 	if m.config == nil {
 		return fmt.Errorf("config service not initialized")
 	}
-	return m.config.Update(func(c *config.Config) {
+	return m.updateLibraries(func(c *config.Config) {
 		c.Libraries = libs
 	})
 }
@@ -45,7 +46,7 @@ func (m *Manager) AddLibrary(path string) error {
 
 	// We need to check existence inside the Update/Lock to be atomic?
 	// Or check first? ConfigService.Update locks.
-	return m.config.Update(func(c *config.Config) {
+	return m.updateLibraries(func(c *config.Config) {
 		for _, l := range c.Libraries {
 			if l == path {
 				return // Already exists, logic handling? Update doesn't return error from specific logic easily.
@@ -60,7 +61,7 @@ func (m *Manager) RemoveLibrary(path string) error {
 	if m.config == nil {
 		return fmt.Errorf("config service not initialized")
 	}
-	return m.config.Update(func(c *config.Config) {
+	return m.updateLibraries(func(c *config.Config) {
 		newLibs := []string{}
 		for _, l := range c.Libraries {
 			if l != path {
@@ -71,6 +72,15 @@ func (m *Manager) RemoveLibrary(path string) error {
 	})
 }
 
+// updateLibraries applies fn to the config and announces the resulting library list
+func (m *Manager) updateLibraries(fn func(*config.Config)) error {
+	if err := m.config.Update(fn); err != nil {
+		return err
+	}
+	m.events.Publish(events.LibrariesChanged{Libraries: m.GetLibraries()})
+	return nil
+}
+
 // These methods were previously on *Manager in config.go or implicit?
 // We need to match whatever App expects.
 // App calls: GetLibraries, AddLibrary, RemoveLibrary, SetLibraries.
@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"context"
+	"yavam/pkg/graph"
+)
+
+// UsedBy lists the packages that directly depend on pkgPath
+func (m *Manager) UsedBy(ctx context.Context, libraryPath string, pkgPath string) ([]graph.NodeRef, error) {
+	return m.library.UsedBy(ctx, libraryPath, pkgPath)
+}
+
+// GraphRoots lists the packages of a library that nothing depends on
+func (m *Manager) GraphRoots(ctx context.Context, libraryPath string) ([]graph.NodeRef, error) {
+	return m.library.GraphRoots(ctx, libraryPath)
+}
+
+// GraphOrphans lists unreferenced dependency-only packages of a library
+func (m *Manager) GraphOrphans(ctx context.Context, libraryPath string) ([]graph.NodeRef, error) {
+	return m.library.GraphOrphans(ctx, libraryPath)
+}
+
+// DependencyClosure returns everything pkgPath needs, transitively
+func (m *Manager) DependencyClosure(ctx context.Context, libraryPath string, pkgPath string) (*graph.Closure, error) {
+	return m.library.DependencyClosure(ctx, libraryPath, pkgPath)
+}
+
+// DependencyCycles lists groups of packages that depend on each other
+func (m *Manager) DependencyCycles(ctx context.Context, libraryPath string) ([][]graph.NodeRef, error) {
+	return m.library.DependencyCycles(ctx, libraryPath)
+}
@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"yavam/pkg/events"
+	"yavam/pkg/models"
+)
+
+// InvalidateIndex drops the cached parse results for every package below libraryPath.
+// The next scan of that library re-opens every file.
+func (m *Manager) InvalidateIndex(libraryPath string) int {
+	if m.index == nil {
+		return 0
+	}
+	removed := m.index.InvalidateRoot(libraryPath)
+	if err := m.index.Save(); err != nil {
+		fmt.Printf("[Manager] Failed to save package index: %v\n", err)
+	}
+	m.events.Publish(events.IndexInvalidated{LibraryPath: libraryPath, Removed: removed})
+	return removed
+}
+
+// RebuildIndex invalidates the cached entries of a library and rescans it from scratch
+func (m *Manager) RebuildIndex(ctx context.Context, libraryPath string, onPackage func(models.VarPackage), onProgress func(int, int)) error {
+	m.InvalidateIndex(libraryPath)
+	return m.ScanAndAnalyze(ctx, libraryPath, onPackage, onProgress)
+}
@@ -3,16 +3,22 @@ package manager
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
+	"yavam/pkg/events"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/index"
+	"yavam/pkg/jobs"
 	"yavam/pkg/models"
+	"yavam/pkg/scans"
 	"yavam/pkg/services/config"
 	"yavam/pkg/services/library"
 	"yavam/pkg/services/system"
+	"yavam/pkg/thumbnails"
+	"yavam/pkg/utils"
 )
 
 type Manager struct {
@@ -22,6 +28,11 @@ type Manager struct {
 	mu       sync.Mutex
 	DataPath string
 	config   config.ConfigService
+	index    *index.PackageIndex
+	text     *fulltext.Index
+	scans    *scans.Coordinator
+	jobs     *jobs.Manager
+	events   *events.Bus
 }
 
 func (m *Manager) GetConfig() *config.Config {
@@ -34,7 +45,21 @@ func (m *Manager) UpdateConfig(fn func(*config.Config)) error {
 
 // Close cleans up resources
 func (m *Manager) Close() error {
-	// Placeholder for future cleanup (e.g. database connections)
+	// Running scans write to the indexes, stop them before saving
+	if m.scans != nil {
+		m.scans.CancelAll()
+	}
+	if m.jobs != nil {
+		m.jobs.CancelAll()
+	}
+	if m.text != nil {
+		if err := m.text.Save(); err != nil {
+			fmt.Printf("[Manager] Failed to save full-text index: %v\n", err)
+		}
+	}
+	if m.index != nil {
+		return m.index.Save()
+	}
 	return nil
 }
 
@@ -58,21 +83,80 @@ func NewManager(sys system.SystemService, lib library.LibraryService, cfg config
 		lib = library.NewLibraryService(sys, nil)
 	}
 
+	// Parse results are cached between scans (and restarts) so unchanged packages are not re-opened
+	idx := index.NewPackageIndex(filepath.Join(dataPath, "cache", "package_index.json"))
+	lib.SetIndex(idx)
+	lib.SetThumbnailCache(thumbnails.NewCache(filepath.Join(dataPath, "cache", "thumbnails")))
+	text := fulltext.NewIndex(filepath.Join(dataPath, "cache", "fulltext_index.json"))
+	lib.SetFullTextIndex(text)
+	// Every change is published once and delivered to the desktop and web clients alike
+	bus := events.NewBus()
+	lib.SetEventBus(bus)
+
 	m := &Manager{
 		system:   sys,
 		library:  lib,
 		config:   cfg,
 		DataPath: dataPath,
+		index:    idx,
+		text:     text,
+		events:   bus,
 	}
+	m.jobs = jobs.NewManager()
+	m.jobs.Subscribe(func(j jobs.Job) {
+		bus.Publish(j)
+	})
+	m.scans = scans.NewCoordinator(m.scanJob)
 
 	return m
 }
 
+// Scans returns the coordinator shared by the desktop and web UIs, one running scan per library
+func (m *Manager) Scans() *scans.Coordinator {
+	return m.scans
+}
+
+// Events returns the bus every change to the libraries is published on
+func (m *Manager) Events() *events.Bus {
+	return m.events
+}
+
+// Jobs returns the registry of long running operations shared by the desktop and web UIs
+func (m *Manager) Jobs() *jobs.Manager {
+	return m.jobs
+}
+
+// scanJob runs a coordinated scan as a job, so it is listed and can be cancelled for every subscriber
+func (m *Manager) scanJob(ctx context.Context, rootPath string, onPackage func(models.VarPackage), onProgress func(int, int)) error {
+	_, err := m.jobs.Run(ctx, jobs.Options{Type: jobs.TypeScan, Target: rootPath, Cancellable: true}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		count := 0
+		err := m.ScanAndAnalyze(ctx, rootPath, func(p models.VarPackage) {
+			count++
+			onPackage(p)
+		}, func(current, total int) {
+			report(current, total, "")
+			onProgress(current, total)
+		})
+		return map[string]int{"packages": count}, err
+	})
+	return err
+}
+
 // ScanAndAnalyze delegates to LibraryService
 func (m *Manager) ScanAndAnalyze(ctx context.Context, rootPath string, onPackage func(models.VarPackage), onProgress func(int, int)) error {
 	return m.library.Scan(ctx, rootPath, onPackage, onProgress)
 }
 
+// GetPackage analyzes a single package file (served from the index when unchanged)
+func (m *Manager) GetPackage(pkgPath string) (models.VarPackage, error) {
+	return m.library.GetPackage(pkgPath)
+}
+
+// GetPackageDetails returns contents plus undeclared/unused dependency references
+func (m *Manager) GetPackageDetails(pkgPath string) (*models.PackageDetails, error) {
+	return m.library.GetPackageDetails(pkgPath)
+}
+
 // GetPackageContents delegates to LibraryService
 func (m *Manager) GetPackageContents(pkgPath string) ([]models.PackageContent, error) {
 	return m.library.GetPackageContents(pkgPath)
@@ -92,15 +176,16 @@ func (m *Manager) TogglePackage(pkgs []models.VarPackage, pkgID string, enable b
 }
 
 // InstallPackage delegates to LibraryService to copy files to the library. Overwrite is forced.
-func (m *Manager) InstallPackage(files []string, vamPath string, onProgress func(current, total int)) ([]string, error) {
-	return m.library.Install(files, vamPath, true, func(c, t int, f string) {
+func (m *Manager) InstallPackage(ctx context.Context, files []string, vamPath string, onProgress func(current, total int)) ([]string, error) {
+	return m.library.Install(ctx, files, vamPath, true, func(c, t int, f string) {
 		if onProgress != nil {
 			onProgress(c, t)
 		}
 	})
 }
 
-func (m *Manager) checkDependencies(pkgs []models.VarPackage) []models.VarPackage {
+// CheckDependencies classifies the dependencies of every package against the enabled packages of the same set
+func (m *Manager) CheckDependencies(pkgs []models.VarPackage) []models.VarPackage {
 	return m.library.CheckDependencies(pkgs)
 }
 
@@ -163,7 +248,11 @@ func (m *Manager) OpenFolder(path string) error {
 }
 
 func (m *Manager) DeleteToTrash(path string) error {
-	return m.system.DeleteToTrash(path)
+	if err := m.system.DeleteToTrash(path); err != nil {
+		return err
+	}
+	m.events.Publish(events.PackageDeleted{Path: path})
+	return nil
 }
 
 // CopyFileToClipboard copies the file object to the clipboard (so it can be pasted in Explorer)
@@ -179,6 +268,11 @@ func (m *Manager) CutFileToClipboard(path string) error {
 
 // DownloadPackage copies the package to the destination folder
 func (m *Manager) DownloadPackage(pkgPath string, destDir string) error {
+	return m.copyPackage(context.Background(), pkgPath, destDir)
+}
+
+// copyPackage copies one package into destDir, removing the partial copy on failure or cancellation
+func (m *Manager) copyPackage(ctx context.Context, pkgPath string, destDir string) error {
 	// Check source
 	sourceFile, err := os.Open(pkgPath)
 	if err != nil {
@@ -200,10 +294,33 @@ func (m *Manager) DownloadPackage(pkgPath string, destDir string) error {
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+	_, err = utils.CopyContext(ctx, destFile, sourceFile)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destPath)
+		return err
+	}
+	m.events.Publish(events.PackagesInstalled{Folder: destDir, Files: []string{destPath}})
+	return nil
+}
+
+// SalvagePackage rebuilds a damaged package from its still-valid entries into destDir (same file name).
+// The original is never modified; quarantine or delete it separately once the result is checked.
+func (m *Manager) SalvagePackage(pkgPath string, destDir string) (*utils.SalvageReport, error) {
+	if _, err := os.Stat(destDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("destination directory does not exist: %s", destDir)
+	}
+	report, err := utils.SalvageZip(pkgPath, filepath.Join(destDir, filepath.Base(pkgPath)))
+	if report != nil {
+		fmt.Printf("[Manager] Salvage of %s: %d entries recovered, %d lost\n", filepath.Base(pkgPath), len(report.Recovered), len(report.Lost))
+	}
+	if err == nil {
+		m.events.Publish(events.PackagesInstalled{Folder: destDir, Files: []string{report.Output}})
+	}
+	return report, err
 }
 
 // ResolveConflicts handles deduplication and cleanup of conflicting packages
@@ -212,9 +329,42 @@ func (m *Manager) ResolveConflicts(keepPath string, others []string, libraryPath
 	return m.library.ResolveConflicts(keepPath, others, libraryPath)
 }
 
+// FindDuplicates reports byte-identical package files across all configured libraries
+func (m *Manager) FindDuplicates(ctx context.Context) ([]models.DuplicateGroup, error) {
+	return m.library.FindDuplicates(ctx, m.GetLibraries())
+}
+
+// VerifyIntegrity runs the deep CRC check over a library and returns the packages that failed
+func (m *Manager) VerifyIntegrity(ctx context.Context, libraryPath string, onProgress func(current, total int, p models.VarPackage)) ([]models.VarPackage, error) {
+	return m.library.VerifyIntegrity(ctx, libraryPath, onProgress)
+}
+
+// StartIntegrityCheck replaces a running integrity check of the same library (desktop or web) with a
+// background job for libraryPath; checks of other libraries keep running. The job result is the list of
+// packages that failed.
+func (m *Manager) StartIntegrityCheck(libraryPath string, onProgress func(current, total int, p models.VarPackage)) jobs.Job {
+	opts := jobs.Options{Type: jobs.TypeIntegrity, Target: libraryPath, Cancellable: true, Replace: true}
+	return m.jobs.Start(opts, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+		return m.VerifyIntegrity(ctx, libraryPath, func(current, total int, p models.VarPackage) {
+			report(current, total, p.FileName)
+			onProgress(current, total, p)
+		})
+	})
+}
+
+// CancelIntegrityCheck stops the running integrity check of libraryPath (every library when empty)
+// and waits for it
+func (m *Manager) CancelIntegrityCheck(libraryPath string) {
+	for _, j := range m.jobs.Running(jobs.TypeIntegrity) {
+		if libraryPath == "" || j.Target == libraryPath {
+			m.jobs.Cancel(j.ID)
+		}
+	}
+}
+
 // CopyPackagesToLibrary copies a list of package files to a destination library
 // Returns list of collided filenames (if overwrite=false) or error
-func (m *Manager) CopyPackagesToLibrary(filePaths []string, destLibPath string, overwrite bool, onProgress func(current, total int, filename string, status string)) ([]string, error) {
+func (m *Manager) CopyPackagesToLibrary(ctx context.Context, filePaths []string, destLibPath string, overwrite bool, onProgress func(current, total int, filename string, status string)) ([]string, error) {
 	fmt.Printf("[Manager] CopyPackagesToLibrary called. Dest: %s, Overwrite: %v, Count: %d\n", destLibPath, overwrite, len(filePaths))
 
 	var collisions []string
@@ -262,7 +412,7 @@ func (m *Manager) CopyPackagesToLibrary(filePaths []string, destLibPath string,
 		// We use overwrite=true for the filtered list because we already handled collisions manually
 		// Or overwrite=overwrite (which is false), but list is filtered so it shouldn't matter.
 		// Using overwrite=true ensures we force copy the 'safe' ones.
-		_, err := m.library.Install(filesToInstall, destLibPath, true, wrapperProgress)
+		_, err := m.library.Install(ctx, filesToInstall, destLibPath, true, wrapperProgress)
 		if err != nil {
 			// If error mentions "ignored", it might be partial success.
 			// Ideally we return collisions (if any) and the error.
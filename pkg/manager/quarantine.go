@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"yavam/pkg/models"
+)
+
+// Quarantine moves a package of libraryPath into the library's quarantine folder
+func (m *Manager) Quarantine(pkgPath string, libraryPath string, reason string) (*models.QuarantineEntry, error) {
+	if !m.validatePath(pkgPath, libraryPath) {
+		return nil, fmt.Errorf("access denied: %s is not a package of %s", pkgPath, libraryPath)
+	}
+	if reason == "" {
+		reason = "manual"
+	}
+	return m.library.Quarantine(pkgPath, libraryPath, reason)
+}
+
+// Restore moves a quarantined package back to where it came from
+func (m *Manager) Restore(libraryPath string, id string) (string, error) {
+	return m.library.Restore(libraryPath, id)
+}
+
+// ListQuarantine returns the quarantine records of a library
+func (m *Manager) ListQuarantine(libraryPath string) ([]models.QuarantineEntry, error) {
+	return m.library.ListQuarantine(libraryPath)
+}
+
+// QuarantineCorrupt quarantines every package the scan flags as corrupt.
+// Scan results come from the package index, so packages failing a previous integrity check are included
+// without re-reading them. Failures to move single files are logged and skipped.
+func (m *Manager) QuarantineCorrupt(ctx context.Context, libraryPath string) ([]models.QuarantineEntry, error) {
+	var corrupt []models.VarPackage
+	err := m.ScanAndAnalyze(ctx, libraryPath, func(p models.VarPackage) {
+		if p.IsCorrupt {
+			corrupt = append(corrupt, p)
+		}
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := []models.QuarantineEntry{}
+	for _, p := range corrupt {
+		if err := ctx.Err(); err != nil {
+			return moved, err
+		}
+		entry, err := m.Quarantine(p.FilePath, libraryPath, corruptReason(p))
+		if err != nil {
+			fmt.Printf("[Manager] Failed to quarantine %s: %v\n", p.FileName, err)
+			continue
+		}
+		moved = append(moved, *entry)
+	}
+	return moved, nil
+}
+
+// corruptReason describes why a package was flagged, using the integrity verdict when there is one
+func corruptReason(p models.VarPackage) string {
+	if p.Integrity != nil && p.Integrity.Status != models.IntegrityOK {
+		if p.Integrity.Entry != "" {
+			return fmt.Sprintf("corrupt: %s in %s", p.Integrity.Status, p.Integrity.Entry)
+		}
+		return "corrupt: " + p.Integrity.Status
+	}
+	return "corrupt: archive could not be opened"
+}
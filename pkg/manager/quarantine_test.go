@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/services/config"
+	"yavam/pkg/services/library"
+)
+
+func TestQuarantineCorrupt(t *testing.T) {
+	lib := t.TempDir()
+	writeVar(t, filepath.Join(lib, "Creator.Good.1.var"), `{"creatorName":"Creator","packageName":"Good"}`)
+	broken := filepath.Join(lib, "Creator.Broken.1.var")
+	os.WriteFile(broken, []byte("not a zip"), 0644)
+
+	sys := &MockSystemService{}
+	m := NewManager(sys, library.NewLibraryService(sys, nil), &MockConfigService{cfg: &config.Config{Libraries: []string{lib}}})
+
+	moved, err := m.QuarantineCorrupt(context.Background(), lib)
+	if err != nil {
+		t.Fatalf("QuarantineCorrupt failed: %v", err)
+	}
+	if len(moved) != 1 || moved[0].OriginalPath != broken {
+		t.Fatalf("Expected only the broken package to be quarantined, got %v", moved)
+	}
+	if moved[0].Reason != "corrupt: archive could not be opened" {
+		t.Errorf("Unexpected reason %q", moved[0].Reason)
+	}
+	if _, err := os.Stat(filepath.Join(lib, "Creator.Good.1.var")); err != nil {
+		t.Error("Healthy package must stay in the library")
+	}
+
+	if _, err := m.Quarantine(filepath.Join(t.TempDir(), "Other.Pkg.1.var"), lib, ""); err == nil {
+		t.Error("Expected packages outside the library to be rejected")
+	}
+}
@@ -0,0 +1,33 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"yavam/pkg/models"
+	"yavam/pkg/search"
+)
+
+// Search runs a query (see search.Query) over a library. Packages come from the scan, which serves
+// unchanged files from the package index, and carry their dependency status so missing: works.
+// The web server searches its package snapshot instead (see server.librarySnapshot).
+func (m *Manager) Search(ctx context.Context, libraryPath string, query string, opts search.Options) (*search.Result, error) {
+	q, err := search.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if !search.ValidSort(opts.Sort) {
+		return nil, fmt.Errorf("unknown sort mode %q", opts.Sort)
+	}
+
+	var pkgs []models.VarPackage
+	err = m.ScanAndAnalyze(ctx, libraryPath, func(p models.VarPackage) {
+		pkgs = append(pkgs, p)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	pkgs = m.CheckDependencies(pkgs)
+
+	result := search.Run(pkgs, q, opts)
+	return &result, nil
+}
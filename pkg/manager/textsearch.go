@@ -0,0 +1,29 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"yavam/pkg/fulltext"
+)
+
+// SearchText queries the full-text index built during scans. Hits are limited to libraryPath when given,
+// otherwise to all configured libraries, so packages of removed libraries never show up.
+func (m *Manager) SearchText(query string, libraryPath string, limit int) ([]fulltext.Hit, error) {
+	if m.text == nil {
+		return []fulltext.Hit{}, nil
+	}
+	accept := func(pkgPath string) bool {
+		return m.ValidatePath(pkgPath) == nil
+	}
+	if libraryPath != "" {
+		if err := m.ValidatePath(libraryPath); err != nil {
+			return nil, err
+		}
+		prefix := strings.ToLower(filepath.Clean(libraryPath)) + string(os.PathSeparator)
+		accept = func(pkgPath string) bool {
+			return strings.HasPrefix(strings.ToLower(filepath.Clean(pkgPath)), prefix)
+		}
+	}
+	return m.text.Search(query, limit, accept), nil
+}
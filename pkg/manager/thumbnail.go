@@ -1,12 +1,11 @@
 package manager
 
-import "yavam/pkg/parser"
-
-// GetThumbnail extracts the thumbnail from a package
+// GetThumbnail returns the original thumbnail of a package (served from the thumbnail cache)
 func (m *Manager) GetThumbnail(filePath string) ([]byte, error) {
-	_, thumbBytes, _, err := parser.ParseVarMetadata(filePath)
-	if err != nil {
-		return nil, err
-	}
-	return thumbBytes, nil
+	return m.library.GetThumbnail(filePath)
+}
+
+// GetThumbnailFile returns the cached thumbnail variant closest to size (0 for the original)
+func (m *Manager) GetThumbnailFile(filePath string, size int) (string, error) {
+	return m.library.GetThumbnailFile(filePath, size)
 }
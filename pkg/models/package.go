@@ -1,15 +1,46 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+)
+
 type MetaJSON struct {
-	Creator      string                 `json:"creator"`
-	CreatorName  string                 `json:"creatorName,omitempty"` // Alternative to creator
-	PackageName  string                 `json:"packageName"`
-	Version      string                 `json:"version"`
-	Description  string                 `json:"description,omitempty"`
-	Dependencies map[string]interface{} `json:"dependencies,omitempty"`
-	ContentList  []string               `json:"contentList,omitempty"`
-	Tags         []string               `json:"tags,omitempty"`
-	ImageUrl     string                 `json:"imageUrl,omitempty"`
+	Creator      string                `json:"creator"`
+	CreatorName  string                `json:"creatorName,omitempty"` // Alternative to creator
+	PackageName  string                `json:"packageName"`
+	Version      string                `json:"version"`
+	Description  string                `json:"description,omitempty"`
+	LicenseType  string                `json:"licenseType,omitempty"`
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"`
+	ContentList  []string              `json:"contentList,omitempty"`
+	Tags         []string              `json:"tags,omitempty"`
+	ImageUrl     string                `json:"imageUrl,omitempty"`
+}
+
+// Dependency is an entry of meta.json "dependencies", keyed by Creator.Package.Version.
+// VaM embeds each dependency's own requirements recursively, so the tree describes
+// transitive requirements even when the intermediate packages are not installed.
+type Dependency struct {
+	LicenseType  string                `json:"licenseType,omitempty"`
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"`
+}
+
+// UnmarshalJSON accepts the object form VaM writes as well as the plain strings
+// (license or URL) and nulls found in hand-written meta.json files
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		*d = Dependency{}
+		return nil
+	}
+	type plain Dependency
+	err := json.Unmarshal(trimmed, (*plain)(d))
+	if _, ok := err.(*json.UnmarshalTypeError); ok {
+		// Keep what could be decoded, one odd field must not drop the rest of meta.json
+		return nil
+	}
+	return err
 }
 
 type VarPackage struct {
@@ -21,7 +52,7 @@ type VarPackage struct {
 	ThumbnailBase64 string   `json:"thumbnailBase64"`
 	IsEnabled       bool     `json:"isEnabled"`
 	HasThumbnail    bool     `json:"hasThumbnail"`
-	MissingDeps     []string `json:"missingDeps"`
+	MissingDeps     []string `json:"missingDeps"` // Kept for older clients, derived from DependencyStatus
 	IsDuplicate     bool     `json:"isDuplicate"`
 	IsFavorite      bool     `json:"isFavorite"`
 	IsHidden        bool     `json:"isHidden"`
@@ -29,7 +60,51 @@ type VarPackage struct {
 	Categories      []string `json:"categories"`
 	Tags            []string `json:"tags,omitempty"`
 	CreationDate    string   `json:"creationDate"` // ISO 8601
+	ModTime         int64    `json:"-"`            // UnixNano from the file system, keys the package index
 	IsCorrupt       bool     `json:"isCorrupt"`
+	Hash            string   `json:"hash,omitempty"` // SHA-256 of the file contents (hex)
+
+	// Result of the last deep integrity check, nil if the package was never verified
+	Integrity *IntegrityResult `json:"integrity,omitempty"`
+
+	DependencyStatus []DependencyStatus `json:"dependencyStatus,omitempty"`
+	// Requirements declared deeper in the meta.json tree that nothing installed satisfies
+	MissingTransitiveDeps []string `json:"missingTransitiveDeps,omitempty"`
+}
+
+// Integrity check outcomes (see parser.VerifyArchive)
+const (
+	IntegrityOK        = "ok"
+	IntegrityTruncated = "truncated"  // File or entry ends early (interrupted download or copy)
+	IntegrityBadCRC    = "bad-crc"    // Entry data does not match its checksum (bit rot)
+	IntegrityBadHeader = "bad-header" // Central directory or local header unreadable
+)
+
+// IntegrityResult is the outcome of reading every entry of a package and verifying its CRC32
+type IntegrityResult struct {
+	Status    string `json:"status"`
+	Entry     string `json:"entry,omitempty"` // First failing entry
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checkedAt"` // ISO 8601
+}
+
+// Dependency resolution outcomes, following VaM's latest / minN / exact version forms
+const (
+	DependencySatisfiedExact       = "satisfied-exact"
+	DependencySatisfiedByNewer     = "satisfied-by-newer"
+	DependencySatisfiedByOlderOnly = "satisfied-by-older-only"
+	DependencyMissing              = "missing"
+)
+
+// DependencyStatus is the resolution of one meta.json dependency against the library
+type DependencyStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ResolvedID   string `json:"resolvedId,omitempty"`
+	ResolvedPath string `json:"resolvedPath,omitempty"`
+	LicenseType  string `json:"licenseType,omitempty"`
+	Transitive   bool   `json:"transitive,omitempty"` // Declared by another dependency, not by the package itself
+	Via          string `json:"via,omitempty"`        // Declaring dependency for transitive entries
 }
 
 type PackageContent struct {
@@ -40,6 +115,14 @@ type PackageContent struct {
 	Size            int64  `json:"size"`
 }
 
+// ContentItem is a file inside a package that users search for (scene, look, clothing or hair item, ...)
+type ContentItem struct {
+	Path string   `json:"path"` // Path inside the .var
+	Name string   `json:"name"` // File name without extension
+	Type string   `json:"type"`
+	Tags []string `json:"tags,omitempty"` // From .vam items
+}
+
 type ScanResult struct {
 	Packages []VarPackage `json:"packages"`
 	Tags     []string     `json:"tags"`
@@ -52,6 +135,72 @@ type ResolveConflictResult struct {
 	NewPath  string `json:"newPath"`
 }
 
+// DuplicateGroup lists byte-identical package files (same SHA-256), possibly across libraries
+type DuplicateGroup struct {
+	Hash  string       `json:"hash"`
+	Size  int64        `json:"size"`
+	Files []FileDetail `json:"files"`
+}
+
+// PackageReference is a package referenced from a content file (scene, preset, item JSON)
+type PackageReference struct {
+	ID   string `json:"id"`   // As written in the reference, e.g. Creator.Package.latest
+	File string `json:"file"` // First file inside the .var containing the reference
+}
+
+// ReferenceAnalysis compares the packages a .var actually references with its meta.json dependencies
+type ReferenceAnalysis struct {
+	Referenced []PackageReference `json:"referenced"`
+	Undeclared []PackageReference `json:"undeclared"` // Referenced but not declared: loads with missing items
+	Unused     []string           `json:"unused"`     // Declared but never referenced
+}
+
+// PackageDetails bundles everything the details panel shows for one package
+type PackageDetails struct {
+	Contents   []PackageContent   `json:"contents"`
+	References *ReferenceAnalysis `json:"references"`
+}
+
+// PackageFilter selects packages of a library, mirroring the filters of the library view.
+// Empty fields match everything.
+type PackageFilter struct {
+	Type    string `json:"type,omitempty"`
+	Creator string `json:"creator,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+	Search  string `json:"search,omitempty"`  // Case-insensitive substring of the file name
+	Enabled *bool  `json:"enabled,omitempty"` // nil matches both
+}
+
+// BundleRequest describes the packages of a bulk download. The sources are combined.
+type BundleRequest struct {
+	Paths     []string       `json:"paths,omitempty"`
+	Library   string         `json:"library,omitempty"`   // Required for Filter and ClosureOf
+	Filter    *PackageFilter `json:"filter,omitempty"`    // Saved filter applied to the library
+	ClosureOf string         `json:"closureOf,omitempty"` // Package included with its dependency closure
+}
+
+// ClosureExport reports the result of exporting a package with its dependencies
+type ClosureExport struct {
+	Root       string   `json:"root"`       // Package ID of the exported package
+	Files      []string `json:"files"`      // Package files exported, the root first
+	Missing    []string `json:"missing"`    // Dependency IDs the library cannot provide
+	Collisions []string `json:"collisions"` // File names skipped because they exist at the destination
+	Target     string   `json:"target"`     // Destination folder or zip file
+}
+
+// QuarantineEntry is the JSON sidecar kept next to a quarantined package
+type QuarantineEntry struct {
+	ID            string           `json:"id"` // Name of the file inside the quarantine folder (without extension)
+	FileName      string           `json:"fileName"`
+	OriginalPath  string           `json:"originalPath"`
+	LibraryPath   string           `json:"libraryPath"`
+	Reason        string           `json:"reason"`
+	Integrity     *IntegrityResult `json:"integrity,omitempty"`
+	Size          int64            `json:"size"`
+	Hash          string           `json:"hash,omitempty"`
+	QuarantinedAt string           `json:"quarantinedAt"` // ISO 8601
+}
+
 // FileDetail represents basic file information for UI display
 type FileDetail struct {
 	Name string `json:"name"`
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+	"yavam/pkg/models"
+)
+
+// VerifyArchive reads every entry of a .var to the end so archive/zip checks its CRC32.
+// ParseVarMetadata only opens the central directory; this catches truncated files and bit rot inside entries.
+// The first failing entry stops the check. Cancelling ctx returns ctx.Err().
+func VerifyArchive(ctx context.Context, filePath string) (models.IntegrityResult, error) {
+	result := models.IntegrityResult{
+		Status:    models.IntegrityOK,
+		CheckedAt: time.Now().Format(time.RFC3339),
+	}
+
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		result.Status = classifyOpenError(filePath, err)
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer r.Close()
+
+	buf := make([]byte, 64*1024)
+	for _, f := range r.File {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if err := verifyEntry(ctx, f, buf); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Status = classifyEntryError(err)
+			result.Entry = f.Name
+			result.Error = err.Error()
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+func verifyEntry(ctx context.Context, f *zip.File, buf []byte) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	for {
+		// Large textures can take a while, check for cancellation between chunks
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := rc.Read(buf)
+		if err == io.EOF {
+			return nil // The CRC is verified by the reader when it reaches EOF
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// classifyEntryError maps archive/zip and flate errors to an integrity status
+func classifyEntryError(err error) string {
+	var corrupt flate.CorruptInputError
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return models.IntegrityTruncated
+	case errors.Is(err, zip.ErrChecksum), errors.As(err, &corrupt):
+		return models.IntegrityBadCRC
+	default:
+		// zip.ErrFormat (damaged local header), zip.ErrAlgorithm and anything else
+		return models.IntegrityBadHeader
+	}
+}
+
+// classifyOpenError tells a cut-off download (starts like a zip, end record missing) from a damaged header
+func classifyOpenError(filePath string, err error) string {
+	if errors.Is(err, zip.ErrFormat) {
+		f, openErr := os.Open(filePath)
+		if openErr == nil {
+			defer f.Close()
+			sig := make([]byte, 4)
+			if _, readErr := io.ReadFull(f, sig); readErr == nil && bytes.Equal(sig, []byte("PK\x03\x04")) {
+				return models.IntegrityTruncated
+			}
+		}
+	}
+	return models.IntegrityBadHeader
+}
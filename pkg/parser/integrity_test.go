@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/models"
+)
+
+func TestVerifyArchive(t *testing.T) {
+	payload := bytes.Repeat([]byte("texture-data-"), 1000)
+
+	// Stored entries keep the payload verbatim, so single bytes can be damaged on purpose
+	buildVar := func() []byte {
+		buf := new(bytes.Buffer)
+		w := zip.NewWriter(buf)
+		for _, e := range []struct {
+			name string
+			data []byte
+		}{
+			{"meta.json", []byte(`{"creatorName":"Creator","packageName":"Pkg"}`)},
+			{"Custom/Atom/Person/Textures/skin.jpg", payload},
+		} {
+			f, err := w.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Write(e.data)
+		}
+		w.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name      string
+		damage    func(data []byte) []byte
+		wantState string
+		wantEntry string
+	}{
+		{"Intact", func(d []byte) []byte { return d }, models.IntegrityOK, ""},
+		{"Bit Rot", func(d []byte) []byte {
+			d[bytes.Index(d, payload)+500] ^= 0xFF
+			return d
+		}, models.IntegrityBadCRC, "Custom/Atom/Person/Textures/skin.jpg"},
+		{"Bad Local Header", func(d []byte) []byte {
+			second := bytes.Index(d[4:], []byte("PK\x03\x04")) + 4
+			d[second+3] = 0x09
+			return d
+		}, models.IntegrityBadHeader, "Custom/Atom/Person/Textures/skin.jpg"},
+		{"Cut Off Download", func(d []byte) []byte { return d[:len(d)/2] }, models.IntegrityTruncated, ""},
+		{"Not A Zip", func(d []byte) []byte { return []byte("definitely not a zip archive") }, models.IntegrityBadHeader, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Creator.Pkg.1.var")
+			if err := os.WriteFile(path, tt.damage(buildVar()), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := VerifyArchive(context.Background(), path)
+			if err != nil {
+				t.Fatalf("VerifyArchive failed: %v", err)
+			}
+			if result.Status != tt.wantState {
+				t.Errorf("Status = %s (%s), want %s", result.Status, result.Error, tt.wantState)
+			}
+			if result.Entry != tt.wantEntry {
+				t.Errorf("Entry = %q, want %q", result.Entry, tt.wantEntry)
+			}
+			if result.CheckedAt == "" {
+				t.Error("CheckedAt not set")
+			}
+		})
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "Creator.Pkg.1.var")
+		os.WriteFile(path, buildVar(), 0644)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := VerifyArchive(ctx, path); err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// ContentType classifies a file inside a .var (lowercase, forward slashes) as user-facing content.
+// Returns "" for supporting files such as textures, thumbnails and item data.
+func ContentType(lowerName string) string {
+	switch {
+	case strings.Contains(lowerName, "saves/scene/") && strings.HasSuffix(lowerName, ".json"):
+		return "Scene"
+	case strings.Contains(lowerName, "saves/person/appearance/") && strings.HasSuffix(lowerName, ".vap"):
+		return "Look"
+	case strings.Contains(lowerName, "custom/clothing/") && strings.HasSuffix(lowerName, ".vap"):
+		return "Clothing"
+	case strings.Contains(lowerName, "custom/hair/") && strings.HasSuffix(lowerName, ".vap"):
+		return "Hair"
+	case strings.Contains(lowerName, "custom/atom/person/morphs/") && (strings.HasSuffix(lowerName, ".vmi") || strings.HasSuffix(lowerName, ".vmb")):
+		return "Morph"
+	case strings.Contains(lowerName, "custom/assets/") && strings.HasSuffix(lowerName, ".assetbundle"):
+		// Only show asset bundles, not every texture
+		return "Asset"
+	case strings.Contains(lowerName, "custom/") && strings.HasSuffix(lowerName, ".vap"):
+		// Generic VAP in custom folder (Shoes, etc): infer the type from the folder name
+		parts := strings.Split(lowerName, "/")
+		for i, p := range parts {
+			if p == "custom" && i+1 < len(parts) {
+				cat := parts[i+1]
+				if cat != "clothing" && cat != "hair" && cat != "assets" && cat != "atom" {
+					return titleCase(cat)
+				}
+				break
+			}
+		}
+		return "Preset"
+	}
+	return ""
+}
+
+// ContentItems lists the content of a .var for search indexing: everything ContentType recognizes plus
+// the clothing and hair items themselves (.vam), which carry their own tags.
+func ContentItems(filePath string) ([]models.ContentItem, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var items []models.ContentItem
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		lowerName := strings.ReplaceAll(strings.ToLower(f.Name), "\\", "/")
+
+		item := models.ContentItem{
+			Path: f.Name,
+			Name: strings.TrimSuffix(filepath.Base(f.Name), filepath.Ext(f.Name)),
+			Type: ContentType(lowerName),
+		}
+		if strings.HasSuffix(lowerName, ".vam") {
+			switch {
+			case strings.Contains(lowerName, "custom/clothing/"):
+				item.Type = "Clothing"
+			case strings.Contains(lowerName, "custom/hair/"):
+				item.Type = "Hair"
+			}
+			item.Tags = readItemTags(f)
+		}
+		if item.Type != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// readItemTags returns the comma separated "tags" of a .vam item
+func readItemTags(f *zip.File) []string {
+	rc, err := f.Open()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, 5*1024*1024))
+	if err != nil {
+		return nil
+	}
+	var item struct {
+		Tags string `json:"tags"`
+	}
+	if err := json.Unmarshal(decodeBytes(data), &item); err != nil {
+		return nil
+	}
+	var tags []string
+	for _, t := range strings.Split(item.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func titleCase(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
@@ -106,3 +106,96 @@ func TestThumbnailPriority(t *testing.T) {
 		"Saves/scene/a_scene.jpg":  "a_thumb",
 	}, "z_thumb")
 }
+
+func TestParseVarMetadata_NestedDependencies(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, _ := w.Create("meta.json")
+	f.Write([]byte(`{
+		"creatorName": "Me",
+		"packageName": "Scene",
+		"licenseType": "CC BY",
+		"dependencies": {
+			"A.Look.1": {
+				"licenseType": "FC",
+				"dependencies": {
+					"B.Morphs.latest": {"licenseType": "PC", "dependencies": {}}
+				}
+			},
+			"C.Legacy.2": "https://example.com/license",
+			"D.Null.1": null
+		}
+	}`))
+	w.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "test.var")
+	if err := os.WriteFile(tmpFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _, _, err := ParseVarMetadata(tmpFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if meta.LicenseType != "CC BY" || meta.PackageName != "Scene" {
+		t.Errorf("Top level fields lost: %+v", meta)
+	}
+	if len(meta.Dependencies) != 3 {
+		t.Fatalf("Expected 3 direct dependencies, got %d", len(meta.Dependencies))
+	}
+	look := meta.Dependencies["A.Look.1"]
+	if look.LicenseType != "FC" {
+		t.Errorf("Expected licenseType FC, got %q", look.LicenseType)
+	}
+	if nested, ok := look.Dependencies["B.Morphs.latest"]; !ok || nested.LicenseType != "PC" {
+		t.Errorf("Nested dependency not decoded: %+v", look.Dependencies)
+	}
+}
+
+func TestAnalyzeReferences(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	files := map[string]string{
+		"meta.json": `{"creatorName": "Me", "packageName": "Scene", "dependencies": {
+			"A.Clothes.12": {},
+			"B.Unused.3": {}
+		}}`,
+		"Saves/scene/Test.json": `{"atoms": [
+			{"id": "A.Clothes.latest:/Custom/Clothing/Female/A/Dress.vam"},
+			{"id": "C.Hair.min2:/Custom/Hair/Female/C/Bob.vam"},
+			{"url": "Me.Scene.1:/Custom/Sounds/own.wav"},
+			{"url": "SELF:/Custom/Sounds/self.wav"}
+		]}`,
+		"Custom/Clothing/Female/Me/Item.vaj": `{"texture": "D.Textures.4:/Custom/Atom/Person/Textures/skin.jpg"}`,
+		"Custom/Scripts/readme.txt":          `"E.Ignored.1:/Custom/x"`,
+	}
+	for name, content := range files {
+		f, _ := w.Create(name)
+		f.Write([]byte(content))
+	}
+	w.Close()
+
+	tmpFile := filepath.Join(t.TempDir(), "Me.Scene.1.var")
+	if err := os.WriteFile(tmpFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := AnalyzeReferences(tmpFile)
+	if err != nil {
+		t.Fatalf("AnalyzeReferences failed: %v", err)
+	}
+
+	var undeclared []string
+	for _, r := range res.Undeclared {
+		undeclared = append(undeclared, r.ID)
+	}
+	if strings.Join(undeclared, ",") != "C.Hair.min2,D.Textures.4" {
+		t.Errorf("Undeclared = %v", undeclared)
+	}
+	if len(res.Referenced) != 3 {
+		t.Errorf("Expected 3 referenced packages (self and non-content files excluded), got %v", res.Referenced)
+	}
+	if len(res.Unused) != 1 || res.Unused[0] != "B.Unused.3" {
+		t.Errorf("Unused = %v", res.Unused)
+	}
+}
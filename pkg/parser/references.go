@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// packageRefPattern matches resource paths like "Creator.Package.latest:/Custom/Clothing/..." inside JSON.
+// The version is the last segment before ":/" (latest, minN or a number); names may contain dots.
+var packageRefPattern = regexp.MustCompile(`(?i)"([^":/\\]+\.[^":/\\]+\.(?:latest|min\d+|\d+)):/`)
+
+// referenceExtensions are the content files VaM stores resource references in
+var referenceExtensions = []string{".json", ".vap", ".vaj"}
+
+// maxReferenceFileSize skips huge content files (scene JSON is rarely above a few MB)
+const maxReferenceFileSize = 50 * 1024 * 1024
+
+// AnalyzeReferences scans the scene, preset and item JSON inside a .var for references to other packages
+// and compares them with the dependencies declared in meta.json. Versions are ignored when comparing:
+// a reference to .latest is covered by a declared .12 of the same package.
+func AnalyzeReferences(filePath string) (*models.ReferenceAnalysis, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var meta models.MetaJSON
+	referenced := make(map[string]string)   // base -> first reference seen
+	referencedIn := make(map[string]string) // base -> first content file referencing it
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		normName := strings.ReplaceAll(strings.ToLower(f.Name), "\\", "/")
+
+		if normName == "meta.json" || normName == "core/meta.json" {
+			meta = readMeta(f)
+			continue
+		}
+		if !hasReferenceExtension(normName) || f.UncompressedSize64 > maxReferenceFileSize {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxReferenceFileSize))
+		rc.Close()
+		if err != nil {
+			continue
+		}
+
+		for _, m := range packageRefPattern.FindAllSubmatch(data, -1) {
+			ref := string(m[1])
+			base := referenceBase(ref)
+			if _, ok := referenced[base]; !ok {
+				referenced[base] = ref
+				referencedIn[base] = f.Name
+			}
+		}
+	}
+
+	// Packages may reference their own files by full ID
+	creator := meta.Creator
+	if creator == "" {
+		creator = meta.CreatorName
+	}
+	self := strings.ToLower(creator + "." + meta.PackageName)
+	if creator == "" || meta.PackageName == "" {
+		name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filePath), ".disabled"), ".var")
+		self = referenceBase(name)
+	}
+	delete(referenced, self)
+
+	declared := make(map[string]string) // base -> declared ID
+	for id := range meta.Dependencies {
+		declared[referenceBase(id)] = id
+	}
+
+	result := &models.ReferenceAnalysis{
+		Referenced: []models.PackageReference{},
+		Undeclared: []models.PackageReference{},
+		Unused:     []string{},
+	}
+	for base, ref := range referenced {
+		pr := models.PackageReference{ID: ref, File: referencedIn[base]}
+		result.Referenced = append(result.Referenced, pr)
+		if _, ok := declared[base]; !ok {
+			result.Undeclared = append(result.Undeclared, pr)
+		}
+	}
+	for base, id := range declared {
+		if _, ok := referenced[base]; !ok {
+			result.Unused = append(result.Unused, id)
+		}
+	}
+
+	sortRefs := func(refs []models.PackageReference) {
+		sort.Slice(refs, func(i, j int) bool { return strings.ToLower(refs[i].ID) < strings.ToLower(refs[j].ID) })
+	}
+	sortRefs(result.Referenced)
+	sortRefs(result.Undeclared)
+	sort.Strings(result.Unused)
+	return result, nil
+}
+
+// referenceBase strips the version segment: "Creator.Package.latest" -> "creator.package"
+func referenceBase(id string) string {
+	lower := strings.ToLower(id)
+	if i := strings.LastIndex(lower, "."); i > 0 {
+		return lower[:i]
+	}
+	return lower
+}
+
+func hasReferenceExtension(name string) bool {
+	for _, ext := range referenceExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func readMeta(f *zip.File) models.MetaJSON {
+	var meta models.MetaJSON
+	rc, err := f.Open()
+	if err != nil {
+		return meta
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, 5*1024*1024))
+	if err != nil {
+		return meta
+	}
+	_ = json.Unmarshal(decodeBytes(data), &meta)
+	return meta
+}
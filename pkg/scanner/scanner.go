@@ -37,24 +37,10 @@ func (s *Scanner) scanDirectory(root string) ([]models.VarPackage, error) {
 			return nil
 		}
 
-		lowerName := strings.ToLower(info.Name())
-		var isEnabled bool
-
-		if strings.HasSuffix(lowerName, ".var") {
-			isEnabled = true
-		} else if strings.HasSuffix(lowerName, ".var.disabled") {
-			isEnabled = false
-		} else {
+		pkg, ok := PackageFromFileInfo(path, info)
+		if !ok {
 			return nil // Not a var package
 		}
-
-		pkg := models.VarPackage{
-			FilePath:     path,
-			FileName:     info.Name(),
-			Size:         info.Size(),
-			IsEnabled:    isEnabled,
-			CreationDate: info.ModTime().Format("2006-01-02T15:04:05Z07:00"),
-		}
 		pkgs = append(pkgs, pkg)
 		return nil
 	})
@@ -62,6 +48,29 @@ func (s *Scanner) scanDirectory(root string) ([]models.VarPackage, error) {
 	return pkgs, err
 }
 
+// IsPackageFile reports whether a file name is a .var package (enabled or disabled)
+func IsPackageFile(name string) bool {
+	lowerName := strings.ToLower(name)
+	return strings.HasSuffix(lowerName, ".var") || strings.HasSuffix(lowerName, ".var.disabled")
+}
+
+// PackageFromFileInfo builds the unparsed package entry for a file found on disk.
+// It returns false if the file is not a .var package.
+func PackageFromFileInfo(path string, info os.FileInfo) (models.VarPackage, bool) {
+	if info.IsDir() || !IsPackageFile(info.Name()) {
+		return models.VarPackage{}, false
+	}
+
+	return models.VarPackage{
+		FilePath:     path,
+		FileName:     info.Name(),
+		Size:         info.Size(),
+		IsEnabled:    strings.HasSuffix(strings.ToLower(info.Name()), ".var"),
+		CreationDate: info.ModTime().Format("2006-01-02T15:04:05Z07:00"),
+		ModTime:      info.ModTime().UnixNano(),
+	}, true
+}
+
 // CountPackages returns the number of .var packages in the directory (recursive)
 func (s *Scanner) CountPackages(root string) (int, error) {
 	count := 0
@@ -72,8 +81,7 @@ func (s *Scanner) CountPackages(root string) (int, error) {
 		if info.IsDir() {
 			return nil
 		}
-		name := strings.ToLower(info.Name())
-		if strings.HasSuffix(name, ".var") || strings.HasSuffix(name, ".var.disabled") {
+		if IsPackageFile(info.Name()) {
 			count++
 		}
 		return nil
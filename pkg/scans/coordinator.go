@@ -0,0 +1,351 @@
+package scans
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"yavam/pkg/models"
+)
+
+// ScanFunc scans one library, reporting every package and the progress (see LibraryService.Scan)
+type ScanFunc func(ctx context.Context, libraryPath string, onPackage func(models.VarPackage), onProgress func(int, int)) error
+
+// Coordinator runs at most one scan per library. Concurrent requests for the same library attach
+// to the running scan instead of starting (or cancelling) another one; scans of different libraries
+// run independently. A scan is cancelled when its last subscriber leaves or Cancel is called for it.
+type Coordinator struct {
+	mu      sync.Mutex
+	scan    ScanFunc
+	running map[string]*scanRun
+	wg      sync.WaitGroup
+}
+
+// scanRun is one running scan and everyone listening to it
+type scanRun struct {
+	library string
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+
+	// mu guards the fields below; live callbacks are invoked with it held so they reach
+	// every subscriber in order
+	mu       sync.Mutex
+	subs     map[*Subscription]bool
+	packages []models.VarPackage
+	current  int
+	total    int
+	finished bool
+}
+
+// Subscription is one listener of a scan
+type Subscription struct {
+	run        *scanRun
+	coord      *Coordinator
+	key        string
+	onPackage  func(models.VarPackage)
+	onProgress func(int, int)
+	joined     bool
+
+	once sync.Once
+	done chan struct{}
+	err  error
+
+	// mu guards the fields below. While a late subscriber replays the backlog, live events are
+	// queued and delivered after it.
+	mu        sync.Mutex
+	replaying bool
+	pending   []event
+}
+
+// event is a package or progress update queued during a replay
+type event struct {
+	pkg            *models.VarPackage
+	current, total int
+}
+
+// NewCoordinator creates a coordinator running scans through scan
+func NewCoordinator(scan ScanFunc) *Coordinator {
+	return &Coordinator{
+		scan:    scan,
+		running: make(map[string]*scanRun),
+	}
+}
+
+func libraryKey(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// Subscribe attaches to the running scan of libraryPath, starting one if none is running.
+// A late subscriber first receives the packages and progress reported so far; this replay runs
+// on the calling goroutine without holding any lock, live events follow in order once it is done.
+// Live callbacks run on the scan goroutine and must not call back into the coordinator for the same library.
+func (c *Coordinator) Subscribe(libraryPath string, onPackage func(models.VarPackage), onProgress func(int, int)) *Subscription {
+	sub := &Subscription{
+		coord:      c,
+		key:        libraryKey(libraryPath),
+		onPackage:  onPackage,
+		onProgress: onProgress,
+		done:       make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	key := sub.key
+	if run, ok := c.running[key]; ok {
+		run.mu.Lock()
+		if !run.finished {
+			sub.run = run
+			sub.joined = true
+			sub.replaying = true
+			run.subs[sub] = true
+			// Packages are only appended, the first len(run.packages) never change
+			backlog := run.packages[:len(run.packages):len(run.packages)]
+			current, total := run.current, run.total
+			run.mu.Unlock()
+			c.mu.Unlock()
+
+			sub.replay(backlog, current, total)
+			return sub
+		}
+		run.mu.Unlock()
+	}
+	defer c.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &scanRun{
+		library: libraryPath,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		subs:    map[*Subscription]bool{sub: true},
+	}
+	sub.run = run
+	c.running[key] = run
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		c.execute(ctx, key, run)
+	}()
+	return sub
+}
+
+func (c *Coordinator) execute(ctx context.Context, key string, run *scanRun) {
+	err := c.scan(ctx, run.library, func(p models.VarPackage) {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		run.packages = append(run.packages, p)
+		for sub := range run.subs {
+			sub.deliverPackage(p)
+		}
+	}, func(current, total int) {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		run.current, run.total = current, total
+		for sub := range run.subs {
+			sub.deliverProgress(current, total)
+		}
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	c.mu.Lock()
+	c.detach(key, run)
+	c.mu.Unlock()
+
+	run.mu.Lock()
+	run.err = err
+	run.finished = true
+	subs := run.subs
+	run.subs = nil
+	run.mu.Unlock()
+
+	for sub := range subs {
+		sub.finish(err)
+	}
+	close(run.done)
+}
+
+// detach removes a finished or cancelled scan so the next request starts a fresh one (c.mu held)
+func (c *Coordinator) detach(key string, run *scanRun) {
+	if c.running[key] == run {
+		delete(c.running, key)
+	}
+}
+
+// Cancel stops the scan of libraryPath for every subscriber and waits for it to end
+func (c *Coordinator) Cancel(libraryPath string) {
+	key := libraryKey(libraryPath)
+	c.mu.Lock()
+	run, ok := c.running[key]
+	if ok {
+		c.detach(key, run)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	run.cancel()
+	<-run.done
+}
+
+// CancelAll stops every running scan and waits for them
+func (c *Coordinator) CancelAll() {
+	c.mu.Lock()
+	for _, run := range c.running {
+		run.cancel()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+}
+
+// Running lists the libraries currently being scanned
+func (c *Coordinator) Running() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	libs := make([]string, 0, len(c.running))
+	for _, run := range c.running {
+		libs = append(libs, run.library)
+	}
+	return libs
+}
+
+// Run scans libraryPath (sharing a running scan) and returns every package.
+// Cancelling ctx only detaches this caller.
+func (c *Coordinator) Run(ctx context.Context, libraryPath string) ([]models.VarPackage, error) {
+	sub := c.Subscribe(libraryPath, nil, nil)
+	select {
+	case <-sub.Done():
+	case <-ctx.Done():
+		sub.Unsubscribe()
+		return nil, ctx.Err()
+	}
+	if err := sub.Err(); err != nil {
+		return nil, err
+	}
+	return sub.Packages(), nil
+}
+
+// Done is closed when the scan finished or the subscription ended
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err is the scan result once Done is closed: nil on success, context.Canceled when
+// cancelled or unsubscribed
+func (s *Subscription) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Joined reports whether the subscription attached to a scan that was already running.
+// Such a scan may have started before the latest change on disk.
+func (s *Subscription) Joined() bool {
+	return s.joined
+}
+
+// Packages returns the packages reported until Done (all of them after a successful scan)
+func (s *Subscription) Packages() []models.VarPackage {
+	<-s.done
+	s.run.mu.Lock()
+	defer s.run.mu.Unlock()
+	pkgs := make([]models.VarPackage, len(s.run.packages))
+	copy(pkgs, s.run.packages)
+	return pkgs
+}
+
+// Unsubscribe stops receiving events. The scan is cancelled if nobody else is listening.
+func (s *Subscription) Unsubscribe() {
+	run := s.run
+	s.coord.mu.Lock()
+	run.mu.Lock()
+	if run.finished || !run.subs[s] {
+		run.mu.Unlock()
+		s.coord.mu.Unlock()
+		return
+	}
+	delete(run.subs, s)
+	last := len(run.subs) == 0
+	if last {
+		s.coord.detach(s.key, run)
+	}
+	run.mu.Unlock()
+	s.coord.mu.Unlock()
+
+	if last {
+		run.cancel()
+	}
+	s.finish(context.Canceled)
+}
+
+func (s *Subscription) finish(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// replay delivers the backlog of a late subscriber, then the events queued meanwhile
+func (s *Subscription) replay(backlog []models.VarPackage, current, total int) {
+	for _, p := range backlog {
+		s.callPackage(p)
+	}
+	if total > 0 {
+		s.callProgress(current, total)
+	}
+	for {
+		s.mu.Lock()
+		pending := s.pending
+		s.pending = nil
+		if len(pending) == 0 {
+			s.replaying = false
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+
+		for _, e := range pending {
+			if e.pkg != nil {
+				s.callPackage(*e.pkg)
+			} else {
+				s.callProgress(e.current, e.total)
+			}
+		}
+	}
+}
+
+// queue holds back a live event while the backlog is replayed
+func (s *Subscription) queue(e event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.replaying {
+		return false
+	}
+	s.pending = append(s.pending, e)
+	return true
+}
+
+func (s *Subscription) deliverPackage(p models.VarPackage) {
+	if !s.queue(event{pkg: &p}) {
+		s.callPackage(p)
+	}
+}
+
+func (s *Subscription) deliverProgress(current, total int) {
+	if !s.queue(event{current: current, total: total}) {
+		s.callProgress(current, total)
+	}
+}
+
+func (s *Subscription) callPackage(p models.VarPackage) {
+	if s.onPackage != nil {
+		s.onPackage(p)
+	}
+}
+
+func (s *Subscription) callProgress(current, total int) {
+	if s.onProgress != nil {
+		s.onProgress(current, total)
+	}
+}
@@ -0,0 +1,218 @@
+package scans
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"yavam/pkg/models"
+)
+
+// fakeScanner reports one package, then blocks until released or cancelled
+type fakeScanner struct {
+	calls   atomic.Int32
+	started chan string
+	release chan struct{}
+}
+
+func newFakeScanner() *fakeScanner {
+	return &fakeScanner{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (f *fakeScanner) scan(ctx context.Context, lib string, onPackage func(models.VarPackage), onProgress func(int, int)) error {
+	f.calls.Add(1)
+	onPackage(models.VarPackage{FilePath: lib + "/A.var"})
+	onProgress(1, 2)
+	f.started <- lib
+	select {
+	case <-f.release:
+		onPackage(models.VarPackage{FilePath: lib + "/B.var"})
+		onProgress(2, 2)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	packages []string
+	progress int
+}
+
+func (r *recorder) onPackage(p models.VarPackage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packages = append(r.packages, p.FilePath)
+}
+
+func (r *recorder) onProgress(current, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress = current
+}
+
+func waitDone(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case <-sub.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Subscription did not finish")
+	}
+}
+
+func TestCoordinator_SharesScanBetweenSubscribers(t *testing.T) {
+	f := newFakeScanner()
+	c := NewCoordinator(f.scan)
+
+	var first, second recorder
+	a := c.Subscribe("/lib", first.onPackage, first.onProgress)
+	<-f.started
+	b := c.Subscribe("/LIB/", second.onPackage, second.onProgress)
+	if !b.Joined() || a.Joined() {
+		t.Error("Expected the second subscriber to join the running scan")
+	}
+
+	close(f.release)
+	waitDone(t, a)
+	waitDone(t, b)
+
+	if n := f.calls.Load(); n != 1 {
+		t.Errorf("Expected one scan, got %d", n)
+	}
+	if a.Err() != nil || b.Err() != nil {
+		t.Fatalf("Unexpected errors: %v, %v", a.Err(), b.Err())
+	}
+	// The late subscriber got the replayed package and then the live one
+	if len(second.packages) != 2 || second.progress != 2 {
+		t.Errorf("Expected replay plus live events, got %v (progress %d)", second.packages, second.progress)
+	}
+	if len(b.Packages()) != 2 {
+		t.Errorf("Expected 2 packages, got %d", len(b.Packages()))
+	}
+}
+
+func TestCoordinator_ReplayDoesNotHoldLocks(t *testing.T) {
+	f := newFakeScanner()
+	c := NewCoordinator(f.scan)
+
+	a := c.Subscribe("/lib", nil, nil)
+	<-f.started
+
+	// A slow late subscriber blocks in the replay of A.var
+	replaying := make(chan struct{})
+	resume := make(chan struct{})
+	var slow recorder
+	var b *Subscription
+	subscribed := make(chan struct{})
+	go func() {
+		b = c.Subscribe("/lib", func(p models.VarPackage) {
+			if len(slow.packages) == 0 {
+				close(replaying)
+				<-resume
+			}
+			slow.onPackage(p)
+		}, slow.onProgress)
+		close(subscribed)
+	}()
+	<-replaying
+
+	// Meanwhile the coordinator and the scan keep going
+	running := make(chan struct{})
+	go func() {
+		c.Running()
+		close(running)
+	}()
+	select {
+	case <-running:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The replay holds the coordinator lock")
+	}
+	other := c.Subscribe("/other", nil, nil)
+	<-f.started
+	close(f.release)
+	waitDone(t, a)
+	waitDone(t, other)
+
+	// Packages reported during the replay follow it in order
+	close(resume)
+	<-subscribed
+	waitDone(t, b)
+	if len(slow.packages) != 2 || slow.packages[0] != "/lib/A.var" || slow.packages[1] != "/lib/B.var" || slow.progress != 2 {
+		t.Errorf("Expected replay then live events in order, got %v (progress %d)", slow.packages, slow.progress)
+	}
+}
+
+func TestCoordinator_CancelsWhenLastSubscriberLeaves(t *testing.T) {
+	f := newFakeScanner()
+	c := NewCoordinator(f.scan)
+
+	a := c.Subscribe("/lib", nil, nil)
+	<-f.started
+	b := c.Subscribe("/lib", nil, nil)
+
+	a.Unsubscribe()
+	if a.Err() != context.Canceled {
+		t.Errorf("Expected Canceled for the leaving subscriber, got %v", a.Err())
+	}
+	select {
+	case <-b.Done():
+		t.Fatal("Scan was cancelled while another subscriber was listening")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.Unsubscribe()
+	c.CancelAll() // Waits for the scan goroutine
+	if len(c.Running()) != 0 {
+		t.Errorf("Expected no running scans, got %v", c.Running())
+	}
+
+	// The next request starts a fresh scan
+	d := c.Subscribe("/lib", nil, nil)
+	<-f.started
+	if d.Joined() || f.calls.Load() != 2 {
+		t.Errorf("Expected a new scan after cancellation, calls=%d", f.calls.Load())
+	}
+	c.Cancel("/lib")
+	waitDone(t, d)
+}
+
+func TestCoordinator_LibrariesAreIndependent(t *testing.T) {
+	f := newFakeScanner()
+	c := NewCoordinator(f.scan)
+
+	a := c.Subscribe("/lib-a", nil, nil)
+	b := c.Subscribe("/lib-b", nil, nil)
+	<-f.started
+	<-f.started
+
+	// Explicit cancel stops every subscriber of that library only
+	c.Cancel("/lib-a")
+	waitDone(t, a)
+	if a.Err() != context.Canceled {
+		t.Errorf("Expected Canceled, got %v", a.Err())
+	}
+	select {
+	case <-b.Done():
+		t.Fatal("Cancelling library A stopped library B")
+	default:
+	}
+
+	close(f.release)
+	waitDone(t, b)
+	if b.Err() != nil {
+		t.Errorf("Unexpected error for library B: %v", b.Err())
+	}
+}
+
+func TestCoordinator_Run(t *testing.T) {
+	f := newFakeScanner()
+	close(f.release)
+	c := NewCoordinator(f.scan)
+
+	pkgs, err := c.Run(context.Background(), "/lib")
+	if err != nil || len(pkgs) != 2 {
+		t.Errorf("Run = %d packages, %v", len(pkgs), err)
+	}
+}
@@ -0,0 +1,239 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"yavam/pkg/models"
+)
+
+// Query is a parsed search string. All terms must match (AND).
+//
+// Grammar (case-insensitive keys, values may be quoted):
+//
+//	creator:foo  type:Look  tag:hair  name:braid  license:CC-BY
+//	enabled:true|false  missing:true|false  corrupt:true|false
+//	size>500MB  size<=1.5GB  size=0  (units B, KB, MB, GB)
+//	"free text"  -tag:hair (any term prefixed with - is negated)
+//
+// A quoted term is always free text, so "hair:braid" is not read as a field.
+//
+// Free text matches file name, package name, creator and description.
+type Query struct {
+	Terms []Term
+}
+
+// Term is one condition of a query
+type Term struct {
+	Field  string // Empty for free text
+	Op     string // ":" for field matches, or one of > >= < <= = for size
+	Value  string
+	Negate bool
+
+	bytes int64 // Parsed size for size comparisons
+}
+
+var textFields = map[string]bool{"creator": true, "type": true, "tag": true, "name": true, "license": true}
+var boolFields = map[string]bool{"enabled": true, "missing": true, "corrupt": true}
+
+var sizeUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"gb", 1 << 30},
+	{"mb", 1 << 20},
+	{"kb", 1 << 10},
+	{"b", 1},
+}
+
+// Parse turns a search string into a Query. Unknown fields are an error so typos are not silently ignored.
+func Parse(input string) (Query, error) {
+	var q Query
+	for _, tok := range tokenize(input) {
+		t, err := parseTerm(tok)
+		if err != nil {
+			return Query{}, err
+		}
+		q.Terms = append(q.Terms, t)
+	}
+	return q, nil
+}
+
+func parseTerm(tok string) (Term, error) {
+	var t Term
+	if strings.HasPrefix(tok, "-") && len(tok) > 1 {
+		t.Negate = true
+		tok = tok[1:]
+	}
+
+	// Quoted text is never a field, "hair:braid" searches for the colon too
+	if strings.HasPrefix(tok, `"`) {
+		t.Value = strings.ToLower(unquote(tok))
+		return t, nil
+	}
+
+	// size comparisons: size>500MB, size<=1GB
+	if lower := strings.ToLower(tok); strings.HasPrefix(lower, "size") && len(tok) > 4 && strings.ContainsAny(tok[4:5], "<>=:") {
+		rest := tok[4:]
+		for _, op := range []string{">=", "<=", ">", "<", "=", ":"} {
+			if strings.HasPrefix(rest, op) {
+				value := unquote(rest[len(op):])
+				n, err := parseSize(value)
+				if err != nil {
+					return Term{}, err
+				}
+				if op == ":" {
+					op = "="
+				}
+				t.Field, t.Op, t.Value, t.bytes = "size", op, value, n
+				return t, nil
+			}
+		}
+	}
+
+	if i := strings.Index(tok, ":"); i > 0 {
+		field := strings.ToLower(tok[:i])
+		value := unquote(tok[i+1:])
+		switch {
+		case textFields[field]:
+			t.Field, t.Op, t.Value = field, ":", strings.ToLower(value)
+			return t, nil
+		case boolFields[field]:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return Term{}, fmt.Errorf("%s expects true or false, got %q", field, value)
+			}
+			t.Field, t.Op, t.Value = field, ":", strings.ToLower(value)
+			return t, nil
+		default:
+			return Term{}, fmt.Errorf("unknown search field %q", field)
+		}
+	}
+
+	t.Value = strings.ToLower(unquote(tok))
+	return t, nil
+}
+
+// tokenize splits on whitespace, keeping double-quoted sections (also after field:) together
+func tokenize(input string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+// parseSize reads "500MB", "1.5gb", "1024"
+func parseSize(s string) (int64, error) {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	factor := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(lower, u.suffix) {
+			factor = u.factor
+			lower = strings.TrimSuffix(lower, u.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(lower, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * factor), nil
+}
+
+// Match reports whether a package satisfies every term
+func (q Query) Match(p models.VarPackage) bool {
+	for _, t := range q.Terms {
+		if t.match(p) == t.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t Term) match(p models.VarPackage) bool {
+	switch t.Field {
+	case "":
+		return containsFold(p.FileName, t.Value) ||
+			containsFold(p.Meta.PackageName, t.Value) ||
+			containsFold(p.Meta.Creator, t.Value) ||
+			containsFold(p.Meta.Description, t.Value)
+	case "creator":
+		return strings.EqualFold(p.Meta.Creator, t.Value)
+	case "type":
+		if strings.EqualFold(p.Type, t.Value) {
+			return true
+		}
+		for _, c := range p.Categories {
+			if strings.EqualFold(c, t.Value) {
+				return true
+			}
+		}
+		return false
+	case "tag":
+		for _, tag := range p.Tags {
+			if strings.EqualFold(tag, t.Value) {
+				return true
+			}
+		}
+		return false
+	case "name":
+		return containsFold(p.FileName, t.Value) || containsFold(p.Meta.PackageName, t.Value)
+	case "license":
+		return strings.EqualFold(p.Meta.LicenseType, t.Value)
+	case "enabled":
+		return p.IsEnabled == isTrue(t.Value)
+	case "missing":
+		return hasMissing(p) == isTrue(t.Value)
+	case "corrupt":
+		return p.IsCorrupt == isTrue(t.Value)
+	case "size":
+		switch t.Op {
+		case ">":
+			return p.Size > t.bytes
+		case ">=":
+			return p.Size >= t.bytes
+		case "<":
+			return p.Size < t.bytes
+		case "<=":
+			return p.Size <= t.bytes
+		default:
+			return p.Size == t.bytes
+		}
+	}
+	return false
+}
+
+// hasMissing counts unsatisfied direct and transitive dependencies (see LibraryService.CheckDependencies)
+func hasMissing(p models.VarPackage) bool {
+	return len(p.MissingDeps) > 0 || len(p.MissingTransitiveDeps) > 0
+}
+
+func isTrue(v string) bool {
+	b, _ := strconv.ParseBool(v)
+	return b
+}
+
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), sub)
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"testing"
+	"time"
+	"yavam/pkg/models"
+)
+
+func testPackages() []models.VarPackage {
+	return []models.VarPackage{
+		{
+			FilePath: "/lib/Alice.BraidHair.2.var", FileName: "Alice.BraidHair.2.var", Size: 600 << 20,
+			Type: "Hair", Tags: []string{"hair", "braid"}, IsEnabled: true, CreationDate: "2024-01-02T00:00:00Z", ModTime: unixNano("2024-01-02T00:00:00Z"),
+			Meta: models.MetaJSON{Creator: "Alice", PackageName: "BraidHair", LicenseType: "CC BY"},
+		},
+		{
+			FilePath: "/lib/Bob.Beach.1.var.disabled", FileName: "Bob.Beach.1.var.disabled", Size: 100 << 20,
+			Type: "Scene", Categories: []string{"Scene", "Look"}, CreationDate: "2024-03-01T00:00:00Z", ModTime: unixNano("2024-03-01T00:00:00Z"),
+			Meta:        models.MetaJSON{Creator: "Bob", PackageName: "Beach", Description: "Sunset at the beach"},
+			MissingDeps: []string{"Alice.Other.1"},
+		},
+		{
+			FilePath: "/lib/Bob.Look.3.var", FileName: "Bob.Look.3.var", Size: 5 << 20, Type: "Look",
+			IsEnabled: true, IsCorrupt: true, CreationDate: "2023-05-01T00:00:00Z", ModTime: unixNano("2023-05-01T00:00:00Z"),
+			Meta: models.MetaJSON{Creator: "Bob", PackageName: "Look"},
+		},
+	}
+}
+
+func unixNano(date string) int64 {
+	t, _ := time.Parse(time.RFC3339, date)
+	return t.UnixNano()
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string // File names in name-asc order
+	}{
+		{"", []string{"Alice.BraidHair.2.var", "Bob.Beach.1.var.disabled", "Bob.Look.3.var"}},
+		{"creator:bob", []string{"Bob.Beach.1.var.disabled", "Bob.Look.3.var"}},
+		{"type:Look", []string{"Bob.Beach.1.var.disabled", "Bob.Look.3.var"}},
+		{"tag:hair size>500MB", []string{"Alice.BraidHair.2.var"}},
+		{"size<=100MB", []string{"Bob.Beach.1.var.disabled", "Bob.Look.3.var"}},
+		{"enabled:false", []string{"Bob.Beach.1.var.disabled"}},
+		{"missing:true", []string{"Bob.Beach.1.var.disabled"}},
+		{"corrupt:true", []string{"Bob.Look.3.var"}},
+		{"sunset", []string{"Bob.Beach.1.var.disabled"}},
+		{`license:"CC BY"`, []string{"Alice.BraidHair.2.var"}},
+		{"creator:bob -corrupt:true", []string{"Bob.Beach.1.var.disabled"}},
+		{"CREATOR:Bob name:look", []string{"Bob.Look.3.var"}},
+		// Quoted free text is never a field, even with a colon inside
+		{`"beach:"`, []string{}},
+		{`"sunset at"`, []string{"Bob.Beach.1.var.disabled"}},
+		{`-"hair:braid"`, []string{"Alice.BraidHair.2.var", "Bob.Beach.1.var.disabled", "Bob.Look.3.var"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			res := Run(testPackages(), q, Options{})
+			if res.Total != len(tt.want) {
+				t.Fatalf("Expected %d matches, got %d", len(tt.want), res.Total)
+			}
+			for i, p := range res.Results {
+				if p.FileName != tt.want[i] {
+					t.Errorf("Result %d = %s, want %s", i, p.FileName, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{"colour:red", "enabled:maybe", "size>lots"} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Expected %q to be rejected", q)
+		}
+	}
+}
+
+func TestRunSortAndPaging(t *testing.T) {
+	res := Run(testPackages(), Query{}, Options{Sort: SortSizeDesc, Offset: 1, Limit: 1})
+	if res.Total != 3 || len(res.Results) != 1 || res.Results[0].FileName != "Bob.Beach.1.var.disabled" {
+		t.Errorf("Unexpected page %+v", res)
+	}
+
+	res = Run(testPackages(), Query{}, Options{Sort: SortDateNewest})
+	if res.Results[0].FileName != "Bob.Beach.1.var.disabled" || res.Results[2].FileName != "Bob.Look.3.var" {
+		t.Errorf("Unexpected date order %v", res.Results)
+	}
+
+	// Dates in another offset still sort by the instant, not lexically
+	pkgs := testPackages()
+	pkgs[0].CreationDate = "2024-03-01T04:00:00+05:00" // 2024-02-29T23:00Z, before Bob.Beach
+	pkgs[0].ModTime = unixNano(pkgs[0].CreationDate)
+	res = Run(pkgs, Query{}, Options{Sort: SortDateOldest})
+	if res.Results[1].FileName != "Alice.BraidHair.2.var" || res.Results[2].FileName != "Bob.Beach.1.var.disabled" {
+		t.Errorf("Unexpected date order across offsets %v", res.Results)
+	}
+
+	res = Run(testPackages(), Query{}, Options{Offset: 10})
+	if res.Total != 3 || len(res.Results) != 0 {
+		t.Errorf("Expected an empty page past the end, got %+v", res)
+	}
+	if res.Limit != DefaultLimit {
+		t.Errorf("Expected default limit, got %d", res.Limit)
+	}
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// Sort modes, the same names the library view uses
+const (
+	SortNameAsc    = "name-asc"
+	SortNameDesc   = "name-desc"
+	SortSizeAsc    = "size-asc"
+	SortSizeDesc   = "size-desc"
+	SortDateNewest = "date-newest"
+	SortDateOldest = "date-oldest"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Options control ordering and paging of a search
+type Options struct {
+	Sort   string
+	Offset int
+	Limit  int // 0 means DefaultLimit, capped at MaxLimit
+}
+
+// Result is one page of matches
+type Result struct {
+	Total   int                 `json:"total"` // Matches before paging
+	Offset  int                 `json:"offset"`
+	Limit   int                 `json:"limit"`
+	Results []models.VarPackage `json:"results"`
+}
+
+// Run filters, sorts and pages pkgs. The input slice is not modified.
+func Run(pkgs []models.VarPackage, q Query, opts Options) Result {
+	matches := make([]models.VarPackage, 0, len(pkgs))
+	for _, p := range pkgs {
+		if q.Match(p) {
+			matches = append(matches, p)
+		}
+	}
+	sortPackages(matches, opts.Sort)
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+	offset := max(opts.Offset, 0)
+
+	start := min(offset, len(matches))
+	end := min(start+limit, len(matches))
+	return Result{
+		Total:   len(matches),
+		Offset:  offset,
+		Limit:   limit,
+		Results: matches[start:end],
+	}
+}
+
+// ValidSort reports whether mode is a known sort mode (empty selects name-asc)
+func ValidSort(mode string) bool {
+	switch mode {
+	case "", SortNameAsc, SortNameDesc, SortSizeAsc, SortSizeDesc, SortDateNewest, SortDateOldest:
+		return true
+	}
+	return false
+}
+
+// sortPackages orders by mode with the file path as tie-breaker, so pages are stable between requests
+func sortPackages(pkgs []models.VarPackage, mode string) {
+	sort.SliceStable(pkgs, func(i, j int) bool {
+		a, b := pkgs[i], pkgs[j]
+		var cmp int
+		switch mode {
+		case SortNameDesc:
+			cmp = strings.Compare(strings.ToLower(b.FileName), strings.ToLower(a.FileName))
+		case SortSizeAsc:
+			cmp = compareInt(a.Size, b.Size)
+		case SortSizeDesc:
+			cmp = compareInt(b.Size, a.Size)
+		case SortDateNewest:
+			// CreationDate carries the local offset and only seconds, ModTime compares across both
+			cmp = compareInt(b.ModTime, a.ModTime)
+		case SortDateOldest:
+			cmp = compareInt(a.ModTime, b.ModTime)
+		default:
+			cmp = strings.Compare(strings.ToLower(a.FileName), strings.ToLower(b.FileName))
+		}
+		if cmp == 0 {
+			return a.FilePath < b.FilePath
+		}
+		return cmp < 0
+	})
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
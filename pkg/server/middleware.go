@@ -1,11 +1,34 @@
 package server
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"strings"
+	"yavam/pkg/services/auth"
 )
 
-// AuthMiddleware protects routes requiring authentication
+// clientIP returns the address of the remote end without the port
+func clientIP(r *http.Request) string {
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return ip
+	}
+	return r.RemoteAddr
+}
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// userFromRequest returns the signed-in user of a request passed through AuthMiddleware (nil for guests)
+func userFromRequest(r *http.Request) *auth.User {
+	user, _ := r.Context().Value(userContextKey).(*auth.User)
+	return user
+}
+
+// AuthMiddleware protects routes requiring authentication. Signed-in users need the role the route
+// requires (see requiredRole), API tokens additionally its scope (see requiredScope); guests only
+// reach the public routes when public access is enabled.
 func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 1. Get Token from Header or Query
@@ -24,10 +47,18 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 
 		// 2. Validate Token
 		if token != "" {
-			_, err := s.auth.ValidateToken(token)
+			user, err := s.auth.ValidateToken(token)
 			if err == nil {
-				// Token Valid -> Admin Access
-				next.ServeHTTP(w, r)
+				if !auth.RoleAllows(user.Role, s.requiredRole(r)) {
+					s.writeError(w, "Forbidden: your account does not have access to this action", http.StatusForbidden)
+					return
+				}
+				if scope := s.requiredScope(r); !user.HasScope(scope) {
+					s.writeError(w, "Forbidden: token is missing the scope "+scope, http.StatusForbidden)
+					return
+				}
+				s.auth.TouchSession(token, clientIP(r))
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
 				return
 			}
 			// Strict Mode: If a token is provided but invalid, Fail immediately.
@@ -59,6 +90,8 @@ func (s *Server) isGuestAllowed(r *http.Request) bool {
 	if r.Method == "GET" {
 		switch path {
 		case "/api/packages",
+			"/api/search",
+			"/api/search/text",
 			"/api/disk-space",
 			"/api/config",
 			"/api/thumbnail":
@@ -74,7 +107,7 @@ func (s *Server) isGuestAllowed(r *http.Request) bool {
 	// 2. Special POST Requests (Read-Only)
 	if r.Method == "POST" {
 		switch path {
-		case "/api/contents": // Read package contents
+		case "/api/contents", "/api/details": // Read package contents
 			return true
 		case "/api/scan/collisions": // Check collisions (Read-Only?)
 			// Technically read only check, but often precedes write.
@@ -85,3 +118,88 @@ func (s *Server) isGuestAllowed(r *http.Request) bool {
 
 	return false
 }
+
+// requiredRole is the least role a signed-in user needs for a request. Viewers get everything
+// guests may see plus their own session tools; uploading needs an uploader; the rest is admin only.
+func (s *Server) requiredRole(r *http.Request) string {
+	path := r.URL.Path
+	if path == "/api/upload" {
+		return auth.RoleUploader
+	}
+	if s.isGuestAllowed(r) {
+		return auth.RoleViewer
+	}
+
+	switch path {
+	case "/api/events", // Live updates
+		"/api/auth/verify",      // Session check
+		"/api/library/counts",   // Read-only
+		"/api/packages/refresh", // Rescans without changing anything
+		"/api/download/bundle",  // Download (GET or POST body with the selection)
+		"/api/auth/tokens",      // Own API tokens
+		"/api/auth/totp",        // Own two-factor setup
+		"/api/auth/totp/confirm":
+		return auth.RoleViewer
+	case "/api/scan/cancel":
+		// Stopping to follow a refresh is fine, cancelling a library scan for everyone is not
+		if r.URL.Query().Get("path") == "" {
+			return auth.RoleViewer
+		}
+	}
+	if r.Method == "GET" && (path == "/api/duplicates" || strings.HasPrefix(path, "/api/graph/")) {
+		// Read-only reports, but they can start scans of whole libraries, so not for guests
+		return auth.RoleViewer
+	}
+	if strings.HasPrefix(path, "/api/auth/tokens/") {
+		return auth.RoleViewer
+	}
+	return auth.RoleAdmin
+}
+
+// requiredScope is the scope an API token needs for a request. Everything a viewer may do is
+// packages:read; changes to packages have their own scopes; settings, accounts, sessions and
+// tokens need admin.
+func (s *Server) requiredScope(r *http.Request) string {
+	path := r.URL.Path
+	switch path {
+	case "/api/upload":
+		return auth.ScopeUpload
+	case "/api/toggle":
+		return auth.ScopePackagesToggle
+	case "/api/auth/verify":
+		return auth.ScopePackagesRead // Any token may check itself
+	case "/api/auth/tokens", "/api/auth/totp", "/api/auth/totp/confirm":
+		return auth.ScopeAdmin
+	}
+	if strings.HasPrefix(path, "/api/auth/tokens/") {
+		return auth.ScopeAdmin
+	}
+	if s.requiredRole(r) == auth.RoleViewer {
+		return auth.ScopePackagesRead
+	}
+
+	switch path {
+	case "/api/quarantine", "/api/jobs":
+		if r.Method == "GET" {
+			return auth.ScopePackagesRead
+		}
+		return auth.ScopePackagesWrite
+	case "/api/install",
+		"/api/delete",
+		"/api/resolve",
+		"/api/salvage",
+		"/api/index/invalidate",
+		"/api/export/closure",
+		"/api/scan/cancel":
+		return auth.ScopePackagesWrite
+	}
+	if r.Method == "GET" && strings.HasPrefix(path, "/api/jobs/") {
+		return auth.ScopePackagesRead
+	}
+	for _, prefix := range []string{"/api/quarantine/", "/api/integrity/", "/api/jobs/"} {
+		if strings.HasPrefix(path, prefix) {
+			return auth.ScopePackagesWrite
+		}
+	}
+	return auth.ScopeAdmin
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"yavam/pkg/manager"
 	"yavam/pkg/services/auth"
 	"yavam/pkg/services/config"
@@ -11,6 +12,8 @@ import (
 
 type MockAuthService struct {
 	validToken string
+	roles      map[string]string // Additional tokens and their roles
+	apiTokens  map[string]*auth.User
 }
 
 func (m *MockAuthService) InitiateLogin(username string) (string, error) {
@@ -23,7 +26,13 @@ func (m *MockAuthService) CompleteLogin(username, nonce, proof, deviceName strin
 
 func (m *MockAuthService) ValidateToken(token string) (*auth.User, error) {
 	if token == m.validToken {
-		return &auth.User{Username: "admin"}, nil
+		return &auth.User{Username: "admin", Role: auth.RoleAdmin}, nil
+	}
+	if role, ok := m.roles[token]; ok {
+		return &auth.User{Username: role, Role: role}, nil
+	}
+	if user, ok := m.apiTokens[token]; ok {
+		return user, nil
 	}
 	return nil, auth.ErrInvalidToken
 }
@@ -53,6 +62,33 @@ func (m *MockAuthService) Login(username, password, deviceName string) (string,
 	return "", auth.ErrInvalidToken
 }
 
+func (m *MockAuthService) ListUsers() ([]auth.Account, error)               { return nil, nil }
+func (m *MockAuthService) CreateUser(username, password, role string) error { return nil }
+func (m *MockAuthService) DeleteUser(username string) error                 { return nil }
+func (m *MockAuthService) SetUserRole(username, role string) error          { return nil }
+func (m *MockAuthService) SetUserPassword(username, password string) error  { return nil }
+
+func (m *MockAuthService) BeginTOTPEnrollment(username string) (*auth.TOTPEnrollment, error) {
+	return &auth.TOTPEnrollment{}, nil
+}
+func (m *MockAuthService) ConfirmTOTPEnrollment(username, code string) ([]string, error) {
+	return nil, nil
+}
+func (m *MockAuthService) DisableTOTP(username string) error              { return nil }
+func (m *MockAuthService) VerifySecondFactor(username, code string) error { return nil }
+func (m *MockAuthService) CompleteTwoFactorLogin(challenge, code string) (string, error) {
+	return m.validToken, nil
+}
+
+func (m *MockAuthService) SetSessionPolicy(p auth.SessionPolicy) {}
+func (m *MockAuthService) TouchSession(token, ip string)         {}
+
+func (m *MockAuthService) CreateAPIToken(username, name string, scopes []string, expiresAt time.Time) (string, *auth.User, error) {
+	return "api-token", &auth.User{Kind: auth.KindAPIToken, Username: username, Name: name, Scopes: scopes}, nil
+}
+func (m *MockAuthService) ListAPITokens(username string) ([]auth.User, error) { return nil, nil }
+func (m *MockAuthService) RevokeAPIToken(username, name string) error         { return nil }
+
 type MockConfigService struct {
 	config *config.Config
 }
@@ -159,3 +195,139 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddleware_Roles(t *testing.T) {
+	mockAuth := &MockAuthService{validToken: "admin-token", roles: map[string]string{
+		"viewer-token":   auth.RoleViewer,
+		"uploader-token": auth.RoleUploader,
+	}}
+	srv := &Server{
+		auth:    mockAuth,
+		manager: manager.NewManager(nil, nil, &MockConfigService{config: &config.Config{}}),
+	}
+	protected := srv.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userFromRequest(r) == nil {
+			t.Error("Expected the user in the request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"viewer-token", "GET", "/api/packages", http.StatusOK},
+		{"viewer-token", "GET", "/files/Creator.Package.1.var", http.StatusOK},
+		{"viewer-token", "POST", "/api/download/bundle", http.StatusOK},
+		{"viewer-token", "GET", "/api/graph/roots", http.StatusOK},
+		{"viewer-token", "GET", "/api/duplicates", http.StatusOK},
+		{"viewer-token", "POST", "/api/resolve", http.StatusForbidden},
+		{"viewer-token", "POST", "/api/upload", http.StatusForbidden},
+		{"viewer-token", "POST", "/api/toggle", http.StatusForbidden},
+		{"viewer-token", "GET", "/api/users", http.StatusForbidden},
+		// Jobs carry the targets and results of every user's operations
+		{"viewer-token", "GET", "/api/jobs", http.StatusForbidden},
+		{"viewer-token", "GET", "/api/jobs/abc", http.StatusForbidden},
+		{"admin-token", "GET", "/api/jobs", http.StatusOK},
+		{"uploader-token", "POST", "/api/upload", http.StatusOK},
+		{"uploader-token", "POST", "/api/delete", http.StatusForbidden},
+		{"uploader-token", "POST", "/api/config", http.StatusForbidden},
+		{"admin-token", "POST", "/api/delete", http.StatusOK},
+		{"admin-token", "GET", "/api/users", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.token, tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestAuthMiddleware_PublicAccess(t *testing.T) {
+	srv := &Server{
+		auth:    &MockAuthService{validToken: "admin-token"},
+		manager: manager.NewManager(nil, nil, &MockConfigService{config: &config.Config{PublicAccess: true}}),
+	}
+	protected := srv.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"GET", "/api/packages", http.StatusOK},
+		{"GET", "/files/Creator.Package.1.var", http.StatusOK},
+		// Graph queries can start a scan of the whole library, guests must not be able to trigger them
+		{"GET", "/api/graph/roots", http.StatusUnauthorized},
+		{"GET", "/api/duplicates", http.StatusUnauthorized},
+		{"GET", "/api/events", http.StatusUnauthorized},
+		{"POST", "/api/toggle", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("guest %s %s: expected %d, got %d", tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestAuthMiddleware_TokenScopes(t *testing.T) {
+	token := func(role string, scopes ...string) *auth.User {
+		return &auth.User{Kind: auth.KindAPIToken, Username: "script", Role: role, Scopes: scopes}
+	}
+	mockAuth := &MockAuthService{validToken: "admin-token", apiTokens: map[string]*auth.User{
+		"read-token":      token(auth.RoleAdmin, auth.ScopePackagesRead),
+		"toggle-token":    token(auth.RoleAdmin, auth.ScopePackagesRead, auth.ScopePackagesToggle),
+		"upload-token":    token(auth.RoleAdmin, auth.ScopeUpload),
+		"overreach-token": token(auth.RoleViewer, auth.ScopePackagesToggle),
+	}}
+	srv := &Server{
+		auth:    mockAuth,
+		manager: manager.NewManager(nil, nil, &MockConfigService{config: &config.Config{}}),
+	}
+	protected := srv.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"read-token", "GET", "/api/packages", http.StatusOK},
+		{"read-token", "GET", "/api/duplicates", http.StatusOK},
+		{"read-token", "GET", "/api/graph/closure", http.StatusOK},
+		{"read-token", "GET", "/api/auth/verify", http.StatusOK},
+		{"read-token", "POST", "/api/toggle", http.StatusForbidden},
+		{"read-token", "POST", "/api/upload", http.StatusForbidden},
+		{"read-token", "POST", "/api/auth/tokens", http.StatusForbidden},
+		{"read-token", "GET", "/api/jobs/abc", http.StatusOK},
+		{"read-token", "DELETE", "/api/jobs/abc", http.StatusForbidden},
+		{"toggle-token", "POST", "/api/toggle", http.StatusOK},
+		{"toggle-token", "POST", "/api/delete", http.StatusForbidden},
+		{"upload-token", "POST", "/api/upload", http.StatusOK},
+		{"upload-token", "GET", "/api/packages", http.StatusForbidden},
+		// Scopes never grant more than the owner's role
+		{"overreach-token", "POST", "/api/toggle", http.StatusForbidden},
+		// Sessions are not limited by scopes
+		{"admin-token", "POST", "/api/config", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.token, tt.method, tt.path, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"yavam/pkg/events"
+	"yavam/pkg/graph"
+	"yavam/pkg/models"
+	"yavam/pkg/scans"
+)
+
+// packageSnapshot is the last known state of a library as served by /api/packages
+type packageSnapshot struct {
+	Library string
+	Version uint64
+	ETag    string
+	Body    []byte // Encoded []VarPackage with dependency status
+	// Packages is Body decoded, for searches. Shared between every copy of the snapshot, never modify.
+	Packages  []models.VarPackage
+	Count     int
+	ScannedAt time.Time
+
+	hash     [32]byte
+	stale    bool         // A change was detected, a refresh is pending or running
+	depGraph *graph.Graph // Built on the first graph request, see dependencyGraph
+}
+
+// packageRefresh is a pending snapshot update of one library, fed by a shared scan (see scans.Coordinator)
+type packageRefresh struct {
+	done    chan struct{}
+	sub     *scans.Subscription
+	err     error
+	again   bool // Another change arrived while scanning, run once more when done
+	initial bool // No snapshot yet, clients wait for this one and get progress and errors
+}
+
+// packageCache keeps one snapshot per library so GET /api/packages never has to scan
+// (and never cancels another client's scan). Versions are unique across libraries.
+type packageCache struct {
+	mu        sync.Mutex
+	version   uint64
+	snapshots map[string]*packageSnapshot
+	refreshes map[string]*packageRefresh
+}
+
+func newPackageCache() *packageCache {
+	return &packageCache{
+		snapshots: make(map[string]*packageSnapshot),
+		refreshes: make(map[string]*packageRefresh),
+	}
+}
+
+func libraryKey(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// get returns the snapshot of a library, nil if it was never scanned
+func (c *packageCache) get(libraryPath string) *packageSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snap, ok := c.snapshots[libraryKey(libraryPath)]
+	if !ok {
+		return nil
+	}
+	out := *snap
+	return &out
+}
+
+// store records a finished scan. The version only changes when the content did,
+// so clients polling with If-None-Match get 304 after a no-op rescan.
+func (c *packageCache) store(libraryPath string, pkgs []models.VarPackage, body []byte) (*packageSnapshot, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := libraryKey(libraryPath)
+	hash := sha256.Sum256(body)
+	snap, ok := c.snapshots[key]
+	changed := !ok || snap.hash != hash
+	if changed {
+		c.version++
+		snap = &packageSnapshot{
+			Library:  libraryPath,
+			Version:  c.version,
+			ETag:     fmt.Sprintf(`"%d"`, c.version),
+			Body:     body,
+			Packages: pkgs,
+			Count:    len(pkgs),
+			hash:     hash,
+		}
+		c.snapshots[key] = snap
+	}
+	snap.ScannedAt = time.Now()
+	snap.stale = false
+	out := *snap
+	return &out, changed
+}
+
+// dependencyGraph returns the dependency graph of a snapshot. It is built once per version and
+// kept with the cached snapshot, so graph requests never scan.
+func (c *packageCache) dependencyGraph(snap *packageSnapshot) *graph.Graph {
+	key := libraryKey(snap.Library)
+	c.mu.Lock()
+	if cached, ok := c.snapshots[key]; ok && cached.Version == snap.Version && cached.depGraph != nil {
+		g := cached.depGraph
+		c.mu.Unlock()
+		return g
+	}
+	c.mu.Unlock()
+
+	// Built outside the lock; concurrent first requests may both build it, the last one is kept
+	g := graph.Build(snap.Packages)
+	c.mu.Lock()
+	if cached, ok := c.snapshots[key]; ok && cached.Version == snap.Version {
+		cached.depGraph = g
+	}
+	c.mu.Unlock()
+	return g
+}
+
+// markStale flags every cached library containing one of paths (a library, package or folder path)
+// and returns those libraries. Without paths every cached library is flagged.
+func (c *packageCache) markStale(paths ...string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var libs []string
+	for key, snap := range c.snapshots {
+		match := len(paths) == 0
+		for _, p := range paths {
+			k := libraryKey(p)
+			if k == key || strings.HasPrefix(k, key+string(os.PathSeparator)) {
+				match = true
+				break
+			}
+		}
+		if match {
+			snap.stale = true
+			libs = append(libs, snap.Library)
+		}
+	}
+	return libs
+}
+
+// cancelAll detaches from every running refresh; scans other clients still listen to keep running
+func (c *packageCache) cancelAll() {
+	c.mu.Lock()
+	refreshes := make([]*packageRefresh, 0, len(c.refreshes))
+	for _, r := range c.refreshes {
+		r.again = false
+		refreshes = append(refreshes, r)
+	}
+	c.mu.Unlock()
+	for _, r := range refreshes {
+		r.sub.Unsubscribe()
+	}
+}
+
+// refreshPackages updates the snapshot of libraryPath from a scan shared with every other client
+// (see scans.Coordinator). If a refresh is already pending it is reused, and repeated afterwards if
+// requeue is set, because it may have missed the change. The returned refresh is done once the
+// snapshot is updated. Only the first scan of a library reports progress; later ones are silent
+// until packages:updated announces the new snapshot.
+func (s *Server) refreshPackages(libraryPath string, requeue bool) *packageRefresh {
+	c := s.packages
+	key := libraryKey(libraryPath)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if r, ok := c.refreshes[key]; ok {
+		r.again = r.again || requeue
+		return r
+	}
+
+	// Not tied to the request: the scan is shared and outlives the client that triggered it
+	_, scanned := c.snapshots[key]
+	r := &packageRefresh{done: make(chan struct{}), initial: !scanned}
+	var onProgress func(current, total int)
+	if r.initial {
+		onProgress = func(current, total int) {
+			s.Broadcast("scan:progress", map[string]interface{}{
+				"current": current,
+				"total":   total,
+			})
+		}
+	}
+	r.sub = s.manager.Scans().Subscribe(libraryPath, nil, onProgress)
+	// A scan started earlier (e.g. by the desktop) may predate the change
+	r.again = requeue && r.sub.Joined()
+	c.refreshes[key] = r
+
+	s.scanWg.Add(1)
+	go func() {
+		defer s.scanWg.Done()
+		r.err = s.storePackages(libraryPath, r.sub)
+		if r.err != nil {
+			if r.initial {
+				s.Broadcast("scan:error", r.err.Error())
+			} else {
+				fmt.Printf("[Server] Refreshing packages of %s failed: %v\n", libraryPath, r.err)
+			}
+		}
+
+		c.mu.Lock()
+		delete(c.refreshes, key)
+		again := r.again && r.err == nil
+		c.mu.Unlock()
+		close(r.done)
+
+		if again {
+			s.refreshPackages(libraryPath, false)
+		}
+	}()
+	return r
+}
+
+// librarySnapshot returns the snapshot of a library. Only a library that was never scanned is
+// scanned here, and concurrent first requests share that scan.
+func (s *Server) librarySnapshot(ctx context.Context, libraryPath string) (*packageSnapshot, error) {
+	if snap := s.packages.get(libraryPath); snap != nil {
+		return snap, nil
+	}
+	s.log(fmt.Sprintf("Client requested packages for: %s (not scanned yet)", libraryPath))
+	refresh := s.refreshPackages(libraryPath, false)
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if refresh.err != nil {
+		return nil, refresh.err
+	}
+	snap := s.packages.get(libraryPath)
+	if snap == nil {
+		return nil, fmt.Errorf("library scan did not complete")
+	}
+	return snap, nil
+}
+
+// snapshotPackages is a manager.PackageSource reading the cached snapshot instead of scanning
+func (s *Server) snapshotPackages(ctx context.Context, libraryPath string) ([]models.VarPackage, error) {
+	snap, err := s.librarySnapshot(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Packages, nil
+}
+
+// libraryGraph returns the dependency graph of the cached snapshot of a library
+func (s *Server) libraryGraph(ctx context.Context, libraryPath string) (*graph.Graph, error) {
+	snap, err := s.librarySnapshot(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return s.packages.dependencyGraph(snap), nil
+}
+
+// storePackages waits for a scan and stores the resulting snapshot
+func (s *Server) storePackages(libraryPath string, sub *scans.Subscription) error {
+	if err := sub.Err(); err != nil {
+		return err
+	}
+
+	// Dependency status needs the whole library, so it is only attached to the snapshot
+	pkgs := s.manager.CheckDependencies(sub.Packages())
+	if pkgs == nil {
+		pkgs = []models.VarPackage{}
+	}
+	// Scans finish in any order; a stable order keeps the ETag of an unchanged library stable
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].FilePath < pkgs[j].FilePath })
+	body, err := json.Marshal(pkgs)
+	if err != nil {
+		return err
+	}
+
+	snap, changed := s.packages.store(libraryPath, pkgs, body)
+	if changed {
+		s.Broadcast("packages:updated", map[string]interface{}{
+			"path":    libraryPath,
+			"version": snap.Version,
+			"count":   snap.Count,
+		})
+	}
+	return nil
+}
+
+// PackagesChanged marks the cached package lists containing paths as outdated and rescans them in
+// the background. Called for watcher events and after every request that modifies a library.
+func (s *Server) PackagesChanged(paths ...string) {
+	for _, lib := range s.packages.markStale(paths...) {
+		s.refreshPackages(lib, true)
+	}
+}
+
+// onEvent forwards an event of the bus to SSE clients and refreshes the package lists it changed
+func (s *Server) onEvent(e events.Event) {
+	s.Broadcast(e.Name(), e)
+	if c, ok := e.(events.Change); ok {
+		if paths := c.Paths(); len(paths) > 0 {
+			s.PackagesChanged(paths...)
+		}
+	}
+}
+
+// etagMatches implements If-None-Match for a single strong ETag
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -3,19 +3,27 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"yavam/pkg/events"
+	"yavam/pkg/jobs"
 	"yavam/pkg/manager"
 	"yavam/pkg/models"
+	"yavam/pkg/search"
 	"yavam/pkg/services/auth"
 	"yavam/pkg/services/config"
+	"yavam/pkg/thumbnails"
 	"yavam/pkg/updater"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -34,15 +42,17 @@ type Server struct {
 	version   string           // App Version
 	onRestore func()
 
-	// SSE Clients
-	clients   map[chan string]bool
+	// SSE Clients, replaying from the event log on reconnect
+	clients   map[*sseClient]bool
 	clientsMu sync.Mutex
+	events    *eventLog
 
-	// Scan Management
-	scanMu     sync.Mutex
-	scanCancel context.CancelFunc
+	// Scan Management: last known package list per library, refreshed in the background
+	packages *packageCache
+	scanWg   sync.WaitGroup
 
-	scanWg sync.WaitGroup
+	// Removes the event bus subscription that forwards events to SSE clients
+	unsubscribeEvents func()
 
 	SkipEvents bool // For testing
 }
@@ -56,7 +66,9 @@ func NewServer(ctx context.Context, m *manager.Manager, authService auth.AuthSer
 		libraries: []string{},
 		assets:    assets,
 		version:   version,
-		clients:   make(map[chan string]bool),
+		clients:   make(map[*sseClient]bool),
+		events:    newEventLog(),
+		packages:  newPackageCache(),
 	}
 }
 
@@ -106,12 +118,7 @@ func (s *Server) writeError(w http.ResponseWriter, message string, code int) {
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		remoteIP := r.RemoteAddr
-		if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-			remoteIP = ip
-		}
-
-		s.log(fmt.Sprintf("[Request] %s -> %s %s", remoteIP, r.Method, r.URL.Path))
+		s.log(fmt.Sprintf("[Request] %s -> %s %s", clientIP(r), r.Method, r.URL.Path))
 
 		next.ServeHTTP(w, r)
 
@@ -135,7 +142,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Broadcast sends a message to all connected SSE clients
+// Broadcast sends a message to all connected SSE clients. Every message gets the next event ID and
+// is kept in the event log, so clients that fall behind or reconnect can catch up.
 func (s *Server) Broadcast(eventType string, data interface{}) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
@@ -144,23 +152,12 @@ func (s *Server) Broadcast(eventType string, data interface{}) {
 		return
 	}
 
-	payload := map[string]interface{}{
-		"event": eventType,
-		"data":  data,
-	}
-	jsonBytes, err := json.Marshal(payload)
-	if err != nil {
+	if _, err := s.events.append(eventType, data); err != nil {
 		fmt.Printf("Error marshalling broadcast: %v\n", err)
 		return
 	}
-	msg := fmt.Sprintf("data: %s\n\n", string(jsonBytes))
-
-	for clientChan := range s.clients {
-		select {
-		case clientChan <- msg:
-		default:
-			// Drop message if channel is full to prevent blocking
-		}
+	for client := range s.clients {
+		client.notify()
 	}
 }
 
@@ -183,18 +180,31 @@ func (s *Server) Start(port string, libraries []string) error {
 		w.Header().Set("Connection", "keep-alive")
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
-		clientChan := make(chan string, 1000) // Increase buffer
+		client := newSSEClient()
 		s.clientsMu.Lock()
-		s.clients[clientChan] = true
+		s.clients[client] = true
 		s.clientsMu.Unlock()
 
 		defer func() {
 			s.clientsMu.Lock()
-			delete(s.clients, clientChan)
+			delete(s.clients, client)
 			s.clientsMu.Unlock()
-			close(clientChan)
 		}()
 
+		// Resume after the last event the client saw, or start with the next one
+		lastID, resume := parseLastEventID(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("lastEventId"))
+		if !resume {
+			lastID = s.events.latest()
+		} else {
+			client.notify()
+		}
+
+		flush := func() {
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		}
+
 		notify := r.Context().Done()
 		ticker := time.NewTicker(15 * time.Second)
 		defer ticker.Stop()
@@ -203,11 +213,21 @@ func (s *Server) Start(port string, libraries []string) error {
 			select {
 			case <-notify:
 				return
-			case msg := <-clientChan:
-				fmt.Fprint(w, msg)
-				if flusher, ok := w.(http.Flusher); ok {
-					flusher.Flush()
+			case <-client.wake:
+				msgs, newest, ok := s.events.since(lastID)
+				if !ok {
+					// Too far behind: the missed events are gone, the client has to refetch its state
+					var msg string
+					msg, lastID = s.events.resyncMessage(lastID)
+					fmt.Fprint(w, msg)
+					flush()
+					continue
+				}
+				for _, msg := range msgs {
+					fmt.Fprint(w, msg)
 				}
+				lastID = newest
+				flush()
 			case <-ticker.C:
 				// Send a comment to keep the connection alive
 				fmt.Fprint(w, ": keep-alive\n\n")
@@ -219,18 +239,24 @@ func (s *Server) Start(port string, libraries []string) error {
 	})))
 
 	// Scan Cancel Endpoint
-	mux.HandleFunc("/api/scan/cancel", func(w http.ResponseWriter, r *http.Request) {
-		s.scanMu.Lock()
-		if s.scanCancel != nil {
-			s.scanCancel()
+	// ?path=<library> cancels that library's scan for every client (desktop included),
+	// otherwise the web clients stop listening and scans nobody else follows are cancelled.
+	mux.Handle("/api/scan/cancel", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if libraryPath := r.URL.Query().Get("path"); libraryPath != "" {
+			if err := s.manager.ValidatePath(libraryPath); err != nil {
+				s.writeError(w, "Access denied to this library path", 403)
+				return
+			}
+			s.manager.Scans().Cancel(libraryPath)
+		} else {
+			s.packages.cancelAll()
 		}
-		s.scanMu.Unlock()
 
 		// Wait for completion
 		s.scanWg.Wait()
 
 		json.NewEncoder(w).Encode(map[string]bool{"success": true})
-	})
+	})))
 
 	// Rate Limiter for Login: 5 attempts per minute
 	loginLimiter := NewRateLimiter(5, 1*time.Minute)
@@ -291,12 +317,26 @@ func (s *Server) Start(port string, libraries []string) error {
 		}
 
 		token, err := s.auth.Login(req.Username, req.Password, req.DeviceName)
+		var twoFactor *auth.TwoFactorRequiredError
+		if errors.As(err, &twoFactor) {
+			// Password accepted, the session is created by /api/auth/login/totp
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":           false,
+				"message":           "Two-factor code required",
+				"twoFactorRequired": true,
+				"challenge":         twoFactor.Challenge,
+			})
+			return
+		}
 		if err != nil {
 			s.log(fmt.Sprintf("Login failed for user '%s' from device '%s': %v", req.Username, req.DeviceName, err))
 			// Generic error for security
 			s.writeError(w, "Invalid credentials", 401)
 			return
 		}
+		s.auth.TouchSession(token, clientIP(r))
 		s.log(fmt.Sprintf("User '%s' logged in successfully from '%s'", req.Username, req.DeviceName))
 
 		w.Header().Set("Content-Type", "application/json")
@@ -306,6 +346,117 @@ func (s *Server) Start(port string, libraries []string) error {
 		})
 	}))
 
+	// Auth: Second login step for accounts with two-factor authentication
+	mux.HandleFunc("/api/auth/login/totp", loginLimiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Challenge string `json:"challenge"`
+			Code      string `json:"code"` // TOTP or recovery code
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+
+		if s.auth == nil {
+			s.writeError(w, "Auth service not initialized", 500)
+			return
+		}
+
+		token, err := s.auth.CompleteTwoFactorLogin(req.Challenge, req.Code)
+		if err != nil {
+			s.log(fmt.Sprintf("Two-factor login failed from %s: %v", clientIP(r), err))
+			if err == auth.ErrInvalidLoginAttempt {
+				s.writeError(w, err.Error(), 401)
+				return
+			}
+			s.writeError(w, "Invalid code", 401)
+			return
+		}
+		s.auth.TouchSession(token, clientIP(r))
+		s.log(fmt.Sprintf("Two-factor login completed from %s", clientIP(r)))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"token":   token,
+		})
+	}))
+
+	// Two-Factor Setup of the signed-in user: POST starts enrollment ({"secret", "uri"}), DELETE {"code"} turns it off
+	mux.Handle("/api/auth/totp", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := userFromRequest(r)
+		if user == nil {
+			s.writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "POST":
+			enrollment, err := s.auth.BeginTOTPEnrollment(user.Username)
+			if err != nil {
+				s.writeTOTPError(w, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(enrollment)
+		case "DELETE":
+			var req struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			if err := s.auth.VerifySecondFactor(user.Username, req.Code); err != nil {
+				s.writeTOTPError(w, err)
+				return
+			}
+			if err := s.auth.DisableTOTP(user.Username); err != nil {
+				s.writeTOTPError(w, err)
+				return
+			}
+			s.log(fmt.Sprintf("User '%s' disabled two-factor authentication", user.Username))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Two-Factor Setup: POST {"code"} confirms enrollment with a code from the app and returns the recovery codes
+	mux.Handle("/api/auth/totp/confirm", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := userFromRequest(r)
+		if user == nil {
+			s.writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+		codes, err := s.auth.ConfirmTOTPEnrollment(user.Username, req.Code)
+		if err != nil {
+			s.writeTOTPError(w, err)
+			return
+		}
+		s.log(fmt.Sprintf("User '%s' enabled two-factor authentication", user.Username))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":       true,
+			"recoveryCodes": codes,
+		})
+	})))
+
 	// Auth: List Sessions
 	mux.Handle("/api/auth/sessions", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sessions, err := s.auth.ListSessions()
@@ -326,130 +477,678 @@ func (s *Server) Start(port string, libraries []string) error {
 		}
 
 		var req struct {
-			Libraries []string `json:"libraries"`
+			Libraries []string `json:"libraries"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+
+		// Security Check: Ensure all requested paths are allowed?
+		// Actually, if client requests counts for arbitrary path, it might be info leak.
+		// We should validate each path against s.libraries?
+		// Or trust Manager? Manager gets counts for whatever path.
+		// Let's validate.
+		for _, lib := range req.Libraries {
+			if err := s.manager.ValidatePath(lib); err != nil {
+				s.writeError(w, "Access denied: Invalid library path", 403)
+				return
+			}
+		}
+
+		counts := s.manager.GetLibraryCounts(req.Libraries)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(counts)
+	})))
+
+	// Verify Endpoint
+	mux.Handle("/api/auth/verify", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		resp := map[string]interface{}{"success": true}
+		if user := userFromRequest(r); user != nil {
+			resp["username"] = user.Username
+			resp["role"] = user.Role
+		}
+		json.NewEncoder(w).Encode(resp)
+	})))
+
+	// Auth: Revoke Session
+	mux.Handle("/api/auth/revoke", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+
+		if err := s.auth.RevokeSession(req.ID); err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+
+		// Broadcast revoked event to force clients to logout immediately
+		s.Broadcast("auth:revoked", map[string]string{"id": req.ID})
+
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	})))
+
+	// API Tokens of the signed-in user: GET lists them, POST {"name", "scopes", "expiresAt"} creates one.
+	// The token itself is only returned on creation.
+	mux.Handle("/api/auth/tokens", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := userFromRequest(r)
+		if user == nil {
+			s.writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			tokens, err := s.auth.ListAPITokens(user.Username)
+			if err != nil {
+				s.writeError(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(tokens)
+		case "POST":
+			var req struct {
+				Name      string   `json:"name"`
+				Scopes    []string `json:"scopes"`
+				ExpiresAt string   `json:"expiresAt"` // RFC3339, empty for no expiry
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			var expiresAt time.Time
+			if req.ExpiresAt != "" {
+				t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+				if err != nil {
+					s.writeError(w, "Invalid expiresAt, expected RFC3339", 400)
+					return
+				}
+				expiresAt = t
+			}
+			token, info, err := s.auth.CreateAPIToken(user.Username, req.Name, req.Scopes, expiresAt)
+			if err != nil {
+				if err == auth.ErrTokenExists {
+					s.writeError(w, err.Error(), 409)
+					return
+				}
+				s.writeUserError(w, err)
+				return
+			}
+			s.log(fmt.Sprintf("User '%s' created API token '%s'", user.Username, info.Name))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token": token,
+				"info":  info,
+			})
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// API Tokens: DELETE /api/auth/tokens/{name} revokes a token of the signed-in user
+	mux.Handle("/api/auth/tokens/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := userFromRequest(r)
+		if user == nil {
+			s.writeError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != "DELETE" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/api/auth/tokens/")
+		tokens, _ := s.auth.ListAPITokens(user.Username)
+		if err := s.auth.RevokeAPIToken(user.Username, name); err != nil {
+			if err == auth.ErrTokenNotFound {
+				s.writeError(w, err.Error(), 404)
+			} else {
+				s.writeError(w, err.Error(), 500)
+			}
+			return
+		}
+		for _, t := range tokens {
+			if t.Name == name {
+				s.Broadcast("auth:revoked", map[string]string{"id": t.ID})
+			}
+		}
+		s.log(fmt.Sprintf("User '%s' revoked API token '%s'", user.Username, name))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	})))
+
+	// User Management (admin only): GET lists accounts, POST {"username", "password", "role"} creates one
+	mux.Handle("/api/users", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			users, err := s.auth.ListUsers()
+			if err != nil {
+				s.writeError(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(users)
+		case "POST":
+			var req struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				Role     string `json:"role"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			if err := s.auth.CreateUser(req.Username, req.Password, req.Role); err != nil {
+				s.writeUserError(w, err)
+				return
+			}
+			s.log(fmt.Sprintf("Created %s account '%s'", req.Role, req.Username))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// User Management (admin only): PUT /api/users/{name} {"role", "password"} (both optional), DELETE removes the account
+	mux.Handle("/api/users/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := strings.TrimPrefix(r.URL.Path, "/api/users/")
+		switch r.Method {
+		case "PUT":
+			var req struct {
+				Role             string `json:"role"`
+				Password         string `json:"password"`
+				DisableTwoFactor bool   `json:"disableTwoFactor"` // For users who lost their authenticator
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			if req.Role != "" {
+				if err := s.auth.SetUserRole(username, req.Role); err != nil {
+					s.writeUserError(w, err)
+					return
+				}
+			}
+			if req.Password != "" {
+				if err := s.auth.SetUserPassword(username, req.Password); err != nil {
+					s.writeUserError(w, err)
+					return
+				}
+			}
+			if req.DisableTwoFactor {
+				if err := s.auth.DisableTOTP(username); err != nil {
+					s.writeUserError(w, err)
+					return
+				}
+			}
+			s.log(fmt.Sprintf("Updated account '%s'", username))
+		case "DELETE":
+			if err := s.auth.DeleteUser(username); err != nil {
+				s.writeUserError(w, err)
+				return
+			}
+			s.log(fmt.Sprintf("Deleted account '%s'", username))
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	})))
+
+	mux.Handle("/api/packages", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Clients may keep the list but have to revalidate it (ETag / If-None-Match)
+		w.Header().Set("Cache-Control", "no-cache")
+
+		// Allow client to request a specific library, default to activePath
+		targetPath := r.URL.Query().Get("path")
+
+		if targetPath == "" {
+			s.writeError(w, "No library path selected", 400)
+			return
+		}
+
+		// Security: Ensure targetPath is in allowed libraries
+		if err := s.manager.ValidatePath(targetPath); err != nil {
+			s.log(fmt.Sprintf("Access denied. Target: %s. Error: %v", targetPath, err))
+			s.writeError(w, "Access denied to this library path", 403)
+			return
+		}
+
+		// The last known state is served immediately, see librarySnapshot
+		snap, err := s.librarySnapshot(r.Context(), targetPath)
+		if err != nil {
+			if r.Context().Err() == nil {
+				s.writeError(w, err.Error(), 500)
+			}
+			return
+		}
+
+		w.Header().Set("ETag", snap.ETag)
+		w.Header().Set("X-Library-Version", strconv.FormatUint(snap.Version, 10))
+		if snap.stale {
+			w.Header().Set("X-Library-Stale", "true")
+		}
+		if etagMatches(r.Header.Get("If-None-Match"), snap.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(snap.Body)
+	})))
+
+	// Explicit rescan of a library: POST /api/packages/refresh {"path": "<library>"}.
+	// Runs in the background; packages:updated announces a new version.
+	mux.Handle("/api/packages/refresh", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+		if err := s.manager.ValidatePath(req.Path); err != nil {
+			s.writeError(w, "Access denied to this library path", 403)
+			return
+		}
+		s.packages.markStale(req.Path)
+		s.refreshPackages(req.Path, true)
+		s.log(fmt.Sprintf("Rescan requested for %s", req.Path))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]bool{"started": true})
+	})))
+
+	// Search: filtered, sorted and paged packages so clients do not need the whole library
+	// GET /api/search?path=<library>&q=<query>&sort=<mode>&offset=0&limit=100
+	mux.Handle("/api/search", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		params := r.URL.Query()
+		libraryPath := params.Get("path")
+		if libraryPath == "" {
+			s.writeError(w, "No library path selected", 400)
+			return
+		}
+		if err := s.manager.ValidatePath(libraryPath); err != nil {
+			s.writeError(w, "Access denied to this library path", 403)
+			return
+		}
+
+		opts := search.Options{Sort: params.Get("sort")}
+		for name, target := range map[string]*int{"offset": &opts.Offset, "limit": &opts.Limit} {
+			if v := params.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					s.writeError(w, fmt.Sprintf("Invalid %s", name), 400)
+					return
+				}
+				*target = n
+			}
+		}
+		// Reject bad queries before waiting for a first scan
+		q, err := search.Parse(params.Get("q"))
+		if err != nil {
+			s.writeError(w, err.Error(), 400)
+			return
+		}
+		if !search.ValidSort(opts.Sort) {
+			s.writeError(w, "Unknown sort mode", 400)
+			return
+		}
+
+		// Searches the snapshot served by /api/packages, which already carries the dependency status
+		snap, err := s.librarySnapshot(r.Context(), libraryPath)
+		if err != nil {
+			if r.Context().Err() == nil {
+				s.writeError(w, err.Error(), 500)
+			}
+			return
+		}
+		result := search.Run(snap.Packages, q, opts)
+		w.Header().Set("X-Library-Version", strconv.FormatUint(snap.Version, 10))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})))
+
+	// Full-text search over descriptions, tags and inner file names (index is built during scans)
+	// GET /api/search/text?q=<words>&path=<library, optional>&limit=50
+	mux.Handle("/api/search/text", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		params := r.URL.Query()
+		if strings.TrimSpace(params.Get("q")) == "" {
+			s.writeError(w, "Query is empty", 400)
+			return
+		}
+		libraryPath := params.Get("path")
+		if libraryPath != "" {
+			if err := s.manager.ValidatePath(libraryPath); err != nil {
+				s.writeError(w, "Access denied to this library path", 403)
+				return
+			}
+		}
+		limit := 50
+		if v := params.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				s.writeError(w, "Invalid limit", 400)
+				return
+			}
+			limit = min(n, search.MaxLimit)
+		}
+
+		hits, err := s.manager.SearchText(params.Get("q"), libraryPath, limit)
+		if err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hits)
+	})))
+
+	// Index Invalidation Endpoint (next /api/packages request re-parses every file)
+	mux.Handle("/api/index/invalidate", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+
+		if err := s.manager.ValidatePath(req.Path); err != nil {
+			s.writeError(w, "Access denied: Invalid library path", 403)
+			return
+		}
+
+		removed := s.manager.InvalidateIndex(req.Path)
+		s.log(fmt.Sprintf("Invalidated %d index entries for %s", removed, req.Path))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":     true,
+			"invalidated": removed,
+		})
+	})))
+
+	// Dependency Graph: /api/graph/{used-by,roots,orphans,closure,cycles}?path=<library>[&filePath=<package>]
+	mux.Handle("/api/graph/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		libraryPath := r.URL.Query().Get("path")
+		filePath := r.URL.Query().Get("filePath")
+		if libraryPath == "" {
+			s.writeError(w, "No library path selected", 400)
+			return
+		}
+		if err := s.manager.ValidatePath(libraryPath); err != nil {
+			s.writeError(w, "Access denied to this library path", 403)
+			return
+		}
+
+		query := strings.TrimPrefix(r.URL.Path, "/api/graph/")
+		switch query {
+		case "used-by", "roots", "orphans", "closure", "cycles":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if query == "used-by" || query == "closure" {
+			if filePath == "" {
+				s.writeError(w, "filePath is required", 400)
+				return
+			}
+			if err := s.manager.ValidatePath(filePath); err != nil {
+				s.writeError(w, "Access denied", 403)
+				return
+			}
+		}
+
+		// Served from the snapshot of /api/packages, the graph is only rebuilt when the library changed
+		g, err := s.libraryGraph(r.Context(), libraryPath)
+		if err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+
+		var result interface{}
+		switch query {
+		case "used-by":
+			if _, ok := g.Lookup(filePath); !ok {
+				s.writeError(w, "Package not found in library", 404)
+				return
+			}
+			result = g.UsedBy(filePath)
+		case "roots":
+			result = g.Roots()
+		case "orphans":
+			result = g.Orphans()
+		case "closure":
+			closure, ok := g.Closure(filePath)
+			if !ok {
+				s.writeError(w, "Package not found in library", 404)
+				return
+			}
+			result = closure
+		case "cycles":
+			result = g.Cycles()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})))
+
+	// Duplicate Report: byte-identical packages across all libraries
+	mux.Handle("/api/duplicates", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groups, err := s.manager.FindDuplicates(r.Context())
+		if err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(groups)
+	})))
+
+	// Quarantine: GET lists a library's quarantine, POST moves a package into it
+	mux.Handle("/api/quarantine", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			libraryPath := r.URL.Query().Get("path")
+			if err := s.manager.ValidatePath(libraryPath); err != nil {
+				s.writeError(w, "Access denied to this library path", 403)
+				return
+			}
+			entries, err := s.manager.ListQuarantine(libraryPath)
+			if err != nil {
+				s.writeError(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entries)
+		case "POST":
+			var req struct {
+				FilePath    string `json:"filePath"`
+				LibraryPath string `json:"libraryPath"`
+				Reason      string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			if err := s.manager.ValidatePath(req.LibraryPath); err != nil {
+				s.writeError(w, "Security violation: Invalid library", 403)
+				return
+			}
+			if err := s.manager.ValidatePath(req.FilePath); err != nil {
+				s.writeError(w, "Security violation: Invalid file path", 403)
+				return
+			}
+			entry, err := s.manager.Quarantine(req.FilePath, req.LibraryPath, req.Reason)
+			if err != nil {
+				s.writeError(w, err.Error(), 500)
+				return
+			}
+			s.log(fmt.Sprintf("Quarantined package: %s", entry.FileName))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(entry)
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Quarantine Actions: /api/quarantine/restore {"libraryPath", "id"} and /api/quarantine/corrupt {"libraryPath"}
+	mux.Handle("/api/quarantine/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			LibraryPath string `json:"libraryPath"`
+			ID          string `json:"id"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			s.writeError(w, "Invalid request body", 400)
 			return
 		}
+		if err := s.manager.ValidatePath(req.LibraryPath); err != nil {
+			s.writeError(w, "Security violation: Invalid library", 403)
+			return
+		}
 
-		// Security Check: Ensure all requested paths are allowed?
-		// Actually, if client requests counts for arbitrary path, it might be info leak.
-		// We should validate each path against s.libraries?
-		// Or trust Manager? Manager gets counts for whatever path.
-		// Let's validate.
-		for _, lib := range req.Libraries {
-			if err := s.manager.ValidatePath(lib); err != nil {
-				s.writeError(w, "Access denied: Invalid library path", 403)
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/api/quarantine/") {
+		case "restore":
+			restored, err := s.manager.Restore(req.LibraryPath, req.ID)
+			if os.IsNotExist(err) {
+				s.writeError(w, "Quarantine entry not found", 404)
+				return
+			}
+			if err != nil {
+				s.writeError(w, err.Error(), 409)
+				return
+			}
+			s.log(fmt.Sprintf("Restored package: %s", filepath.Base(restored)))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":  true,
+				"filePath": restored,
+			})
+		case "corrupt":
+			moved, err := s.manager.QuarantineCorrupt(r.Context(), req.LibraryPath)
+			if err != nil {
+				s.writeError(w, err.Error(), 500)
 				return
 			}
+			s.log(fmt.Sprintf("Quarantined %d corrupt packages", len(moved)))
+			json.NewEncoder(w).Encode(moved)
+		default:
+			s.writeError(w, "Unknown quarantine action", 404)
 		}
-
-		counts := s.manager.GetLibraryCounts(req.Libraries)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(counts)
-	})))
-
-	// Verify Endpoint
-	mux.Handle("/api/auth/verify", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
-		w.Header().Set("Pragma", "no-cache")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
 	})))
 
-	// Auth: Revoke Session
-	mux.Handle("/api/auth/revoke", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// Salvage: rebuilds a damaged package from the entries that still verify, the original is kept
+	mux.Handle("/api/salvage", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		var req struct {
-			ID string `json:"id"`
+			FilePath string `json:"filePath"`
+			Dest     string `json:"dest"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			s.writeError(w, "Invalid request body", 400)
 			return
 		}
-
-		if err := s.auth.RevokeSession(req.ID); err != nil {
-			s.writeError(w, err.Error(), 500)
+		if err := s.manager.ValidatePath(req.FilePath); err != nil {
+			s.writeError(w, "Security violation: Invalid file path", 403)
 			return
 		}
-
-		// Broadcast revoked event to force clients to logout immediately
-		s.Broadcast("auth:revoked", map[string]string{"id": req.ID})
-
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
-	})))
-
-	mux.Handle("/api/packages", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		w.Header().Set("Content-Type", "application/json")
-
-		// Allow client to request a specific library, default to activePath
-		targetPath := r.URL.Query().Get("path")
-
-		if targetPath == "" {
-			s.writeError(w, "No library path selected", 400)
+		if err := s.manager.ValidatePath(req.Dest); err != nil {
+			s.writeError(w, "Security violation: Invalid destination", 403)
 			return
 		}
 
-		if targetPath == "" {
-			s.writeError(w, "No library path selected", 400)
+		report, err := s.manager.SalvagePackage(req.FilePath, req.Dest)
+		if err != nil {
+			if report == nil {
+				s.writeError(w, err.Error(), 500)
+				return
+			}
+			// Nothing recoverable: still return what was found so the client can show the losses
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":  err.Error(),
+				"report": report,
+			})
 			return
 		}
+		s.log(fmt.Sprintf("Salvaged %s: %d entries recovered, %d lost", filepath.Base(req.FilePath), len(report.Recovered), len(report.Lost)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	})))
 
-		s.log(fmt.Sprintf("Client requested packages for: %s", targetPath))
-
-		// Security: Ensure targetPath is in allowed libraries
-		if err := s.manager.ValidatePath(targetPath); err != nil {
-			s.log(fmt.Sprintf("Access denied. Target: %s. Error: %v", targetPath, err))
-			s.writeError(w, "Access denied to this library path", 403)
+	// Integrity Check: /api/integrity/start {"path": "<library>"} and /api/integrity/cancel.
+	// Results arrive as integrity:progress / integrity:complete events.
+	mux.Handle("/api/integrity/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Cancel previous scan if running (wait for it)
-		s.scanMu.Lock()
-		if s.scanCancel != nil {
-			s.scanCancel()
-		}
-		s.scanMu.Unlock()
-		s.scanWg.Wait()
-
-		s.scanMu.Lock()
-		// Wrap request context but allow manual cancellation
-		ctx, cancel := context.WithCancel(r.Context())
-		s.scanCancel = cancel
-		s.scanMu.Unlock()
-
-		s.scanWg.Add(1)
-		defer s.scanWg.Done()
-
-		var pkgs []models.VarPackage
-		err := s.manager.ScanAndAnalyze(ctx, targetPath, func(p models.VarPackage) {
-			pkgs = append(pkgs, p)
-			// Broadcast package to web clients (incremental update)
-			s.Broadcast("package:scanned", p)
-		}, func(current, total int) {
-			// Broadcast progress to web clients
-			s.Broadcast("scan:progress", map[string]interface{}{
-				"current": current,
-				"total":   total,
-			})
-		})
-		if err != nil {
-			s.Broadcast("scan:error", err.Error())
-			s.writeError(w, err.Error(), 500)
-			return
+		switch strings.TrimPrefix(r.URL.Path, "/api/integrity/") {
+		case "start":
+			var req struct {
+				Path string `json:"path"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+			if err := s.manager.ValidatePath(req.Path); err != nil {
+				s.writeError(w, "Access denied to this library path", 403)
+				return
+			}
+			job := s.startIntegrityCheck(req.Path)
+			s.log(fmt.Sprintf("Integrity check started for %s", req.Path))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{"started": true, "jobId": job.ID})
+		case "cancel":
+			// Optional {"path": "<library>"}, without it every running check is stopped
+			var req struct {
+				Path string `json:"path"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			s.manager.CancelIntegrityCheck(req.Path)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]bool{"success": true})
+		default:
+			s.writeError(w, "Unknown integrity action", 404)
 		}
-
-		// Notify completion so frontend stops spinner
-		s.Broadcast("scan:complete", true)
-
-		json.NewEncoder(w).Encode(pkgs)
 	})))
 
 	// Disk Space Endpoint
@@ -504,15 +1203,35 @@ func (s *Server) Start(port string, libraries []string) error {
 			return
 		}
 
-		thumbData, err := s.manager.GetThumbnail(filePath)
+		// size: 160, 320 or full (default). Smaller variants are pre-resized in the thumbnail cache.
+		size, err := thumbnails.ParseSize(r.URL.Query().Get("size"))
+		if err != nil {
+			s.writeError(w, err.Error(), 400)
+			return
+		}
+
+		thumbPath, err := s.manager.GetThumbnailFile(filePath, size)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := os.Open(thumbPath)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
 		if err != nil {
 			http.NotFound(w, r)
 			return
 		}
 
-		w.Header().Set("Content-Type", "image/jpeg")
-		w.Header().Set("Cache-Control", "public, max-age=86400")
-		w.Write(thumbData)
+		// The cache file name is derived from the package identity and variant, so it doubles as ETag.
+		// ServeContent answers If-None-Match with 304 and sniffs the image type.
+		w.Header().Set("ETag", `"`+filepath.Base(thumbPath)+`"`)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		http.ServeContent(w, r, "", info.ModTime(), f)
 	})))
 
 	// Contents Endpoint (for Web Mode)
@@ -545,6 +1264,36 @@ func (s *Server) Start(port string, libraries []string) error {
 		json.NewEncoder(w).Encode(contents)
 	})))
 
+	// Details Endpoint: contents plus undeclared/unused dependency references
+	mux.Handle("/api/details", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			FilePath string `json:"filePath"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, err.Error(), 400)
+			return
+		}
+
+		// Security Check
+		if err := s.manager.ValidatePath(req.FilePath); err != nil {
+			s.writeError(w, "Access denied", 403)
+			return
+		}
+
+		details, err := s.manager.GetPackageDetails(req.FilePath)
+		if err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(details)
+	})))
+
 	// Config Endpoint
 	mux.Handle("/api/config", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "POST" {
@@ -562,7 +1311,18 @@ func (s *Server) Start(port string, libraries []string) error {
 						cfg.PublicAccess = v
 					}
 				}
+				// Session lifetimes in days, 0 for no limit
+				if val, ok := req["sessionMaxAgeDays"].(float64); ok && val >= 0 {
+					cfg.SessionMaxAgeDays = int(val)
+				}
+				if val, ok := req["sessionIdleDays"].(float64); ok && val >= 0 {
+					cfg.SessionIdleDays = int(val)
+				}
 			})
+			if err == nil {
+				cfg := s.manager.GetConfig()
+				s.auth.SetSessionPolicy(auth.SessionPolicyFromDays(cfg.SessionMaxAgeDays, cfg.SessionIdleDays))
+			}
 
 			if err != nil {
 				s.writeError(w, "Failed to update config: "+err.Error(), 500)
@@ -578,10 +1338,12 @@ func (s *Server) Start(port string, libraries []string) error {
 		cfg := s.manager.GetConfig()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"webMode":      true,
-			"libraries":    s.libraries,
-			"version":      s.version,
-			"publicAccess": cfg.PublicAccess,
+			"webMode":           true,
+			"libraries":         s.libraries,
+			"version":           s.version,
+			"publicAccess":      cfg.PublicAccess,
+			"sessionMaxAgeDays": cfg.SessionMaxAgeDays,
+			"sessionIdleDays":   cfg.SessionIdleDays,
 		})
 	})))
 
@@ -630,6 +1392,7 @@ func (s *Server) Start(port string, libraries []string) error {
 		// We track file count progress instead, matching Desktop behavior.
 		totalFiles := len(files)
 		count := 0
+		var uploaded []string
 
 		for i, fileHeader := range files {
 			// Broadcast Progress
@@ -654,9 +1417,13 @@ func (s *Server) Start(port string, libraries []string) error {
 
 			if _, err := io.Copy(dst, file); err == nil {
 				count++
+				uploaded = append(uploaded, dstPath)
 				s.log(fmt.Sprintf("Uploaded: %s (%d bytes)", fileHeader.Filename, fileHeader.Size))
 			}
 		}
+		if count > 0 {
+			s.manager.Events().Publish(events.PackagesInstalled{Folder: targetPath, Files: uploaded})
+		}
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -777,7 +1544,12 @@ func (s *Server) Start(port string, libraries []string) error {
 		}
 
 		// Call Manager
-		res, err := s.manager.ResolveConflicts(req.KeepPath, req.Others, req.LibraryPath)
+		var res *models.ResolveConflictResult
+		_, err := s.manager.Jobs().Run(r.Context(), jobs.Options{Type: jobs.TypeResolve, Target: req.KeepPath}, func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+			var err error
+			res, err = s.manager.ResolveConflicts(req.KeepPath, req.Others, req.LibraryPath)
+			return res, err
+		})
 		if err != nil {
 			s.log(fmt.Sprintf("Error resolving conflicts: %v", err))
 			s.writeError(w, err.Error(), 500)
@@ -799,6 +1571,7 @@ func (s *Server) Start(port string, libraries []string) error {
 			FilePaths []string `json:"filePaths"`
 			DestLib   string   `json:"destLib"`
 			Overwrite bool     `json:"overwrite"`
+			Async     bool     `json:"async"` // Answer 202 with a job ID instead of waiting
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			s.writeError(w, err.Error(), 400)
@@ -821,31 +1594,44 @@ func (s *Server) Start(port string, libraries []string) error {
 			}
 		}
 
-		collisions, err := s.manager.CopyPackagesToLibrary(req.FilePaths, destPath, req.Overwrite, func(current, total int, filename string, status string) {
-			s.Broadcast("install-progress", map[string]interface{}{
-				"current":  current,
-				"total":    total,
-				"filename": filename,
-				"status":   status,
+		install := func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+			collisions, err := s.manager.CopyPackagesToLibrary(ctx, req.FilePaths, destPath, req.Overwrite, func(current, total int, filename string, status string) {
+				report(current, total, filename)
+				s.Broadcast("install-progress", map[string]interface{}{
+					"current":  current,
+					"total":    total,
+					"filename": filename,
+					"status":   status,
+				})
 			})
-		})
+			if err != nil {
+				s.log(fmt.Sprintf("Error installing packages: %v", err))
+				return nil, err
+			}
+			if len(collisions) > 0 {
+				s.log(fmt.Sprintf("Install collisions detected: %d files", len(collisions)))
+			} else {
+				s.log(fmt.Sprintf("Installed %d packages to %s", len(req.FilePaths), filepath.Base(destPath)))
+			}
+			return map[string]interface{}{
+				"success":    true,
+				"collisions": collisions,
+			}, nil
+		}
+
+		opts := jobs.Options{Type: jobs.TypeInstall, Target: destPath}
+		if req.Async {
+			s.startJob(w, opts, install)
+			return
+		}
+		job, err := s.manager.Jobs().Run(r.Context(), opts, install)
 		if err != nil {
-			s.log(fmt.Sprintf("Error installing packages: %v", err))
 			s.writeError(w, err.Error(), 500)
 			return
 		}
 
-		if len(collisions) > 0 {
-			s.log(fmt.Sprintf("Install collisions detected: %d files", len(collisions)))
-		} else {
-			s.log(fmt.Sprintf("Installed %d packages to %s", len(req.FilePaths), filepath.Base(destPath)))
-		}
-
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success":    true,
-			"collisions": collisions,
-		})
+		json.NewEncoder(w).Encode(job.Result)
 	})))
 
 	// Collision Check Endpoint
@@ -942,6 +1728,157 @@ func (s *Server) Start(port string, libraries []string) error {
 		http.ServeFile(w, r, targetFile)
 	})))
 
+	// Bulk Download: streams a zip of several packages (POST JSON body, or GET query for plain links)
+	mux.Handle("/api/download/bundle", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.BundleRequest
+		switch r.Method {
+		case "POST":
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeError(w, "Invalid request body", 400)
+				return
+			}
+		case "GET":
+			req = bundleRequestFromQuery(r.URL.Query())
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Security Check: every path the client named must be inside a library
+		named := append([]string{}, req.Paths...)
+		for _, p := range []string{req.Library, req.ClosureOf} {
+			if p != "" {
+				named = append(named, p)
+			}
+		}
+		for _, p := range named {
+			if err := s.manager.ValidatePath(p); err != nil {
+				s.writeError(w, "Access denied", 403)
+				return
+			}
+		}
+
+		// Resolve and validate everything before the first byte is written
+		paths, err := s.manager.ResolveBundle(r.Context(), req, s.snapshotPackages)
+		if err != nil {
+			s.writeError(w, err.Error(), 400)
+			return
+		}
+
+		s.log(fmt.Sprintf("Streaming bundle of %d packages", len(paths)))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"yavam_bundle_%s.zip\"", time.Now().Format("20060102_150405")))
+		if err := s.manager.WriteBundle(r.Context(), w, paths); err != nil {
+			// Headers are gone already, the client sees a truncated zip
+			s.log(fmt.Sprintf("Bundle download aborted: %v", err))
+		}
+	})))
+
+	// Closure Export: copies (or zips) a package plus everything it depends on into another folder
+	mux.Handle("/api/export/closure", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			FilePath  string `json:"filePath"`
+			Library   string `json:"library"`
+			Dest      string `json:"dest"`
+			Zip       bool   `json:"zip"`
+			Overwrite bool   `json:"overwrite"`
+			Async     bool   `json:"async"` // Answer 202 with a job ID instead of waiting
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, "Invalid request body", 400)
+			return
+		}
+		if req.Dest == "" {
+			s.writeError(w, "Destination path is required", 400)
+			return
+		}
+
+		// Security Check: source, library and destination must all be configured libraries
+		for _, p := range []string{req.FilePath, req.Library, req.Dest} {
+			if err := s.manager.ValidatePath(p); err != nil {
+				s.writeError(w, "Access denied", 403)
+				return
+			}
+		}
+
+		export := func(ctx context.Context, report func(int, int, string)) (interface{}, error) {
+			result, err := s.manager.ExportWithDependencies(ctx, req.Library, req.FilePath, req.Dest, req.Zip, req.Overwrite, func(current, total int, filename string, status string) {
+				report(current, total, filename)
+				s.Broadcast("install-progress", map[string]interface{}{
+					"current":  current,
+					"total":    total,
+					"filename": filename,
+					"status":   status,
+				})
+			})
+			if err != nil {
+				s.log(fmt.Sprintf("Error exporting closure: %v", err))
+				return nil, err
+			}
+			s.log(fmt.Sprintf("Exported %s with %d files (%d missing) to %s", result.Root, len(result.Files), len(result.Missing), filepath.Base(result.Target)))
+			return result, nil
+		}
+
+		opts := jobs.Options{Type: jobs.TypeExport, Target: req.FilePath, Cancellable: true}
+		if req.Async {
+			s.startJob(w, opts, export)
+			return
+		}
+		job, err := s.manager.Jobs().Run(r.Context(), opts, export)
+		if err != nil {
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job.Result)
+	})))
+
+	// Background Jobs (scans, installs, exports, integrity checks):
+	// GET /api/jobs, GET /api/jobs/{id}, DELETE /api/jobs/{id} cancels a running job or removes a finished one
+	mux.Handle("/api/jobs", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.manager.Jobs().List())
+	})))
+	mux.Handle("/api/jobs/", s.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+		var job jobs.Job
+		var err error
+		switch r.Method {
+		case "GET":
+			var ok bool
+			if job, ok = s.manager.Jobs().Get(id); !ok {
+				err = jobs.ErrNotFound
+			}
+		case "DELETE":
+			job, err = s.manager.Jobs().Delete(id)
+		default:
+			s.writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch err {
+		case nil:
+		case jobs.ErrNotFound:
+			s.writeError(w, err.Error(), 404)
+			return
+		case jobs.ErrNotCancellable:
+			s.writeError(w, err.Error(), 409)
+			return
+		default:
+			s.writeError(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	})))
+
 	mux.Handle("/", distFs)
 
 	// Bind to all interfaces to allow remote access
@@ -962,6 +1899,11 @@ func (s *Server) Start(port string, libraries []string) error {
 	s.running = true
 	s.mu.Unlock()
 
+	// Forward every change (made here, on the desktop or on disk) and job update to web clients
+	if s.manager != nil && s.unsubscribeEvents == nil {
+		s.unsubscribeEvents = s.manager.Events().Subscribe(s.onEvent)
+	}
+
 	s.log(fmt.Sprintf("Starting server on port %s...", port))
 	s.log(fmt.Sprintf("Serving %d libraries.", len(libraries)))
 	s.log("Web interface available at root URL.")
@@ -979,6 +1921,67 @@ func (s *Server) Start(port string, libraries []string) error {
 	return nil
 }
 
+// startIntegrityCheck replaces a running integrity check of libraryPath with a new one
+func (s *Server) startIntegrityCheck(libraryPath string) jobs.Job {
+	job := s.manager.StartIntegrityCheck(libraryPath, func(current, total int, p models.VarPackage) {
+		s.Broadcast("integrity:progress", map[string]interface{}{
+			"current":   current,
+			"total":     total,
+			"filePath":  p.FilePath,
+			"integrity": p.Integrity,
+		})
+	})
+	go func() {
+		result, err := s.manager.Jobs().Wait(job.ID)
+		if err != nil {
+			return
+		}
+		switch result.Status {
+		case jobs.StatusCompleted:
+			failed, _ := result.Result.([]models.VarPackage)
+			s.log(fmt.Sprintf("Integrity check finished: %d damaged packages", len(failed)))
+			s.Broadcast("integrity:complete", failed)
+		case jobs.StatusFailed:
+			s.Broadcast("integrity:error", result.Error)
+		}
+	}()
+	return job
+}
+
+// writeUserError maps account management errors to status codes
+func (s *Server) writeUserError(w http.ResponseWriter, err error) {
+	switch err {
+	case auth.ErrUserNotFound:
+		s.writeError(w, err.Error(), 404)
+	case auth.ErrUserExists, auth.ErrLastAdmin:
+		s.writeError(w, err.Error(), 409)
+	default:
+		s.writeError(w, err.Error(), 400)
+	}
+}
+
+// writeTOTPError maps two-factor setup errors to status codes
+func (s *Server) writeTOTPError(w http.ResponseWriter, err error) {
+	switch err {
+	case auth.ErrInvalidCode:
+		s.writeError(w, err.Error(), 401)
+	case auth.ErrTOTPAlreadyEnabled, auth.ErrTOTPNotEnabled, auth.ErrNoPendingEnrollment:
+		s.writeError(w, err.Error(), 409)
+	case auth.ErrUserNotFound:
+		s.writeError(w, err.Error(), 404)
+	default:
+		s.writeError(w, err.Error(), 500)
+	}
+}
+
+// startJob runs fn as a background job and answers 202 with its ID; the result is fetched from /api/jobs/{id}
+func (s *Server) startJob(w http.ResponseWriter, opts jobs.Options, fn jobs.Func) {
+	job := s.manager.Jobs().Start(opts, fn)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"jobId": job.ID})
+}
+
 func (s *Server) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -988,6 +1991,12 @@ func (s *Server) Stop() error {
 	}
 
 	s.log("Stopping server...")
+	if s.unsubscribeEvents != nil {
+		s.unsubscribeEvents()
+		s.unsubscribeEvents = nil
+	}
+	s.packages.cancelAll()
+	s.scanWg.Wait()
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
@@ -1012,3 +2021,27 @@ func (s *Server) GetOutboundIP() string {
 
 	return localAddr.IP.String()
 }
+
+// bundleRequestFromQuery reads a bundle request from query parameters:
+// file (repeatable), library, closure, and the filter fields type, creator, tag, search, enabled
+func bundleRequestFromQuery(q url.Values) models.BundleRequest {
+	req := models.BundleRequest{
+		Paths:     q["file"],
+		Library:   q.Get("library"),
+		ClosureOf: q.Get("closure"),
+	}
+	filter := models.PackageFilter{
+		Type:    q.Get("type"),
+		Creator: q.Get("creator"),
+		Tag:     q.Get("tag"),
+		Search:  q.Get("search"),
+	}
+	if v := q.Get("enabled"); v != "" {
+		enabled := v == "true"
+		filter.Enabled = &enabled
+	}
+	if filter != (models.PackageFilter{}) {
+		req.Filter = &filter
+	}
+	return req
+}
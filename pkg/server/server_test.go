@@ -1,11 +1,17 @@
 package server
 
 import (
+	"archive/zip"
 	"context"
 	"embed"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 	"yavam/pkg/manager"
 	"yavam/pkg/services/config"
 )
@@ -145,3 +151,204 @@ func TestAPI_ExplicitPathSecurity(t *testing.T) {
 		t.Errorf("Expected 403 Forbidden for unsafe path, got %d", w.Code)
 	}
 }
+
+func TestAPI_PackagesServedFromCache(t *testing.T) {
+	// Keep the package index of this test out of the real config directory
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	lib := t.TempDir()
+	writeTestVar(t, filepath.Join(lib, "Creator.First.1.var"))
+
+	mockAuth := &MockAuthService{validToken: "valid"}
+	mgr := manager.NewManager(nil, nil, &TestServerConfigService{libraries: []string{lib}})
+	s := NewServer(context.Background(), mgr, mockAuth, mockAssets, "1.0.0", func() {})
+	s.SkipEvents = true
+	s.Start("0", []string{lib})
+	defer s.Stop()
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/packages?path="+url.QueryEscape(lib), nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		s.httpSrv.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// First request scans
+	w := get("")
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "Creator.First.1.var") {
+		t.Fatalf("Expected package list, got %d: %s", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("X-Library-Version") == "" {
+		t.Fatal("Expected ETag and version headers")
+	}
+
+	// Unchanged: 304 without scanning
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304, got %d", w.Code)
+	}
+
+	// New files are only seen after a rescan
+	writeTestVar(t, filepath.Join(lib, "Creator.Second.1.var"))
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("Expected cached list before rescan, got %d", w.Code)
+	}
+	<-s.refreshPackages(lib, false).done
+
+	w = get(etag)
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "Creator.Second.1.var") {
+		t.Fatalf("Expected updated list after rescan, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("Expected a new ETag after the library changed")
+	}
+
+	// A rescan that finds nothing new keeps the version
+	etag = w.Header().Get("ETag")
+	<-s.refreshPackages(lib, false).done
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 after a no-op rescan, got %d", w.Code)
+	}
+}
+
+func TestAPI_SearchUsesCachedSnapshot(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	lib := t.TempDir()
+	writeTestVar(t, filepath.Join(lib, "Creator.First.1.var"))
+
+	mgr := manager.NewManager(nil, nil, &TestServerConfigService{libraries: []string{lib}})
+	s := NewServer(context.Background(), mgr, &MockAuthService{validToken: "valid"}, mockAssets, "1.0.0", func() {})
+	s.SkipEvents = true
+	s.Start("0", []string{lib})
+	defer s.Stop()
+
+	search := func() string {
+		req := httptest.NewRequest("GET", "/api/search?q=&path="+url.QueryEscape(lib), nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		w := httptest.NewRecorder()
+		s.httpSrv.Handler.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("Expected search results, got %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	// The first search of a library waits for its first scan
+	if body := search(); !strings.Contains(body, `"total":1`) {
+		t.Fatalf("Expected one package, got %s", body)
+	}
+
+	// Later searches do not scan, they see new files once the snapshot is refreshed
+	writeTestVar(t, filepath.Join(lib, "Creator.Second.1.var"))
+	if body := search(); !strings.Contains(body, `"total":1`) {
+		t.Errorf("Expected the cached snapshot, got %s", body)
+	}
+	<-s.refreshPackages(lib, false).done
+	if body := search(); !strings.Contains(body, `"total":2`) {
+		t.Errorf("Expected both packages after a rescan, got %s", body)
+	}
+}
+
+func TestAPI_GraphUsesCachedSnapshot(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	lib := t.TempDir()
+	writeTestVar(t, filepath.Join(lib, "Creator.First.1.var"))
+
+	mgr := manager.NewManager(nil, nil, &TestServerConfigService{libraries: []string{lib}})
+	s := NewServer(context.Background(), mgr, &MockAuthService{validToken: "valid"}, mockAssets, "1.0.0", func() {})
+	s.SkipEvents = true
+	s.Start("0", []string{lib})
+	defer s.Stop()
+
+	roots := func() string {
+		req := httptest.NewRequest("GET", "/api/graph/roots?path="+url.QueryEscape(lib), nil)
+		req.Header.Set("Authorization", "Bearer valid")
+		w := httptest.NewRecorder()
+		s.httpSrv.Handler.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("Expected graph roots, got %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	// The first request waits for the first scan, the graph is then kept with the snapshot
+	if body := roots(); !strings.Contains(body, "Creator.First.1.var") {
+		t.Fatalf("Expected the package as root, got %s", body)
+	}
+	snap := s.packages.get(lib)
+	if s.packages.dependencyGraph(snap) != s.packages.dependencyGraph(snap) {
+		t.Error("Expected the graph to be built once per snapshot version")
+	}
+
+	// New files are only seen once the snapshot is refreshed
+	writeTestVar(t, filepath.Join(lib, "Creator.Second.1.var"))
+	if body := roots(); strings.Contains(body, "Creator.Second.1.var") {
+		t.Errorf("Expected the cached graph, got %s", body)
+	}
+	<-s.refreshPackages(lib, false).done
+	if body := roots(); !strings.Contains(body, "Creator.Second.1.var") {
+		t.Errorf("Expected the new file after a rescan, got %s", body)
+	}
+}
+
+func TestAPI_DesktopChangesReachWebClients(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+
+	lib := t.TempDir()
+	pkgPath := filepath.Join(lib, "Creator.First.1.var")
+	writeTestVar(t, pkgPath)
+
+	mgr := manager.NewManager(nil, nil, &TestServerConfigService{libraries: []string{lib}})
+	s := NewServer(context.Background(), mgr, &MockAuthService{validToken: "valid"}, mockAssets, "1.0.0", func() {})
+	s.SkipEvents = true
+	s.Start("0", []string{lib})
+	defer s.Stop()
+	<-s.refreshPackages(lib, false).done
+	before := s.packages.get(lib).Version
+
+	// A toggle made through the manager (as the desktop UI does) refreshes the web list
+	if _, err := mgr.TogglePackage(nil, pkgPath, false, lib, false); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snap := s.packages.get(lib)
+		if !snap.stale && snap.Version != before {
+			if !strings.Contains(string(snap.Body), ".var.disabled") {
+				t.Errorf("Expected the disabled package in the refreshed list: %s", snap.Body)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Cached list was not refreshed after a desktop toggle")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// writeTestVar creates a minimal package archive
+func writeTestVar(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	meta, _ := zw.Create("meta.json")
+	meta.Write([]byte(`{"creatorName":"Creator","packageName":"Test","version":"1"}`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
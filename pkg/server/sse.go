@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// eventLogSize is how many recent SSE messages are kept for clients that reconnect or fall behind
+const eventLogSize = 4096
+
+// EventResync tells a client that events were lost (it was away too long or the server restarted).
+// Its ID is the latest one, so the client can refetch its state and continue from there.
+const EventResync = "resync:required"
+
+// coalescedEvents only report a current state (e.g. progress of a long scan). A new one replaces
+// the previous one if nothing else was logged in between, so they do not push everything else out
+// of the log. Clients that keep up still receive each of them.
+var coalescedEvents = map[string]bool{
+	"scan:progress":      true,
+	"integrity:progress": true,
+}
+
+type loggedEvent struct {
+	id        uint64
+	eventType string
+	msg       string // Complete SSE frame including the id line
+}
+
+// eventLog assigns increasing IDs to broadcast events and keeps the latest ones in a ring buffer
+type eventLog struct {
+	mu      sync.Mutex
+	ring    []loggedEvent
+	start   int // Index of the oldest entry in ring
+	lastID  uint64
+	dropped uint64 // ID of the newest event evicted from ring; clients behind it missed events
+}
+
+func newEventLog() *eventLog {
+	// IDs start at the current time so they keep increasing across restarts and a stale
+	// Last-Event-ID from a previous run is detected as a gap instead of matching new events
+	start := uint64(time.Now().UnixMilli())
+	return &eventLog{
+		ring:    make([]loggedEvent, 0, eventLogSize),
+		lastID:  start,
+		dropped: start,
+	}
+}
+
+func formatEvent(id uint64, eventType string, data interface{}) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"event": eventType,
+		"data":  data,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("id: %d\ndata: %s\n\n", id, payload), nil
+}
+
+// append records an event and returns its ID
+func (l *eventLog) append(eventType string, data interface{}) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	msg, err := formatEvent(l.lastID+1, eventType, data)
+	if err != nil {
+		return 0, err
+	}
+	l.lastID++
+	e := loggedEvent{id: l.lastID, eventType: eventType, msg: msg}
+	if n := len(l.ring); n > 0 && coalescedEvents[eventType] {
+		newest := (l.start + n - 1) % n
+		if l.ring[newest].eventType == eventType {
+			l.ring[newest] = e
+			return l.lastID, nil
+		}
+	}
+	if len(l.ring) < eventLogSize {
+		l.ring = append(l.ring, e)
+	} else {
+		l.dropped = l.ring[l.start].id
+		l.ring[l.start] = e
+		l.start = (l.start + 1) % eventLogSize
+	}
+	return l.lastID, nil
+}
+
+// latest returns the ID of the newest event
+func (l *eventLog) latest() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+// since returns the messages after id and the ID of the newest one. ok is false if some of them
+// are no longer buffered (or id is unknown), in which case the client has to resync.
+func (l *eventLog) since(id uint64) (msgs []string, newest uint64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id == l.lastID {
+		return nil, id, true
+	}
+	if id > l.lastID || id < l.dropped {
+		return nil, 0, false
+	}
+	for i := 0; i < len(l.ring); i++ {
+		e := l.ring[(l.start+i)%len(l.ring)]
+		if e.id > id {
+			msgs = append(msgs, e.msg)
+		}
+	}
+	return msgs, l.lastID, true
+}
+
+// resyncMessage builds the frame sent instead of the events a client missed
+func (l *eventLog) resyncMessage(lastSeen uint64) (string, uint64) {
+	latest := l.latest()
+	msg, _ := formatEvent(latest, EventResync, map[string]interface{}{
+		"lastEventId":   lastSeen,
+		"latestEventId": latest,
+	})
+	return msg, latest
+}
+
+// sseClient is one connected /api/events stream. Broadcast only wakes it up; the stream itself
+// copies everything after the last event it sent from the log, so a slow client never silently
+// loses events.
+type sseClient struct {
+	wake chan struct{}
+}
+
+func newSSEClient() *sseClient {
+	return &sseClient{wake: make(chan struct{}, 1)}
+}
+
+func (c *sseClient) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+		// Already pending, the stream will pick up this event as well
+	}
+}
+
+// parseLastEventID reads the reconnect position from the Last-Event-ID header (sent by EventSource
+// on automatic reconnects) or the lastEventId query parameter (for clients creating a new stream).
+// An unparsable ID is reported as 0, which is never buffered and leads to a resync.
+func parseLastEventID(header, query string) (uint64, bool) {
+	v := header
+	if v == "" {
+		v = query
+	}
+	if v == "" {
+		return 0, false
+	}
+	id, _ := strconv.ParseUint(v, 10, 64)
+	return id, true
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEventLog_ReplaysAfterLastEventID(t *testing.T) {
+	l := newEventLog()
+	first, _ := l.append("scan:progress", map[string]int{"current": 1})
+	l.append("package:scanned", "A.var")
+	last, _ := l.append("package:scanned", "B.var")
+
+	msgs, newest, ok := l.since(first)
+	if !ok || len(msgs) != 2 || newest != last {
+		t.Fatalf("since(first) = %d messages, newest %d, ok %v", len(msgs), newest, ok)
+	}
+	if !strings.HasPrefix(msgs[1], "id: ") || !strings.Contains(msgs[1], `"B.var"`) {
+		t.Errorf("Unexpected frame %q", msgs[1])
+	}
+
+	if msgs, _, ok := l.since(last); !ok || len(msgs) != 0 {
+		t.Errorf("Expected nothing after the newest event, got %v", msgs)
+	}
+	// IDs from the future (e.g. a previous run with a faster clock) cannot be trusted
+	if _, _, ok := l.since(last + 10); ok {
+		t.Error("Expected a resync for an unknown ID")
+	}
+}
+
+func TestEventLog_GapRequiresResync(t *testing.T) {
+	l := newEventLog()
+	first, _ := l.append("package:scanned", 0)
+	for i := 1; i <= eventLogSize+1; i++ {
+		l.append("package:scanned", i)
+	}
+
+	// The event right after first was evicted
+	if _, _, ok := l.since(first); ok {
+		t.Fatal("Expected a resync after the buffer wrapped")
+	}
+	msgs, _, ok := l.since(first + 1)
+	if !ok || len(msgs) != eventLogSize {
+		t.Errorf("Expected %d buffered events, got %d (ok %v)", eventLogSize, len(msgs), ok)
+	}
+
+	msg, latest := l.resyncMessage(first)
+	if latest != l.latest() || !strings.Contains(msg, EventResync) {
+		t.Errorf("Unexpected resync frame %q", msg)
+	}
+}
+
+func TestParseLastEventID(t *testing.T) {
+	if _, ok := parseLastEventID("", ""); ok {
+		t.Error("Expected no resume position")
+	}
+	if id, ok := parseLastEventID("", "42"); !ok || id != 42 {
+		t.Errorf("Expected 42 from the query, got %d", id)
+	}
+	if id, ok := parseLastEventID("7", "42"); !ok || id != 7 {
+		t.Errorf("Expected the header to win, got %d", id)
+	}
+	if id, ok := parseLastEventID("garbage", ""); !ok || id != 0 {
+		t.Errorf("Expected 0 for an invalid ID, got %d", id)
+	}
+}
+
+func TestEventLog_CoalescesProgress(t *testing.T) {
+	l := newEventLog()
+	start, _ := l.append("packages:updated", "lib")
+	for i := 1; i <= eventLogSize*2; i++ {
+		l.append("scan:progress", map[string]int{"current": i})
+	}
+
+	// A client from before the scan only gets the latest progress, without a resync
+	msgs, _, ok := l.since(start)
+	if !ok || len(msgs) != 1 || !strings.Contains(msgs[0], fmt.Sprintf(`"current":%d`, eventLogSize*2)) {
+		t.Fatalf("Expected only the latest progress, got %d messages (ok %v)", len(msgs), ok)
+	}
+
+	// Progress between other events is kept in order
+	l.append("package:toggled", "A.var")
+	l.append("scan:progress", map[string]int{"current": 1})
+	if msgs, _, _ := l.since(start); len(msgs) != 3 {
+		t.Errorf("Expected 3 messages, got %d", len(msgs))
+	}
+}
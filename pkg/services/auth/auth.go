@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"sync"
@@ -13,11 +14,21 @@ import (
 // SimpleTokenAuthService implements AuthService using Bcrypt
 type SimpleTokenAuthService struct {
 	mu          sync.RWMutex
-	validTokens map[string]*User
-	adminHash   string // Bcrypt hash of password
+	validTokens map[string]*User // By hashToken, the tokens themselves are never stored
+	tokenSalt   []byte
+	users       map[string]*UserRecord     // Accounts by lower-case username
+	challenges  map[string]*loginChallenge // Logins waiting for their second factor
 	store       *FileAuthStore
+	policy      SessionPolicy
+	now         func() time.Time // Replaced in tests
 }
 
+// dummyHash is compared against for unknown users, so a failed login takes as long either way
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
 func NewSimpleAuthService(configPath string) (*SimpleTokenAuthService, error) {
 	store := NewFileAuthStore(configPath)
 	config, err := store.Load()
@@ -25,47 +36,89 @@ func NewSimpleAuthService(configPath string) (*SimpleTokenAuthService, error) {
 		return nil, err
 	}
 
-	var hashStr string
-	if config != nil && config.AdminHash != "" {
-		hashStr = config.AdminHash
-	} else {
-		// Default to "admin"
-		// Generate bcrypt hash for "admin"
-		hash, _ := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
-		hashStr = string(hash)
-		// Save default
-		store.Save(&AuthConfig{AdminHash: hashStr})
-	}
-
-	// Load sessions if present
-	validTokens := make(map[string]*User)
-	if config != nil && config.Sessions != nil {
-		validTokens = config.Sessions
-		// Restore Token field since it's not persisted
-		for token, user := range validTokens {
-			if user.Token == "" {
-				user.Token = token
-			}
+	users := make(map[string]*UserRecord)
+	if config != nil && config.Users != nil {
+		users = config.Users
+	}
+	if len(users) == 0 {
+		// Single password from before accounts existed, or default to "admin"
+		hashStr := ""
+		if config != nil {
+			hashStr = config.AdminHash
+		}
+		if hashStr == "" {
+			hash, _ := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
+			hashStr = string(hash)
+		}
+		users[DefaultAdmin] = &UserRecord{
+			Role:      RoleAdmin,
+			Hash:      hashStr,
+			CreatedAt: time.Now().Format(time.RFC3339),
 		}
 	}
 
 	s := &SimpleTokenAuthService{
-		validTokens: validTokens,
-		adminHash:   hashStr,
+		validTokens: make(map[string]*User),
+		users:       users,
+		challenges:  make(map[string]*loginChallenge),
 		store:       store,
+		policy:      DefaultSessionPolicy,
+		now:         time.Now,
 	}
 
+	// Load sessions if present. Stores without a salt still have the plain tokens as keys.
+	migrateTokens := false
+	if config != nil && config.TokenSalt != "" {
+		if s.tokenSalt, err = hex.DecodeString(config.TokenSalt); err != nil {
+			return nil, fmt.Errorf("invalid token salt: %w", err)
+		}
+	} else {
+		s.tokenSalt = make([]byte, 16)
+		if _, err := rand.Read(s.tokenSalt); err != nil {
+			return nil, err
+		}
+		migrateTokens = true
+	}
+	if config != nil {
+		for key, user := range config.Sessions {
+			if migrateTokens {
+				key = s.hashToken(key)
+			}
+			s.validTokens[key] = user
+		}
+	}
+
+	if config == nil || config.AdminHash != "" || config.Users == nil || migrateTokens {
+		// Save the default or migrated account
+		if err := s.persistState(); err != nil {
+			fmt.Printf("Failed to persist auth config: %v\n", err)
+		}
+	}
+	s.PurgeExpired()
+	go s.purgeLoop()
+
 	return s, nil
 }
 
 // Helper to save current state
 func (s *SimpleTokenAuthService) persistState() error {
 	return s.store.Save(&AuthConfig{
-		AdminHash: s.adminHash,
+		Users:     s.users,
+		TokenSalt: hex.EncodeToString(s.tokenSalt),
 		Sessions:  s.validTokens,
 	})
 }
 
+// hashToken is how tokens are stored and looked up. Tokens are 256 random bits, so a fast
+// hash is enough; the salt keeps hashes from one install useless for any other.
+func (s *SimpleTokenAuthService) hashToken(token string) string {
+	h := sha256.New()
+	h.Write(s.tokenSalt)
+	h.Write([]byte(token))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetPassword updates the password of the default admin account, recreating it if it was deleted
 func (s *SimpleTokenAuthService) SetPassword(newPassword string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -74,7 +127,12 @@ func (s *SimpleTokenAuthService) SetPassword(newPassword string) error {
 	if err != nil {
 		return err
 	}
-	s.adminHash = string(hash)
+	u, ok := s.users[DefaultAdmin]
+	if !ok {
+		u = &UserRecord{Role: RoleAdmin, CreatedAt: time.Now().Format(time.RFC3339)}
+		s.users[DefaultAdmin] = u
+	}
+	u.Hash = string(hash)
 	return s.persistState()
 }
 
@@ -84,21 +142,43 @@ func (s *SimpleTokenAuthService) InitiateLogin(username string) (string, error)
 	return "bcrypt-mode", nil
 }
 
-// Login verifies credentials directly using Bcrypt
+// Login verifies credentials directly using Bcrypt. The session gets the role of the account.
 func (s *SimpleTokenAuthService) Login(username, password, deviceName string) (string, error) {
-	if username != "admin" {
-		return "", ErrInvalidToken
-	}
+	username = normalizeUsername(username)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	account, ok := s.users[username]
+	if !ok {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("unknown-user"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", fmt.Errorf("invalid credentials")
+	}
+
 	// Verify Password
-	err := bcrypt.CompareHashAndPassword([]byte(s.adminHash), []byte(password))
+	err := bcrypt.CompareHashAndPassword([]byte(account.Hash), []byte(password))
 	if err != nil {
 		return "", fmt.Errorf("invalid credentials")
 	}
 
+	if deviceName == "" {
+		deviceName = "Unknown Device"
+	}
+	if account.TOTPEnabled {
+		challenge, err := s.newLoginChallenge(username, deviceName)
+		if err != nil {
+			return "", err
+		}
+		return "", &TwoFactorRequiredError{Challenge: challenge}
+	}
+	return s.createSession(username, deviceName)
+}
+
+// createSession signs username in on deviceName and returns the token (s.mu held)
+func (s *SimpleTokenAuthService) createSession(username, deviceName string) (string, error) {
 	// Generate Token
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
@@ -111,23 +191,22 @@ func (s *SimpleTokenAuthService) Login(username, password, deviceName string) (s
 	rand.Read(sid)
 	sessionID := hex.EncodeToString(sid)
 
-	if deviceName == "" {
-		deviceName = "Unknown Device"
-	}
-
 	// Deduplicate: Remove any existing session for this device
 	for oldToken, user := range s.validTokens {
-		if user.DeviceName == deviceName && user.Username == username {
+		if !user.IsAPIToken() && user.DeviceName == deviceName && user.Username == username {
 			delete(s.validTokens, oldToken)
 		}
 	}
 
-	s.validTokens[token] = &User{
+	now := s.now().Format(time.RFC3339)
+	s.validTokens[s.hashToken(token)] = &User{
 		ID:         sessionID,
+		Kind:       KindSession,
 		Username:   username,
-		Role:       "admin",
+		Role:       s.users[username].Role,
 		DeviceName: deviceName,
-		CreatedAt:  time.Now().Format(time.RFC3339),
+		CreatedAt:  now,
+		LastSeen:   now,
 		Token:      token,
 	}
 
@@ -147,11 +226,15 @@ func (s *SimpleTokenAuthService) ValidateToken(token string) (*User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	user, exists := s.validTokens[token]
-	if !exists {
+	user, exists := s.validTokens[s.hashToken(token)]
+	if !exists || s.isExpired(user, s.now()) {
 		return nil, ErrInvalidToken
 	}
-	return user, nil
+	// A copy: the stored record changes under the write lock (TouchSession, SetUserRole) while
+	// handlers read theirs. The token itself is only known here, the store has the hash.
+	u := *user
+	u.Token = token
+	return &u, nil
 }
 
 func (s *SimpleTokenAuthService) GenerateToken() (string, error) {
@@ -170,12 +253,12 @@ func (s *SimpleTokenAuthService) GenerateToken() (string, error) {
 	rand.Read(sid)
 	sessionID := hex.EncodeToString(sid)
 
-	s.validTokens[token] = &User{
+	s.validTokens[s.hashToken(token)] = &User{
 		ID:         sessionID,
 		Username:   "system",
-		Role:       "admin",
+		Role:       RoleAdmin,
 		DeviceName: "Local System",
-		CreatedAt:  time.Now().Format(time.RFC3339),
+		CreatedAt:  s.now().Format(time.RFC3339),
 		Token:      token,
 	}
 	s.persistState()
@@ -186,9 +269,18 @@ func (s *SimpleTokenAuthService) ListSessions() ([]User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	// Expired sessions are left to the next purge but not listed; the others show when they expire
+	now := s.now()
 	var sessions []User
 	for _, user := range s.validTokens {
-		sessions = append(sessions, *user)
+		if s.isExpired(user, now) {
+			continue
+		}
+		session := *user
+		if expiry := s.policy.sessionExpiry(user); !expiry.IsZero() {
+			session.ExpiresAt = expiry.Format(time.RFC3339)
+		}
+		sessions = append(sessions, session)
 	}
 	return sessions, nil
 }
@@ -215,7 +307,7 @@ func (s *SimpleTokenAuthService) RevokeSession(id string) error {
 func (s *SimpleTokenAuthService) RevokeToken(token string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.validTokens, token)
+	delete(s.validTokens, s.hashToken(token))
 	s.persistState()
 }
 
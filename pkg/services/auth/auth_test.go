@@ -1,8 +1,16 @@
 package auth
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestLoginFlow(t *testing.T) {
@@ -122,3 +130,373 @@ func TestRevocationPersistence(t *testing.T) {
 		t.Fatal("Session should remain revoked after restart")
 	}
 }
+
+func TestUserAccounts(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+
+	if err := svc.CreateUser("Alice", "pw", RoleViewer); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := svc.CreateUser("alice", "pw", RoleAdmin); err != ErrUserExists {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+	if err := svc.CreateUser("bob", "pw", "superuser"); err != ErrInvalidRole {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+
+	// Sessions carry the role of the account
+	token, err := svc.Login("alice", "pw", "phone")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	user, _ := svc.ValidateToken(token)
+	if user.Role != RoleViewer {
+		t.Errorf("Expected viewer session, got %s", user.Role)
+	}
+
+	// Role changes apply to open sessions
+	svc.SetUserRole("alice", RoleUploader)
+	if user, _ := svc.ValidateToken(token); user.Role != RoleUploader {
+		t.Errorf("Expected uploader after role change, got %s", user.Role)
+	}
+
+	// Deleting an account signs it out
+	if err := svc.DeleteUser("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ValidateToken(token); err != ErrInvalidToken {
+		t.Error("Session of a deleted user is still valid")
+	}
+	if _, err := svc.Login("alice", "pw", "phone"); err == nil {
+		t.Error("Deleted user can still log in")
+	}
+}
+
+func TestLastAdminIsProtected(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+
+	if err := svc.DeleteUser(DefaultAdmin); err != ErrLastAdmin {
+		t.Errorf("Expected ErrLastAdmin on delete, got %v", err)
+	}
+	if err := svc.SetUserRole(DefaultAdmin, RoleViewer); err != ErrLastAdmin {
+		t.Errorf("Expected ErrLastAdmin on demotion, got %v", err)
+	}
+
+	svc.CreateUser("second", "pw", RoleAdmin)
+	if err := svc.SetUserRole(DefaultAdmin, RoleViewer); err != nil {
+		t.Errorf("Demotion with another admin failed: %v", err)
+	}
+}
+
+func TestMigratesSingleAdminPassword(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "auth.json")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("old-secret"), bcrypt.MinCost)
+	NewFileAuthStore(configPath).Save(&AuthConfig{AdminHash: string(hash)})
+
+	svc, err := NewSimpleAuthService(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login("admin", "old-secret", "dev"); err != nil {
+		t.Fatalf("Existing password no longer works: %v", err)
+	}
+
+	cfg, _ := NewFileAuthStore(configPath).Load()
+	if cfg.AdminHash != "" || cfg.Users[DefaultAdmin] == nil || cfg.Users[DefaultAdmin].Role != RoleAdmin {
+		t.Errorf("Expected the password to be migrated to an admin account, got %+v", cfg)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "auth.json")
+	svc, _ := NewSimpleAuthService(configPath)
+
+	if _, _, err := svc.CreateAPIToken(DefaultAdmin, "ci", []string{"everything"}, time.Time{}); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+	token, info, err := svc.CreateAPIToken(DefaultAdmin, "ci", []string{ScopePackagesRead}, time.Time{})
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	if !info.IsAPIToken() || info.Role != RoleAdmin {
+		t.Errorf("Unexpected token info %+v", info)
+	}
+	if _, _, err := svc.CreateAPIToken(DefaultAdmin, "ci", []string{ScopeUpload}, time.Time{}); err != ErrTokenExists {
+		t.Errorf("Expected ErrTokenExists, got %v", err)
+	}
+
+	user, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken failed: %v", err)
+	}
+	if !user.HasScope(ScopePackagesRead) || user.HasScope(ScopePackagesToggle) {
+		t.Errorf("Unexpected scopes %v", user.Scopes)
+	}
+
+	// Tokens are listed with the sessions and survive restarts
+	sessions, _ := svc.ListSessions()
+	if len(sessions) != 1 || sessions[0].Name != "ci" {
+		t.Errorf("Expected the token in the sessions, got %+v", sessions)
+	}
+	svc2, _ := NewSimpleAuthService(configPath)
+	if _, err := svc2.ValidateToken(token); err != nil {
+		t.Errorf("Token lost after reload: %v", err)
+	}
+
+	if err := svc2.RevokeAPIToken(DefaultAdmin, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc2.ValidateToken(token); err != ErrInvalidToken {
+		t.Error("Revoked token is still valid")
+	}
+	if err := svc2.RevokeAPIToken(DefaultAdmin, "ci"); err != ErrTokenNotFound {
+		t.Errorf("Expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestAPITokenExpiry(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+
+	if _, _, err := svc.CreateAPIToken(DefaultAdmin, "old", []string{ScopePackagesRead}, time.Now().Add(-time.Hour)); err == nil {
+		t.Error("Expected an error for an expiry in the past")
+	}
+	token, _, err := svc.CreateAPIToken(DefaultAdmin, "soon", []string{ScopePackagesRead}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := svc.ValidateToken(token); err != ErrInvalidToken {
+		t.Error("Expired token is still valid")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	svc.SetSessionPolicy(SessionPolicy{MaxAge: 30 * 24 * time.Hour, IdleTimeout: 7 * 24 * time.Hour})
+
+	token, err := svc.Login(DefaultAdmin, "admin", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.TouchSession(token, "192.168.1.20")
+	sessions, _ := svc.ListSessions()
+	if len(sessions) != 1 || sessions[0].IPAddress != "192.168.1.20" || sessions[0].LastSeen == "" {
+		t.Fatalf("Expected last-seen data, got %+v", sessions)
+	}
+	if want := now.Add(7 * 24 * time.Hour).Format(time.RFC3339); sessions[0].ExpiresAt != want {
+		t.Errorf("Expected expiry %s, got %s", want, sessions[0].ExpiresAt)
+	}
+
+	// Use keeps the session alive (sliding idle timeout) ...
+	for i := 0; i < 4; i++ {
+		now = now.Add(6 * 24 * time.Hour)
+		if _, err := svc.ValidateToken(token); err != nil {
+			t.Fatalf("Session expired while in use after %d days", (i+1)*6)
+		}
+		svc.TouchSession(token, "192.168.1.20")
+	}
+	// ... but not beyond the absolute lifetime
+	now = now.Add(7 * 24 * time.Hour)
+	if _, err := svc.ValidateToken(token); err != ErrInvalidToken {
+		t.Error("Session outlived its maximum age")
+	}
+
+	// An idle session expires and is purged
+	idle, _ := svc.Login(DefaultAdmin, "admin", "laptop")
+	now = now.Add(8 * 24 * time.Hour)
+	if _, err := svc.ValidateToken(idle); err != ErrInvalidToken {
+		t.Error("Idle session is still valid")
+	}
+	if n := svc.PurgeExpired(); n != 2 {
+		t.Errorf("Expected 2 purged sessions, got %d", n)
+	}
+	if sessions, _ := svc.ListSessions(); len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got %+v", sessions)
+	}
+}
+
+func TestTokensAreHashedAtRest(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "auth.json")
+	svc, _ := NewSimpleAuthService(configPath)
+	token, err := svc.Login(DefaultAdmin, "admin", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), token) {
+		t.Error("Token stored in plain text")
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(configPath); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+		}
+	}
+}
+
+func TestMigratesPlaintextSessions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "auth.json")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	old := &AuthConfig{
+		Users: map[string]*UserRecord{DefaultAdmin: {Role: RoleAdmin, Hash: string(hash)}},
+		Sessions: map[string]*User{"plain-token": {
+			ID: "abc", Kind: KindSession, Username: DefaultAdmin, Role: RoleAdmin,
+			DeviceName: "phone", CreatedAt: time.Now().Format(time.RFC3339),
+		}},
+	}
+	if err := NewFileAuthStore(configPath).Save(old); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewSimpleAuthService(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := svc.ValidateToken("plain-token"); err != nil || user.ID != "abc" {
+		t.Fatalf("Migrated session not valid: %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if strings.Contains(string(data), "plain-token") {
+		t.Error("Plain token still in the store after migration")
+	}
+	svc2, _ := NewSimpleAuthService(configPath)
+	if _, err := svc2.ValidateToken("plain-token"); err != nil {
+		t.Errorf("Session lost after reload: %v", err)
+	}
+}
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		if got := totpCode(secret, unix/totpPeriod); got != want {
+			t.Errorf("T=%d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	enrollment, err := svc.BeginTOTPEnrollment(DefaultAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/YAVAM:admin?") || !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("Unexpected provisioning URI %s", enrollment.URI)
+	}
+	secret, _ := totpEncoding.DecodeString(enrollment.Secret)
+	code := func() string { return totpCode(secret, now.Unix()/totpPeriod) }
+
+	// Not enforced until confirmed
+	if _, err := svc.Login(DefaultAdmin, "admin", "phone"); err != nil {
+		t.Fatalf("Login before confirmation failed: %v", err)
+	}
+	if _, err := svc.ConfirmTOTPEnrollment(DefaultAdmin, "000000"); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+	recovery, err := svc.ConfirmTOTPEnrollment(DefaultAdmin, code())
+	if err != nil || len(recovery) != recoveryCodeCount {
+		t.Fatalf("Confirm failed: %v (%d codes)", err, len(recovery))
+	}
+
+	// Password alone is no longer enough
+	now = now.Add(totpPeriod * time.Second)
+	_, err = svc.Login(DefaultAdmin, "admin", "laptop")
+	var required *TwoFactorRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("Expected TwoFactorRequiredError, got %v", err)
+	}
+	if _, err := svc.CompleteTwoFactorLogin(required.Challenge, "123456"); err != ErrInvalidCode {
+		t.Errorf("Expected ErrInvalidCode, got %v", err)
+	}
+	token, err := svc.CompleteTwoFactorLogin(required.Challenge, code())
+	if err != nil {
+		t.Fatalf("Second step failed: %v", err)
+	}
+	if _, err := svc.ValidateToken(token); err != nil {
+		t.Errorf("Session not valid: %v", err)
+	}
+	if _, err := svc.CompleteTwoFactorLogin(required.Challenge, code()); err != ErrInvalidLoginAttempt {
+		t.Errorf("Challenge reused: %v", err)
+	}
+
+	// A code is only accepted once, clock drift of one period is tolerated
+	_, err = svc.Login(DefaultAdmin, "admin", "laptop")
+	errors.As(err, &required)
+	if _, err := svc.CompleteTwoFactorLogin(required.Challenge, code()); err != ErrInvalidCode {
+		t.Errorf("Replayed code accepted: %v", err)
+	}
+	late := code()
+	now = now.Add(totpPeriod * time.Second)
+	if err := svc.VerifySecondFactor(DefaultAdmin, late); err == nil {
+		t.Error("Code accepted after a newer one was used")
+	}
+	previous := code()
+	now = now.Add(totpPeriod * time.Second)
+	if err := svc.VerifySecondFactor(DefaultAdmin, previous); err != nil {
+		t.Errorf("Code of the previous period rejected: %v", err)
+	}
+
+	// Challenges expire
+	_, err = svc.Login(DefaultAdmin, "admin", "laptop")
+	errors.As(err, &required)
+	now = now.Add(loginChallengeTTL + time.Second)
+	if _, err := svc.CompleteTwoFactorLogin(required.Challenge, code()); err != ErrInvalidLoginAttempt {
+		t.Errorf("Expected an expired challenge, got %v", err)
+	}
+
+	// Recovery codes work once each
+	_, err = svc.Login(DefaultAdmin, "admin", "tablet")
+	errors.As(err, &required)
+	if _, err := svc.CompleteTwoFactorLogin(required.Challenge, recovery[0]); err != nil {
+		t.Errorf("Recovery code rejected: %v", err)
+	}
+	if err := svc.VerifySecondFactor(DefaultAdmin, recovery[0]); err != ErrInvalidCode {
+		t.Errorf("Recovery code reused: %v", err)
+	}
+
+	if err := svc.DisableTOTP(DefaultAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Login(DefaultAdmin, "admin", "tablet"); err != nil {
+		t.Errorf("Login after disabling failed: %v", err)
+	}
+}
+
+func TestValidateTokenConcurrently(t *testing.T) {
+	svc, _ := NewSimpleAuthService(filepath.Join(t.TempDir(), "auth.json"))
+	token, err := svc.Login(DefaultAdmin, "admin", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				user, err := svc.ValidateToken(token)
+				if err != nil || user.Token != token {
+					t.Errorf("ValidateToken = %v, %v", user, err)
+					return
+				}
+				svc.TouchSession(token, "10.0.0.1")
+			}
+		}()
+	}
+	wg.Wait()
+}
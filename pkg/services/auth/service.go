@@ -2,21 +2,38 @@ package auth
 
 import (
 	"errors"
+	"time"
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid or expired token")
 )
 
-// User represents an authenticated session
+// Kinds of credentials
+const (
+	KindSession  = "session" // Created by logging in on a device
+	KindAPIToken = "token"   // Personal access token for scripts, limited to its scopes
+)
+
+// User represents an authenticated session or API token
 type User struct {
-	ID         string `json:"id"`
-	Username   string `json:"username"`
-	Role       string `json:"role"`
-	DeviceName string `json:"deviceName"`
-	IPAddress  string `json:"ipAddress"`
-	CreatedAt  string `json:"createdAt"` // ISO or timestamp
-	Token      string `json:"-"`         // Internal use
+	ID         string   `json:"id"`
+	Kind       string   `json:"kind,omitempty"` // KindSession when empty (sessions from older versions)
+	Username   string   `json:"username"`
+	Role       string   `json:"role"`
+	DeviceName string   `json:"deviceName"`
+	Name       string   `json:"name,omitempty"`      // API tokens: unique per user
+	Scopes     []string `json:"scopes,omitempty"`    // API tokens: what the token may do
+	ExpiresAt  string   `json:"expiresAt,omitempty"` // API tokens: RFC3339, empty for no expiry. Listed sessions: when the policy ends them
+	IPAddress  string   `json:"ipAddress"`           // Last address the credential was used from
+	LastSeen   string   `json:"lastSeen,omitempty"`  // RFC3339, updated on use
+	CreatedAt  string   `json:"createdAt"`           // ISO or timestamp
+	Token      string   `json:"-"`                   // Internal use
+}
+
+// IsAPIToken reports whether the credential is a scoped API token
+func (u *User) IsAPIToken() bool {
+	return u.Kind == KindAPIToken
 }
 
 // AuthService handles authentication and token management
@@ -47,4 +64,28 @@ type AuthService interface {
 
 	// SetPassword updates the admin password
 	SetPassword(newPassword string) error
+
+	// User management (viewer, uploader and admin accounts, see RoleAllows)
+	ListUsers() ([]Account, error)
+	CreateUser(username, password, role string) error
+	DeleteUser(username string) error
+	SetUserRole(username, role string) error
+	SetUserPassword(username, password string) error
+
+	// Session lifetime: TouchSession records use (sliding idle timeout), expired credentials are purged periodically
+	SetSessionPolicy(p SessionPolicy)
+	TouchSession(token, ip string)
+
+	// Two-factor login: with TOTP enabled, Login returns *TwoFactorRequiredError and the session is
+	// created by CompleteTwoFactorLogin
+	BeginTOTPEnrollment(username string) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(username, code string) ([]string, error)
+	DisableTOTP(username string) error
+	VerifySecondFactor(username, code string) error
+	CompleteTwoFactorLogin(challenge, code string) (string, error)
+
+	// API tokens: long-lived, scoped credentials of a user, listed with the sessions
+	CreateAPIToken(username, name string, scopes []string, expiresAt time.Time) (string, *User, error)
+	ListAPITokens(username string) ([]User, error)
+	RevokeAPIToken(username, name string) error
 }
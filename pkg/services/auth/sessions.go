@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+)
+
+// touchPersistInterval limits how often last-seen updates are written to disk. Idle timeouts
+// are measured in days, so losing a few minutes of activity on a crash does not matter.
+const touchPersistInterval = 5 * time.Minute
+
+// purgeInterval is how often expired sessions and tokens are removed
+const purgeInterval = time.Hour
+
+// SessionPolicy limits how long a login session stays valid. Zero disables a limit.
+// API tokens are not affected, they have their own expiry.
+type SessionPolicy struct {
+	MaxAge      time.Duration // Since login
+	IdleTimeout time.Duration // Since the session was last used (sliding)
+}
+
+// DefaultSessionPolicy applies until SetSessionPolicy is called
+var DefaultSessionPolicy = SessionPolicy{
+	MaxAge:      90 * 24 * time.Hour,
+	IdleTimeout: 14 * 24 * time.Hour,
+}
+
+// SessionPolicyFromDays builds a policy from the day counts stored in the settings
+func SessionPolicyFromDays(maxAgeDays, idleDays int) SessionPolicy {
+	p := SessionPolicy{}
+	if maxAgeDays > 0 {
+		p.MaxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+	}
+	if idleDays > 0 {
+		p.IdleTimeout = time.Duration(idleDays) * 24 * time.Hour
+	}
+	return p
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+// sessionExpiry returns when a credential stops being valid under the policy (zero for never)
+func (p SessionPolicy) sessionExpiry(u *User) time.Time {
+	if u.IsAPIToken() {
+		return parseTime(u.ExpiresAt)
+	}
+	var expiry time.Time
+	if p.MaxAge > 0 {
+		expiry = parseTime(u.CreatedAt).Add(p.MaxAge)
+	}
+	if p.IdleTimeout > 0 {
+		lastSeen := parseTime(u.LastSeen)
+		if lastSeen.IsZero() {
+			lastSeen = parseTime(u.CreatedAt)
+		}
+		if idle := lastSeen.Add(p.IdleTimeout); expiry.IsZero() || idle.Before(expiry) {
+			expiry = idle
+		}
+	}
+	return expiry
+}
+
+// isExpired reports whether u is no longer valid at now (s.mu held)
+func (s *SimpleTokenAuthService) isExpired(u *User, now time.Time) bool {
+	if u.expired(now) {
+		return true
+	}
+	expiry := s.policy.sessionExpiry(u)
+	return !expiry.IsZero() && !now.Before(expiry)
+}
+
+// SetSessionPolicy changes the session lifetimes. Sessions beyond the new limits are removed right away.
+func (s *SimpleTokenAuthService) SetSessionPolicy(p SessionPolicy) {
+	s.mu.Lock()
+	s.policy = p
+	s.mu.Unlock()
+	s.PurgeExpired()
+}
+
+// TouchSession records that a session was used from ip, which restarts its idle timeout
+func (s *SimpleTokenAuthService) TouchSession(token, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.validTokens[s.hashToken(token)]
+	if !ok {
+		return
+	}
+	now := s.now()
+	previous := parseTime(user.LastSeen)
+	changedIP := ip != "" && ip != user.IPAddress
+	user.LastSeen = now.Format(time.RFC3339)
+	if ip != "" {
+		user.IPAddress = ip
+	}
+	if changedIP || now.Sub(previous) >= touchPersistInterval {
+		if err := s.persistState(); err != nil {
+			fmt.Printf("Failed to persist session: %v\n", err)
+		}
+	}
+}
+
+// PurgeExpired removes expired sessions and API tokens and returns how many were removed
+func (s *SimpleTokenAuthService) PurgeExpired() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for token, user := range s.validTokens {
+		if s.isExpired(user, now) {
+			delete(s.validTokens, token)
+			removed++
+		}
+	}
+	if removed > 0 {
+		if err := s.persistState(); err != nil {
+			fmt.Printf("Failed to persist auth config: %v\n", err)
+		}
+	}
+	return removed
+}
+
+func (s *SimpleTokenAuthService) purgeLoop() {
+	for {
+		time.Sleep(purgeInterval)
+		s.PurgeExpired()
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 )
 
 type AuthConfig struct {
-	AdminHash string           `json:"admin_hash"`
-	Sessions  map[string]*User `json:"sessions,omitempty"` // Persistence
+	AdminHash string                 `json:"admin_hash,omitempty"` // Single password before accounts existed, migrated to Users
+	Users     map[string]*UserRecord `json:"users,omitempty"`
+	TokenSalt string                 `json:"token_salt,omitempty"` // Hex, for the session keys
+	Sessions  map[string]*User       `json:"sessions,omitempty"`   // Persistence, keyed by salted SHA-256 of the token
 }
 
 type FileAuthStore struct {
@@ -58,5 +61,33 @@ func (s *FileAuthStore) Save(config *AuthConfig) error {
 		return err
 	}
 
-	return os.WriteFile(s.filePath, data, 0644)
+	// Only readable by the current user, replaced atomically so a crash never leaves a partial file
+	f, err := os.CreateTemp(dir, filepath.Base(s.filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0600); err != nil && runtime.GOOS != "windows" {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), s.filePath); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scopes of API tokens. A token can never do more than the role of its owner allows.
+const (
+	ScopePackagesRead   = "packages:read"   // Browse, search, details, thumbnails, downloads, events and jobs
+	ScopePackagesToggle = "packages:toggle" // Enable and disable packages
+	ScopePackagesWrite  = "packages:write"  // Install, delete, resolve, quarantine, salvage, export, integrity checks
+	ScopeUpload         = "upload"          // Upload packages
+	ScopeAdmin          = "admin"           // Settings, accounts, sessions and tokens
+)
+
+// apiTokenPrefix makes API tokens recognizable (e.g. by secret scanners)
+const apiTokenPrefix = "yvm_"
+
+var (
+	ErrTokenExists   = errors.New("a token with this name already exists")
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+var validScopes = map[string]bool{
+	ScopePackagesRead:   true,
+	ScopePackagesToggle: true,
+	ScopePackagesWrite:  true,
+	ScopeUpload:         true,
+	ScopeAdmin:          true,
+}
+
+// ValidScope reports whether scope is one of the known scopes
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// HasScope reports whether the credential may act within scope. Sessions are only limited by their role.
+func (u *User) HasScope(scope string) bool {
+	if !u.IsAPIToken() {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) expired(now time.Time) bool {
+	if u.ExpiresAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, u.ExpiresAt)
+	return err != nil || !now.Before(t)
+}
+
+// CreateAPIToken issues a named token for username with the given scopes. expiresAt may be zero for
+// a token that never expires. The secret is only returned here.
+func (s *SimpleTokenAuthService) CreateAPIToken(username, name string, scopes []string, expiresAt time.Time) (string, *User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(s.now()) {
+		return "", nil, fmt.Errorf("expiry must be in the future")
+	}
+	username = normalizeUsername(username)
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := apiTokenPrefix + hex.EncodeToString(b)
+	sid := make([]byte, 8)
+	rand.Read(sid)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.users[username]
+	if !ok {
+		return "", nil, ErrUserNotFound
+	}
+	for _, u := range s.validTokens {
+		if u.IsAPIToken() && u.Username == username && u.Name == name {
+			return "", nil, ErrTokenExists
+		}
+	}
+
+	user := &User{
+		ID:         hex.EncodeToString(sid),
+		Kind:       KindAPIToken,
+		Username:   username,
+		Role:       account.Role,
+		DeviceName: "API Token: " + name,
+		Name:       name,
+		Scopes:     append([]string(nil), scopes...),
+		CreatedAt:  s.now().Format(time.RFC3339),
+		Token:      token,
+	}
+	if !expiresAt.IsZero() {
+		user.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+	key := s.hashToken(token)
+	s.validTokens[key] = user
+	if err := s.persistState(); err != nil {
+		delete(s.validTokens, key)
+		return "", nil, err
+	}
+	out := *user
+	return token, &out, nil
+}
+
+// ListAPITokens returns the tokens of username (all users when empty), sorted by name
+func (s *SimpleTokenAuthService) ListAPITokens(username string) ([]User, error) {
+	username = normalizeUsername(username)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []User{}
+	now := s.now()
+	for _, u := range s.validTokens {
+		if u.IsAPIToken() && (username == "" || u.Username == username) && !s.isExpired(u, now) {
+			tokens = append(tokens, *u)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].Username != tokens[j].Username {
+			return tokens[i].Username < tokens[j].Username
+		}
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
+}
+
+// RevokeAPIToken removes the token called name of username
+func (s *SimpleTokenAuthService) RevokeAPIToken(username, name string) error {
+	username = normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, u := range s.validTokens {
+		if u.IsAPIToken() && u.Username == username && u.Name == name {
+			delete(s.validTokens, token)
+			return s.persistState()
+		}
+	}
+	return ErrTokenNotFound
+}
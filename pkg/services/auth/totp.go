@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238 parameters understood by every authenticator app
+const (
+	totpPeriod = 30 // Seconds per code
+	totpDigits = 6
+	totpSkew   = 1 // Codes of the neighbouring periods are accepted for clock drift
+	totpIssuer = "YAVAM"
+)
+
+// Two-step login
+const (
+	loginChallengeTTL   = 5 * time.Minute
+	loginChallengeTries = 5
+	recoveryCodeCount   = 10
+)
+
+var (
+	ErrInvalidCode          = errors.New("invalid two-factor code")
+	ErrTOTPNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrNoPendingEnrollment  = errors.New("no two-factor enrollment in progress")
+	ErrInvalidLoginAttempt  = errors.New("login attempt expired, sign in again")
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication not available")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorRequiredError is returned by Login when the password was right but the account also
+// needs a TOTP or recovery code. Pass Challenge with the code to CompleteTwoFactorLogin.
+type TwoFactorRequiredError struct {
+	Challenge string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor code required"
+}
+
+// TOTPEnrollment is what an authenticator app needs to be set up
+type TOTPEnrollment struct {
+	Secret string `json:"secret"` // Base32, for manual entry
+	URI    string `json:"uri"`    // otpauth:// provisioning URI, usually shown as a QR code
+}
+
+// loginChallenge is a login waiting for its second factor
+type loginChallenge struct {
+	username   string
+	deviceName string
+	expires    time.Time
+	tries      int
+}
+
+// totpCode computes the code for a time step (RFC 4226 HOTP with the step as counter)
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// matchTOTP returns the time step code belongs to at now, or false
+func matchTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := hex.EncodeToString(b)
+		codes[i] = c[:5] + "-" + c[5:]
+		hashes[i] = hashRecoveryCode(c)
+	}
+	return codes, hashes, nil
+}
+
+// BeginTOTPEnrollment creates a new secret for username. It only takes effect once confirmed with
+// a code from the authenticator app (ConfirmTOTPEnrollment).
+func (s *SimpleTokenAuthService) BeginTOTPEnrollment(username string) (*TOTPEnrollment, error) {
+	username = normalizeUsername(username)
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if u.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	u.TOTPSecret = totpEncoding.EncodeToString(secret)
+	if err := s.persistState(); err != nil {
+		return nil, err
+	}
+
+	label := url.PathEscape(totpIssuer + ":" + username)
+	q := url.Values{}
+	q.Set("secret", u.TOTPSecret)
+	q.Set("issuer", totpIssuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return &TOTPEnrollment{
+		Secret: u.TOTPSecret,
+		URI:    "otpauth://totp/" + label + "?" + q.Encode(),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor login for username if code matches the pending secret.
+// Returns the recovery codes, which are only shown this once.
+func (s *SimpleTokenAuthService) ConfirmTOTPEnrollment(username, code string) ([]string, error) {
+	username = normalizeUsername(username)
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if u.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if u.TOTPSecret == "" {
+		return nil, ErrNoPendingEnrollment
+	}
+	secret, err := totpEncoding.DecodeString(u.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := matchTOTP(secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	u.TOTPEnabled = true
+	u.TOTPLastStep = step
+	u.RecoveryCodes = hashes
+	if err := s.persistState(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor login off for username (e.g. after losing the device)
+func (s *SimpleTokenAuthService) DisableTOTP(username string) error {
+	username = normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.TOTPEnabled = false
+	u.TOTPSecret = ""
+	u.TOTPLastStep = 0
+	u.RecoveryCodes = nil
+	return s.persistState()
+}
+
+// VerifySecondFactor checks a TOTP or recovery code of username. Each TOTP code and recovery code
+// is only accepted once.
+func (s *SimpleTokenAuthService) VerifySecondFactor(username, code string) error {
+	username = normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.verifySecondFactor(username, code)
+}
+
+// verifySecondFactor is VerifySecondFactor with s.mu held
+func (s *SimpleTokenAuthService) verifySecondFactor(username, code string) error {
+	u, ok := s.users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if !u.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+	secret, err := totpEncoding.DecodeString(u.TOTPSecret)
+	if err != nil {
+		return ErrTwoFactorUnavailable
+	}
+
+	if step, ok := matchTOTP(secret, code, s.now()); ok {
+		if step <= u.TOTPLastStep {
+			return ErrInvalidCode // Replayed
+		}
+		u.TOTPLastStep = step
+		return s.persistState()
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, h := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+			fmt.Printf("[Auth] Recovery code used for '%s', %d left\n", username, len(u.RecoveryCodes))
+			return s.persistState()
+		}
+	}
+	return ErrInvalidCode
+}
+
+// newLoginChallenge remembers a login that passed the password check (s.mu held)
+func (s *SimpleTokenAuthService) newLoginChallenge(username, deviceName string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	now := s.now()
+	for id, c := range s.challenges {
+		if now.After(c.expires) {
+			delete(s.challenges, id)
+		}
+	}
+	id := hex.EncodeToString(b)
+	s.challenges[id] = &loginChallenge{
+		username:   username,
+		deviceName: deviceName,
+		expires:    now.Add(loginChallengeTTL),
+	}
+	return id, nil
+}
+
+// CompleteTwoFactorLogin finishes a login started by Login with the TOTP or a recovery code and
+// returns the session token
+func (s *SimpleTokenAuthService) CompleteTwoFactorLogin(challenge, code string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.challenges[challenge]
+	if !ok || s.now().After(c.expires) {
+		delete(s.challenges, challenge)
+		return "", ErrInvalidLoginAttempt
+	}
+	if err := s.verifySecondFactor(c.username, code); err != nil {
+		c.tries++
+		if c.tries >= loginChallengeTries {
+			delete(s.challenges, challenge)
+		}
+		return "", err
+	}
+	delete(s.challenges, challenge)
+	return s.createSession(c.username, c.deviceName)
+}
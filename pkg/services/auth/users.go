@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles, each including the rights of the ones before it
+const (
+	RoleViewer   = "viewer"   // Browse and download
+	RoleUploader = "uploader" // Viewer plus uploading packages
+	RoleAdmin    = "admin"    // Everything, including settings and user management
+)
+
+// DefaultAdmin is the account created on first start (and migrated from the single admin password)
+const DefaultAdmin = "admin"
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+	ErrInvalidRole  = errors.New("invalid role")
+	ErrLastAdmin    = errors.New("at least one admin account is required")
+)
+
+var roleRank = map[string]int{
+	RoleViewer:   1,
+	RoleUploader: 2,
+	RoleAdmin:    3,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAllows reports whether a user with role may do what required demands
+func RoleAllows(role string, required string) bool {
+	return roleRank[role] >= roleRank[required] && roleRank[role] > 0
+}
+
+// UserRecord is a stored account. The password is only kept as a bcrypt hash.
+type UserRecord struct {
+	Role      string `json:"role"`
+	Hash      string `json:"hash"`
+	CreatedAt string `json:"createdAt"`
+
+	// Two-factor login (RFC 6238). The secret is set on enrollment and only used once confirmed.
+	TOTPEnabled   bool     `json:"totpEnabled,omitempty"`
+	TOTPSecret    string   `json:"totpSecret,omitempty"`    // Base32
+	TOTPLastStep  int64    `json:"totpLastStep,omitempty"`  // Last accepted time step, against replays
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"` // SHA-256 of the unused recovery codes
+}
+
+// Account describes a user without credentials
+type Account struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"createdAt"`
+	TwoFactor bool   `json:"twoFactor"`
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// adminCount returns the number of admin accounts (s.mu held)
+func (s *SimpleTokenAuthService) adminCount() int {
+	n := 0
+	for _, u := range s.users {
+		if u.Role == RoleAdmin {
+			n++
+		}
+	}
+	return n
+}
+
+// ListUsers returns every account, sorted by name
+func (s *SimpleTokenAuthService) ListUsers() ([]Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accounts := make([]Account, 0, len(s.users))
+	for name, u := range s.users {
+		accounts = append(accounts, Account{Username: name, Role: u.Role, CreatedAt: u.CreatedAt, TwoFactor: u.TOTPEnabled})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Username < accounts[j].Username })
+	return accounts, nil
+}
+
+// CreateUser adds an account with its own password
+func (s *SimpleTokenAuthService) CreateUser(username, password, role string) error {
+	name := normalizeUsername(username)
+	if name == "" {
+		return fmt.Errorf("username is required")
+	}
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.users[name]; exists {
+		return ErrUserExists
+	}
+	s.users[name] = &UserRecord{
+		Role:      role,
+		Hash:      string(hash),
+		CreatedAt: time.Now().Format(time.RFC3339),
+	}
+	return s.persistState()
+}
+
+// DeleteUser removes an account and signs out all of its sessions
+func (s *SimpleTokenAuthService) DeleteUser(username string) error {
+	name := normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, exists := s.users[name]
+	if !exists {
+		return ErrUserNotFound
+	}
+	if u.Role == RoleAdmin && s.adminCount() == 1 {
+		return ErrLastAdmin
+	}
+	delete(s.users, name)
+	for token, session := range s.validTokens {
+		if session.Username == name {
+			delete(s.validTokens, token)
+		}
+	}
+	return s.persistState()
+}
+
+// SetUserRole changes the role of an account. Open sessions get the new role right away.
+func (s *SimpleTokenAuthService) SetUserRole(username, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	name := normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, exists := s.users[name]
+	if !exists {
+		return ErrUserNotFound
+	}
+	if u.Role == RoleAdmin && role != RoleAdmin && s.adminCount() == 1 {
+		return ErrLastAdmin
+	}
+	u.Role = role
+	for _, session := range s.validTokens {
+		if session.Username == name {
+			session.Role = role
+		}
+	}
+	return s.persistState()
+}
+
+// SetUserPassword replaces the password of an account. Existing sessions stay signed in.
+func (s *SimpleTokenAuthService) SetUserPassword(username, password string) error {
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	name := normalizeUsername(username)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, exists := s.users[name]
+	if !exists {
+		return ErrUserNotFound
+	}
+	u.Hash = string(hash)
+	return s.persistState()
+}
@@ -14,17 +14,24 @@ type Config struct {
 	Theme       string   `json:"theme"`
 	AccentColor string   `json:"accentColor"`
 	// Advanced Settings
-	AutoScan         bool                `json:"autoScan"`
-	CheckUpdates     bool                `json:"checkUpdates"`
-	UseSymlinks      bool                `json:"useSymlinks"` // Default true for efficiency
-	DeleteToTrash    bool                `json:"deleteToTrash"`
-	PublicAccess     bool                `json:"publicAccess"`
-	ServerEnabled    bool                `json:"serverEnabled"`
-	ServerPort       string              `json:"serverPort"`
-	AuthPollInterval int                 `json:"authPollInterval"`
-	LastSeenVersion  string              `json:"lastSeenVersion"`
-	PrivacyMode      bool                `json:"privacyMode"`
-	Keybinds         map[string][]string `json:"keybinds,omitempty"` // ID -> ["CTRL", "F"]
+	AutoScan          bool                `json:"autoScan"`
+	CheckUpdates      bool                `json:"checkUpdates"`
+	UseSymlinks       bool                `json:"useSymlinks"` // Default true for efficiency
+	DeleteToTrash     bool                `json:"deleteToTrash"`
+	PublicAccess      bool                `json:"publicAccess"`
+	ServerEnabled     bool                `json:"serverEnabled"`
+	ServerPort        string              `json:"serverPort"`
+	AuthPollInterval  int                 `json:"authPollInterval"`
+	SessionMaxAgeDays int                 `json:"sessionMaxAgeDays"` // Web sessions end this long after login, 0 for never
+	SessionIdleDays   int                 `json:"sessionIdleDays"`   // Web sessions end after this long unused, 0 for never
+	LastSeenVersion   string              `json:"lastSeenVersion"`
+	PrivacyMode       bool                `json:"privacyMode"`
+	Keybinds          map[string][]string `json:"keybinds,omitempty"` // ID -> ["CTRL", "F"]
+
+	// Library Watching
+	WatchLibraries    bool `json:"watchLibraries"`
+	WatchForcePolling bool `json:"watchForcePolling"` // For SMB/mapped drives without change notifications
+	WatchPollInterval int  `json:"watchPollInterval"` // Seconds
 
 	// UI Preferences
 	GridSize         int    `json:"gridSize"`
@@ -57,13 +64,17 @@ func NewFileConfigService(configDir string) (ConfigService, error) {
 	svc := &fileConfigService{
 		path: configPath,
 		config: &Config{
-			Libraries:        []string{},
-			UseSymlinks:      true,  // Default
-			DeleteToTrash:    true,  // Default
-			PublicAccess:     false, // Default Private
-			ServerPort:       "18888",
-			AuthPollInterval: 15,
-			Keybinds:         make(map[string][]string),
+			Libraries:         []string{},
+			UseSymlinks:       true,  // Default
+			DeleteToTrash:     true,  // Default
+			PublicAccess:      false, // Default Private
+			ServerPort:        "18888",
+			AuthPollInterval:  15,
+			SessionMaxAgeDays: 90,
+			SessionIdleDays:   14,
+			Keybinds:          make(map[string][]string),
+			WatchLibraries:    true,
+			WatchPollInterval: 30,
 			// UI Defaults
 			GridSize:     160,
 			SortMode:     "name-asc",
@@ -1,67 +1,78 @@
 package library
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"yavam/pkg/events"
+	"yavam/pkg/graph"
+	"yavam/pkg/index"
 	"yavam/pkg/models"
+	"yavam/pkg/utils"
 )
 
 // ResolveConflictResult holds statistics about the resolution operation
 // Note: This struct is defined in models, but duplicated in manager.go originally. Used models one.
 
-// CheckDependencies analyzes packages for missing dependencies
+// CheckDependencies resolves every declared dependency against the enabled packages (VaM ignores disabled ones)
+// and records the outcome in DependencyStatus. MissingDeps keeps listing the unsatisfied direct IDs.
 func (s *defaultLibraryService) CheckDependencies(pkgs []models.VarPackage) []models.VarPackage {
-	// Build an index of available package IDs "Creator.Package.Version"
-	// And "Creator.Package" (latest)
-	available := make(map[string]bool)
-
+	var providers []models.VarPackage
 	for _, p := range pkgs {
-		// strict ID
-		id := fmt.Sprintf("%s.%s.%s", p.Meta.Creator, p.Meta.PackageName, p.Meta.Version)
-		available[strings.ToLower(id)] = true
-
-		// loose ID (just package existence)
-		baseId := fmt.Sprintf("%s.%s", p.Meta.Creator, p.Meta.PackageName)
-		available[strings.ToLower(baseId)] = true
+		if p.IsEnabled {
+			providers = append(providers, p)
+		}
 	}
+	resolver := graph.NewResolver(providers)
 
 	for i := range pkgs {
-		var missing []string
-		if pkgs[i].Meta.Dependencies != nil {
-			for depID := range pkgs[i].Meta.Dependencies {
-				// Dependency format in meta.json is "Creator.Package.Version" : "license/url"
-				// We check if "Creator.Package.Version" exists
-				// VaM also allows "latest" typically handled by game, but meta lists specific version.
-
-				depIDLower := strings.ToLower(depID)
-
-				// Check strict existence
-				if !available[depIDLower] {
-					// Check if any version of that package exists?
-					// Technically missing specific version might be fine if a newer one exists,
-					// but strictly it's missing.
-					// Let's check if the base package exists at least.
-					parts := strings.Split(depIDLower, ".")
-					if len(parts) >= 2 {
-						baseId := fmt.Sprintf("%s.%s", parts[0], parts[1])
-						if !available[baseId] {
-							missing = append(missing, depID)
-						}
-					} else {
-						missing = append(missing, depID)
-					}
-				}
+		var statuses []models.DependencyStatus
+		var missing, missingTransitive []string
+
+		// The whole declared tree is checked: intermediate packages may be missing themselves,
+		// their own requirements are still needed once they get installed
+		for _, d := range graph.Declared(pkgs[i].Meta) {
+			res := resolver.Resolve(d.ID)
+			statuses = append(statuses, models.DependencyStatus{
+				ID:           d.ID,
+				Status:       res.Status,
+				ResolvedID:   res.ID,
+				ResolvedPath: res.Path,
+				LicenseType:  d.LicenseType,
+				Transitive:   d.Depth > 1,
+				Via:          d.Via,
+			})
+			if res.Satisfied() {
+				continue
+			}
+			if d.Depth > 1 {
+				missingTransitive = append(missingTransitive, d.ID)
+			} else {
+				missing = append(missing, d.ID)
 			}
 		}
+		pkgs[i].DependencyStatus = statuses
 		pkgs[i].MissingDeps = missing
+		pkgs[i].MissingTransitiveDeps = missingTransitive
 	}
 	return pkgs
 }
 
 // ResolveConflicts handles deduplication and cleanup of conflicting packages
 func (s *defaultLibraryService) ResolveConflicts(keepPath string, others []string, libraryPath string) (*models.ResolveConflictResult, error) {
+	result, err := s.resolveConflicts(keepPath, others, libraryPath)
+	// Files may have been merged or disabled before a failure, so partial results are announced too
+	if result != nil {
+		s.events.Publish(events.ConflictsResolved{LibraryPath: libraryPath, KeepPath: keepPath, Others: others, Result: result})
+	}
+	return result, err
+}
+
+func (s *defaultLibraryService) resolveConflicts(keepPath string, others []string, libraryPath string) (*models.ResolveConflictResult, error) {
 	// 1. Get info of the file to keep
 	keepInfo, err := os.Stat(keepPath)
 	if err != nil {
@@ -84,9 +95,14 @@ func (s *defaultLibraryService) ResolveConflicts(keepPath string, others []strin
 		if os.IsNotExist(err) {
 			continue // Already gone
 		}
+		if err == nil && os.SameFile(keepInfo, otherInfo) {
+			// The same file reached through another path (nested libraries, different case):
+			// deleting it would delete the copy we keep
+			continue
+		}
 
-		// Merge Check: Exactly same size?
-		if otherInfo.Size() == keepInfo.Size() {
+		// Merge Check: Byte-identical? (same size alone is not enough, different builds can match)
+		if s.identical(keepPath, keepInfo, otherPath, otherInfo) {
 			// MATCH! Deleting duplicate
 			if err := os.Remove(otherPath); err == nil {
 				result.Merged++
@@ -97,6 +113,12 @@ func (s *defaultLibraryService) ResolveConflicts(keepPath string, others []strin
 				disabledPath := otherPath + ".disabled"
 				if err := os.Rename(otherPath, disabledPath); err == nil {
 					result.Disabled++
+					if s.index != nil {
+						s.index.Move(otherPath, disabledPath)
+					}
+					if s.text != nil {
+						s.text.Move(otherPath, disabledPath)
+					}
 				}
 			}
 		}
@@ -116,9 +138,12 @@ func (s *defaultLibraryService) ResolveConflicts(keepPath string, others []strin
 				// We have a collision at the destination.
 				// This implies we are merging FROM a subdirectory or sidecar TO the root.
 				// If we are here, we probably should have checked this earlier.
-				// But let's check size.
+				// But let's compare contents.
 				destInfo, _ := os.Stat(targetPath)
-				if destInfo.Size() == keepInfo.Size() {
+				if destInfo != nil && os.SameFile(keepInfo, destInfo) {
+					return result, nil // Already in the root, under another spelling
+				}
+				if s.identical(keepPath, keepInfo, targetPath, destInfo) {
 					// Dest is same. Delete source.
 					os.Remove(keepPath)
 					result.NewPath = targetPath
@@ -137,8 +162,161 @@ func (s *defaultLibraryService) ResolveConflicts(keepPath string, others []strin
 		if err := os.Rename(keepPath, targetPath); err != nil {
 			return result, err
 		}
+		if s.index != nil {
+			s.index.Move(keepPath, targetPath)
+		}
+		if s.text != nil {
+			s.text.Move(keepPath, targetPath)
+		}
 		result.NewPath = targetPath
 	}
 
 	return result, nil
 }
+
+// distinctFiles drops packages that are the same file as an earlier one, reached through another
+// path (symbolic links, case-insensitive file systems, hard links)
+func distinctFiles(pkgs []models.VarPackage) []models.VarPackage {
+	var out []models.VarPackage
+	var infos []os.FileInfo
+	for _, p := range pkgs {
+		info, err := os.Stat(p.FilePath)
+		if err != nil {
+			continue // Vanished since the walk
+		}
+		same := false
+		for _, known := range infos {
+			if os.SameFile(known, info) {
+				same = true
+				break
+			}
+		}
+		if same {
+			continue
+		}
+		out = append(out, p)
+		infos = append(infos, info)
+	}
+	return out
+}
+
+// FindDuplicates reports byte-identical package files across the given libraries.
+// Only files sharing a size are hashed, and hashes of unchanged files come from the index.
+func (s *defaultLibraryService) FindDuplicates(ctx context.Context, libraries []string) ([]models.DuplicateGroup, error) {
+	bySize := make(map[int64][]models.VarPackage)
+	// Nested or overlapping libraries list the same file more than once
+	seen := make(map[string]bool)
+	for _, lib := range libraries {
+		pkgs, err := s.scanner.ScanForPackages(lib)
+		if err != nil {
+			fmt.Printf("[Library] Skipping %s in duplicate report: %v\n", lib, err)
+			continue
+		}
+		for _, p := range pkgs {
+			key := index.Key(p.FilePath)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			bySize[p.Size] = append(bySize[p.Size], p)
+		}
+	}
+
+	var candidates []models.VarPackage
+	for _, group := range bySize {
+		if len(group) > 1 {
+			group = distinctFiles(group)
+		}
+		if len(group) > 1 {
+			candidates = append(candidates, group...)
+		}
+	}
+
+	hashes := make([]string, len(candidates))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 4) // Disk bound, more workers only cause seeking
+	for i, p := range candidates {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			if hash, err := s.contentHash(path); err == nil {
+				hashes[i] = hash
+			}
+		}(i, p.FilePath)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.index != nil {
+		if err := s.index.Save(); err != nil {
+			fmt.Printf("[Library] Failed to save package index: %v\n", err)
+		}
+	}
+
+	byHash := make(map[string]*models.DuplicateGroup)
+	for i, p := range candidates {
+		if hashes[i] == "" {
+			continue
+		}
+		g, ok := byHash[hashes[i]]
+		if !ok {
+			g = &models.DuplicateGroup{Hash: hashes[i], Size: p.Size}
+			byHash[hashes[i]] = g
+		}
+		g.Files = append(g.Files, models.FileDetail{Name: p.FileName, Size: p.Size, Path: p.FilePath})
+	}
+
+	groups := []models.DuplicateGroup{}
+	for _, g := range byHash {
+		if len(g.Files) < 2 {
+			continue
+		}
+		sort.Slice(g.Files, func(i, j int) bool { return g.Files[i].Path < g.Files[j].Path })
+		groups = append(groups, *g)
+	}
+	// Most wasted space first
+	sort.Slice(groups, func(i, j int) bool {
+		wi := groups[i].Size * int64(len(groups[i].Files)-1)
+		wj := groups[j].Size * int64(len(groups[j].Files)-1)
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i].Hash < groups[j].Hash
+	})
+	return groups, nil
+}
+
+// identical reports whether two files have the same content. Sizes are compared first so
+// differently sized files are never hashed; unreadable files are treated as different.
+// A match deletes a file, so both are hashed from disk rather than trusting the index.
+func (s *defaultLibraryService) identical(pathA string, infoA os.FileInfo, pathB string, infoB os.FileInfo) bool {
+	if infoA == nil || infoB == nil || infoA.Size() != infoB.Size() {
+		return false
+	}
+	hashA, err := utils.HashFile(pathA)
+	if err != nil {
+		return false
+	}
+	hashB, err := utils.HashFile(pathB)
+	if err != nil {
+		return false
+	}
+	return hashA == hashB
+}
+
+// contentHash returns the SHA-256 of a package, served from the index when the file is unchanged.
+// Good enough for reports; use utils.HashFile before deleting anything.
+func (s *defaultLibraryService) contentHash(path string) (string, error) {
+	if s.index != nil {
+		if p, err := s.GetPackage(path); err == nil && p.Hash != "" {
+			return p.Hash, nil
+		}
+	}
+	return utils.HashFile(path)
+}
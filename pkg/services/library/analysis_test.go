@@ -0,0 +1,185 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/index"
+	"yavam/pkg/models"
+)
+
+func TestResolveConflicts_SameSizeDifferentContent(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	root := t.TempDir()
+	keep := filepath.Join(root, "Creator.Pkg.1.var")
+	sameBytes := filepath.Join(root, "Sub", "Creator.Pkg.1.var")
+	otherBuild := filepath.Join(root, "Other", "Creator.Pkg.1.var")
+	os.MkdirAll(filepath.Dir(sameBytes), 0755)
+	os.MkdirAll(filepath.Dir(otherBuild), 0755)
+	os.WriteFile(keep, []byte("build-A"), 0644)
+	os.WriteFile(sameBytes, []byte("build-A"), 0644)
+	os.WriteFile(otherBuild, []byte("build-B"), 0644) // Same size, different bytes
+
+	res, err := lib.ResolveConflicts(keep, []string{sameBytes, otherBuild}, root)
+	if err != nil {
+		t.Fatalf("ResolveConflicts failed: %v", err)
+	}
+	if res.Merged != 1 || res.Disabled != 1 {
+		t.Errorf("Expected 1 merged and 1 disabled, got %+v", res)
+	}
+	if _, err := os.Stat(sameBytes); !os.IsNotExist(err) {
+		t.Error("Identical copy should have been deleted")
+	}
+	if _, err := os.Stat(otherBuild + ".disabled"); err != nil {
+		t.Error("Different build must be disabled, not deleted")
+	}
+}
+
+func TestResolveConflicts_IgnoresStaleIndexHash(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	root := t.TempDir()
+	keep := filepath.Join(root, "Creator.Pkg.1.var")
+	other := filepath.Join(root, "Sub", "Creator.Pkg.1.var")
+	os.MkdirAll(filepath.Dir(other), 0755)
+	os.WriteFile(keep, []byte("build-A"), 0644)
+	os.WriteFile(other, []byte("build-A"), 0644)
+	lib.FindDuplicates(context.Background(), []string{root}) // Hashes both into the index
+
+	// Rewritten with the same size, keeping the modification time (e.g. a copy preserving it)
+	info, _ := os.Stat(other)
+	os.WriteFile(other, []byte("build-B"), 0644)
+	os.Chtimes(other, info.ModTime(), info.ModTime())
+
+	res, err := lib.ResolveConflicts(keep, []string{other}, root)
+	if err != nil {
+		t.Fatalf("ResolveConflicts failed: %v", err)
+	}
+	if res.Merged != 0 || res.Disabled != 1 {
+		t.Errorf("Expected the changed file to be disabled, got %+v", res)
+	}
+}
+
+func TestFindDuplicates_AcrossLibraries(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	libA, libB := t.TempDir(), t.TempDir()
+
+	os.WriteFile(filepath.Join(libA, "Creator.Pkg.1.var"), []byte("identical"), 0644)
+	os.WriteFile(filepath.Join(libB, "Creator.Pkg.1.var.disabled"), []byte("identical"), 0644)
+	os.WriteFile(filepath.Join(libB, "Creator.Other.1.var"), []byte("different"), 0644) // Same size only
+	os.WriteFile(filepath.Join(libB, "Creator.Unique.1.var"), []byte("unique size"), 0644)
+
+	groups, err := lib.FindDuplicates(context.Background(), []string{libA, libB})
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("Expected 1 duplicate group, got %d: %+v", len(groups), groups)
+	}
+	if len(groups[0].Files) != 2 || groups[0].Size != int64(len("identical")) {
+		t.Errorf("Unexpected group: %+v", groups[0])
+	}
+}
+
+func TestCheckDependencies_VersionSemantics(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	dep := func(creator, name, version string, enabled bool) models.VarPackage {
+		return models.VarPackage{
+			FilePath:  creator + "." + name + "." + version + ".var",
+			IsEnabled: enabled,
+			Meta:      models.MetaJSON{Creator: creator, PackageName: name, Version: version},
+		}
+	}
+	consumer := models.VarPackage{
+		FilePath:  "Me.Scene.1.var",
+		IsEnabled: true,
+		Meta: models.MetaJSON{Creator: "Me", PackageName: "Scene", Version: "1", Dependencies: map[string]models.Dependency{
+			"A.Morphs.min12": {},
+			"B.Look.latest":  {},
+			"C.Hair.1":       {},
+		}},
+	}
+
+	pkgs := lib.CheckDependencies([]models.VarPackage{
+		consumer,
+		dep("A", "Morphs", "3", true),
+		dep("B", "Look", "2", true),
+		dep("C", "Hair", "1", false), // Disabled packages are not loaded by VaM
+	})
+
+	want := map[string]string{
+		"A.Morphs.min12": models.DependencySatisfiedByOlderOnly,
+		"B.Look.latest":  models.DependencySatisfiedExact,
+		"C.Hair.1":       models.DependencyMissing,
+	}
+	for _, st := range pkgs[0].DependencyStatus {
+		if want[st.ID] != st.Status {
+			t.Errorf("%s: got %s, want %s", st.ID, st.Status, want[st.ID])
+		}
+	}
+	if len(pkgs[0].MissingDeps) != 2 {
+		t.Errorf("Expected older-only and missing in MissingDeps, got %v", pkgs[0].MissingDeps)
+	}
+}
+
+func TestCheckDependencies_MissingTransitively(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	consumer := models.VarPackage{
+		FilePath:  "Me.Scene.1.var",
+		IsEnabled: true,
+		Meta: models.MetaJSON{Creator: "Me", PackageName: "Scene", Version: "1", Dependencies: map[string]models.Dependency{
+			"A.Look.1": {LicenseType: "FC", Dependencies: map[string]models.Dependency{
+				"B.Morphs.latest": {LicenseType: "PC"},
+			}},
+		}},
+	}
+	look := models.VarPackage{FilePath: "A.Look.1.var", IsEnabled: true, Meta: models.MetaJSON{Creator: "A", PackageName: "Look", Version: "1"}}
+
+	pkgs := lib.CheckDependencies([]models.VarPackage{consumer, look})
+
+	if len(pkgs[0].MissingDeps) != 0 {
+		t.Errorf("Direct dependency is installed, got missing %v", pkgs[0].MissingDeps)
+	}
+	if len(pkgs[0].MissingTransitiveDeps) != 1 || pkgs[0].MissingTransitiveDeps[0] != "B.Morphs.latest" {
+		t.Errorf("Expected B.Morphs.latest missing transitively, got %v", pkgs[0].MissingTransitiveDeps)
+	}
+	for _, st := range pkgs[0].DependencyStatus {
+		if st.ID == "B.Morphs.latest" && (!st.Transitive || st.Via != "A.Look.1" || st.LicenseType != "PC") {
+			t.Errorf("Unexpected transitive status: %+v", st)
+		}
+	}
+}
+
+func TestDuplicates_NestedLibraries(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	root := t.TempDir()
+	nested := filepath.Join(root, "Sub")
+	os.MkdirAll(nested, 0755)
+	pkgPath := filepath.Join(nested, "Creator.Pkg.1.var")
+	os.WriteFile(pkgPath, []byte("only copy"), 0644)
+
+	// Both libraries see the same file
+	groups, err := lib.FindDuplicates(context.Background(), []string{root, nested})
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("A file must not be reported as its own duplicate: %+v", groups)
+	}
+
+	// Resolving with the same file under another spelling must not delete it
+	alias := nested + string(os.PathSeparator) + "." + string(os.PathSeparator) + "Creator.Pkg.1.var"
+	res, err := lib.ResolveConflicts(pkgPath, []string{alias}, nested)
+	if err != nil {
+		t.Fatalf("ResolveConflicts failed: %v", err)
+	}
+	if res.Merged != 0 || res.Disabled != 0 {
+		t.Errorf("Expected nothing to merge or disable, got %+v", res)
+	}
+	if _, err := os.Stat(pkgPath); err != nil {
+		t.Errorf("The only copy was removed: %v", err)
+	}
+}
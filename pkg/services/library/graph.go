@@ -0,0 +1,76 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"yavam/pkg/graph"
+	"yavam/pkg/models"
+)
+
+// DependencyGraph builds the dependency graph of a library.
+// Packages come from a regular scan, so unchanged files are served from the index.
+func (s *defaultLibraryService) DependencyGraph(ctx context.Context, libraryPath string) (*graph.Graph, error) {
+	var pkgs []models.VarPackage
+	err := s.Scan(ctx, libraryPath, func(p models.VarPackage) {
+		pkgs = append(pkgs, p)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return graph.Build(pkgs), nil
+}
+
+// UsedBy lists the packages of a library that directly depend on pkgPath
+func (s *defaultLibraryService) UsedBy(ctx context.Context, libraryPath string, pkgPath string) ([]graph.NodeRef, error) {
+	g, err := s.DependencyGraph(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := g.Lookup(pkgPath); !ok {
+		return nil, fmt.Errorf("package not found in library: %s", pkgPath)
+	}
+	return g.UsedBy(pkgPath), nil
+}
+
+// GraphRoots lists the packages of a library that nothing depends on
+func (s *defaultLibraryService) GraphRoots(ctx context.Context, libraryPath string) ([]graph.NodeRef, error) {
+	g, err := s.DependencyGraph(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return g.Roots(), nil
+}
+
+// GraphOrphans lists unreferenced dependency-only packages of a library
+func (s *defaultLibraryService) GraphOrphans(ctx context.Context, libraryPath string) ([]graph.NodeRef, error) {
+	g, err := s.DependencyGraph(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return g.Orphans(), nil
+}
+
+// DependencyClosure returns everything pkgPath needs, transitively
+func (s *defaultLibraryService) DependencyClosure(ctx context.Context, libraryPath string, pkgPath string) (*graph.Closure, error) {
+	g, err := s.DependencyGraph(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	closure, ok := g.Closure(pkgPath)
+	if !ok {
+		return nil, fmt.Errorf("package not found in library: %s", pkgPath)
+	}
+	return closure, nil
+}
+
+// DependencyCycles lists groups of packages of a library that depend on each other
+func (s *defaultLibraryService) DependencyCycles(ctx context.Context, libraryPath string) ([][]graph.NodeRef, error) {
+	g, err := s.DependencyGraph(ctx, libraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return g.Cycles(), nil
+}
@@ -1,15 +1,18 @@
 package library
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"yavam/pkg/events"
+	"yavam/pkg/utils"
 )
 
-// Install copies a list of files to the target library folder
-func (s *defaultLibraryService) Install(files []string, targetLib string, overwrite bool, onProgress func(current, total int, filename string)) ([]string, error) {
+// Install copies a list of files to the target library folder. Cancelling ctx stops after the
+// current file, whose partial copy is removed; the files copied so far are returned with ctx.Err().
+func (s *defaultLibraryService) Install(ctx context.Context, files []string, targetLib string, overwrite bool, onProgress func(current, total int, filename string)) ([]string, error) {
 	var installed []string
 	var ignored []string
 
@@ -35,6 +38,9 @@ func (s *defaultLibraryService) Install(files []string, targetLib string, overwr
 	totalFiles := len(files)
 
 	for i, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
 		func() {
 			fileName := filepath.Base(f)
 			// Emit Progress at the END of processing this file
@@ -100,10 +106,14 @@ func (s *defaultLibraryService) Install(files []string, targetLib string, overwr
 				return // continue
 			}
 
-			_, err = io.Copy(destFile, srcFile)
+			_, err = utils.CopyContext(ctx, destFile, srcFile)
 			srcFile.Close()
 			destFile.Close()
 
+			if err != nil && ctx.Err() != nil {
+				os.Remove(destPath) // Cancelled, don't leave a truncated package behind
+				return
+			}
 			if err != nil {
 				ignored = append(ignored, fmt.Sprintf("%s (copy error: %v)", fileName, err))
 				return // continue
@@ -113,6 +123,13 @@ func (s *defaultLibraryService) Install(files []string, targetLib string, overwr
 		}()
 	}
 
+	if len(installed) > 0 {
+		s.events.Publish(events.PackagesInstalled{Folder: targetLib, Files: installed})
+	}
+	if err := ctx.Err(); err != nil {
+		return installed, err
+	}
+
 	if len(ignored) > 0 {
 		return installed, fmt.Errorf("the following files were ignored or skipped: %s", strings.Join(ignored, ", "))
 	}
@@ -0,0 +1,61 @@
+package library
+
+import (
+	"context"
+	"fmt"
+	"yavam/pkg/events"
+	"yavam/pkg/models"
+	"yavam/pkg/parser"
+)
+
+// VerifyIntegrity reads every entry of every package in the library and verifies its CRC32.
+// Results are stored in the package index with the rest of the analysis, so they survive restarts
+// and are dropped automatically when a file changes. Packages that fail are marked corrupt.
+// onProgress receives each package after it was checked. Returns the packages that failed.
+func (s *defaultLibraryService) VerifyIntegrity(ctx context.Context, libraryPath string, onProgress func(current, total int, p models.VarPackage)) ([]models.VarPackage, error) {
+	rawPkgs, err := s.scanner.ScanForPackages(libraryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := []models.VarPackage{}
+	total := len(rawPkgs)
+	// Sequential on purpose: every file is read completely, parallel reads only make the disk seek
+	for i, raw := range rawPkgs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		modTime := packageModTime(raw)
+		p, ok := s.lookupIndex(raw, modTime)
+		if !ok {
+			p = s.analyzePackage(raw)
+		}
+
+		result, err := parser.VerifyArchive(ctx, p.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		p.Integrity = &result
+		if result.Status != models.IntegrityOK {
+			p.IsCorrupt = true
+			failed = append(failed, p)
+			fmt.Printf("[Library] Integrity check failed for %s: %s (%s)\n", p.FileName, result.Status, result.Entry)
+		}
+		if s.index != nil {
+			s.index.Put(p.FilePath, p.Size, modTime, p)
+		}
+
+		if onProgress != nil {
+			onProgress(i+1, total, p)
+		}
+	}
+
+	if s.index != nil {
+		if err := s.index.Save(); err != nil {
+			fmt.Printf("[Library] Failed to save package index: %v\n", err)
+		}
+	}
+	s.events.Publish(events.IntegrityChecked{LibraryPath: libraryPath, Failed: failed})
+	return failed, nil
+}
@@ -0,0 +1,82 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/index"
+	"yavam/pkg/models"
+)
+
+func TestVerifyIntegrity_StoresResultWithPackage(t *testing.T) {
+	root := t.TempDir()
+	idx := index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(idx)
+
+	writeVar(t, filepath.Join(root, "Creator.Good.1.var"), map[string]string{
+		"meta.json": `{"creatorName":"Creator","packageName":"Good","version":"1"}`,
+	})
+
+	// Readable central directory, damaged entry data: the normal scan cannot tell
+	payload := bytes.Repeat([]byte("morph-data-"), 500)
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	meta, _ := w.Create("meta.json")
+	meta.Write([]byte(`{"creatorName":"Creator","packageName":"Rotten","version":"1"}`))
+	entry, _ := w.CreateHeader(&zip.FileHeader{Name: "Custom/Atom/Person/Morphs/female/a.vmb", Method: zip.Store})
+	entry.Write(payload)
+	w.Close()
+	data := buf.Bytes()
+	data[bytes.Index(data, payload)+100] ^= 0xFF
+	rottenPath := filepath.Join(root, "Creator.Rotten.1.var")
+	os.WriteFile(rottenPath, data, 0644)
+
+	if pkgs := scanAll(t, lib, root); pkgs["Creator.Rotten.1.var"].IsCorrupt {
+		t.Fatal("Expected the shallow scan to miss the damaged entry")
+	}
+
+	var progress []int
+	failed, err := lib.VerifyIntegrity(context.Background(), root, func(current, total int, p models.VarPackage) {
+		progress = append(progress, current)
+		if total != 2 {
+			t.Errorf("Expected total 2, got %d", total)
+		}
+	})
+	if err != nil {
+		t.Fatalf("VerifyIntegrity failed: %v", err)
+	}
+	if len(progress) != 2 {
+		t.Errorf("Expected 2 progress callbacks, got %v", progress)
+	}
+	if len(failed) != 1 || failed[0].FilePath != rottenPath {
+		t.Fatalf("Expected only the rotten package to fail, got %v", failed)
+	}
+	if failed[0].Integrity.Status != models.IntegrityBadCRC || failed[0].Integrity.Entry != "Custom/Atom/Person/Morphs/female/a.vmb" {
+		t.Errorf("Unexpected result %+v", failed[0].Integrity)
+	}
+
+	// The next scan is served from the index and keeps the verdict
+	pkgs := scanAll(t, lib, root)
+	if p := pkgs["Creator.Rotten.1.var"]; !p.IsCorrupt || p.Integrity == nil {
+		t.Errorf("Expected stored corrupt verdict, got corrupt=%v integrity=%v", p.IsCorrupt, p.Integrity)
+	}
+	if p := pkgs["Creator.Good.1.var"]; p.IsCorrupt || p.Integrity == nil || p.Integrity.Status != models.IntegrityOK {
+		t.Errorf("Expected stored ok verdict, got %+v", p.Integrity)
+	}
+}
+
+func TestVerifyIntegrity_Cancelled(t *testing.T) {
+	root := t.TempDir()
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	writeVar(t, filepath.Join(root, "Creator.Good.1.var"), map[string]string{"meta.json": "{}"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lib.VerifyIntegrity(ctx, root, nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -1,9 +1,13 @@
 package library
 
 import (
+	"yavam/pkg/events"
 	"yavam/pkg/fs"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/index"
 	"yavam/pkg/scanner"
 	"yavam/pkg/services/system"
+	"yavam/pkg/thumbnails"
 )
 
 type defaultLibraryService struct {
@@ -11,9 +15,14 @@ type defaultLibraryService struct {
 	system  system.SystemService
 	fs      fs.FileSystem
 
-	// Caches?
-	// Manager didn't persist cache in memory beyond what was returned?
-	// It returned counts on demand using scanner.
+	// index caches parse results between scans (optional, nil disables caching)
+	index *index.PackageIndex
+	// thumbs stores extracted thumbnails (optional, nil extracts them from the zip on every request)
+	thumbs *thumbnails.Cache
+	// text indexes descriptions, tags and inner file names for full-text search (optional)
+	text *fulltext.Index
+	// events receives every change made to a library (optional, nil publishes nothing)
+	events *events.Bus
 }
 
 func NewLibraryService(sys system.SystemService, fileSystem fs.FileSystem) LibraryService {
@@ -26,3 +35,23 @@ func NewLibraryService(sys system.SystemService, fileSystem fs.FileSystem) Libra
 		fs:      fileSystem,
 	}
 }
+
+// SetIndex enables the persistent package index for subsequent scans
+func (s *defaultLibraryService) SetIndex(idx *index.PackageIndex) {
+	s.index = idx
+}
+
+// SetThumbnailCache enables the on-disk thumbnail cache, populated while scanning
+func (s *defaultLibraryService) SetThumbnailCache(c *thumbnails.Cache) {
+	s.thumbs = c
+}
+
+// SetFullTextIndex enables full-text indexing of packages, populated while scanning
+func (s *defaultLibraryService) SetFullTextIndex(idx *fulltext.Index) {
+	s.text = idx
+}
+
+// SetEventBus publishes every change made through the service (toggle, install, quarantine, ...) on bus
+func (s *defaultLibraryService) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
@@ -1,10 +1,12 @@
 package library
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"yavam/pkg/events"
 	"yavam/pkg/services/system"
 )
 
@@ -56,7 +58,7 @@ func TestInstall(t *testing.T) {
 	files := []string{srcFile}
 
 	// Test Install
-	installed, err := lib.Install(files, destDir, false, nil)
+	installed, err := lib.Install(context.Background(), files, destDir, false, nil)
 	if err != nil {
 		t.Fatalf("Install failed: %v", err)
 	}
@@ -88,7 +90,7 @@ func TestInstall_Collision(t *testing.T) {
 	files := []string{srcFile}
 
 	// Test Install with overwrite=false
-	installed, err := lib.Install(files, destDir, false, nil)
+	installed, err := lib.Install(context.Background(), files, destDir, false, nil)
 	// Install returns error if ignored/skipped?
 	// Yes: "the following files were ignored..."
 	if err == nil {
@@ -99,7 +101,7 @@ func TestInstall_Collision(t *testing.T) {
 	}
 
 	// Test Install with overwrite=true
-	installed, err = lib.Install(files, destDir, true, nil)
+	installed, err = lib.Install(context.Background(), files, destDir, true, nil)
 	if err != nil {
 		t.Errorf("Install with overwrite=true failed: %v", err)
 	}
@@ -147,6 +149,33 @@ func TestToggle(t *testing.T) {
 	}
 }
 
+func TestToggle_PublishesEvent(t *testing.T) {
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	bus := events.NewBus()
+	lib.SetEventBus(bus)
+
+	var published []events.Event
+	bus.Subscribe(func(e events.Event) { published = append(published, e) })
+
+	pkgPath := filepath.Join(t.TempDir(), "package.var")
+	os.WriteFile(pkgPath, []byte("data"), 0644)
+
+	newPath, err := lib.Toggle(pkgPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Already disabled: nothing changes, nothing is published
+	lib.Toggle(newPath, false)
+
+	if len(published) != 1 {
+		t.Fatalf("Expected one event, got %v", published)
+	}
+	e, ok := published[0].(events.PackageToggled)
+	if !ok || e.Path != pkgPath || e.NewPath != newPath || e.Enabled {
+		t.Errorf("Unexpected event %#v", published[0])
+	}
+}
+
 func TestCheckCollisions(t *testing.T) {
 	mockSys := &MockSystemService{}
 	lib := NewLibraryService(mockSys, nil)
@@ -198,7 +227,7 @@ func TestInstall_Disabled(t *testing.T) {
 	files := []string{srcFile}
 
 	// Test Install
-	installed, err := lib.Install(files, destDir, false, nil)
+	installed, err := lib.Install(context.Background(), files, destDir, false, nil)
 	if err != nil {
 		t.Fatalf("Install failed: %v", err)
 	}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"yavam/pkg/events"
 )
 
 // Toggle renames a package between .var and .var.disabled
@@ -60,6 +61,15 @@ func (s *defaultLibraryService) Toggle(pkgPath string, enable bool) (string, err
 		return "", err
 	}
 
+	// A rename keeps size and mtime, so carry the index entry over instead of re-parsing
+	if s.index != nil {
+		s.index.Move(sourcePath, destPath)
+	}
+	if s.text != nil {
+		s.text.Move(sourcePath, destPath)
+	}
+	s.events.Publish(events.PackageToggled{Path: sourcePath, NewPath: destPath, Enabled: enable})
+
 	return destPath, nil
 }
 
@@ -1,6 +1,7 @@
 package library
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -47,7 +48,7 @@ func TestInstall_PermissionDenied(t *testing.T) {
 	files := []string{srcFile}
 
 	// Test
-	installed, err := lib.Install(files, destDir, true, nil)
+	installed, err := lib.Install(context.Background(), files, destDir, true, nil)
 
 	// If it succeeds, Chmod didn't prevent write (common on Windows for Admin)
 	if err == nil {
@@ -0,0 +1,164 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"yavam/pkg/events"
+	"yavam/pkg/models"
+)
+
+// QuarantineDir is the folder inside each library that holds quarantined packages.
+// Files in it carry the .quarantined extension, so neither VaM nor the scanner load them.
+const QuarantineDir = ".yavam_quarantine"
+
+const (
+	quarantineExt = ".quarantined"
+	sidecarExt    = ".json"
+)
+
+// Quarantine moves a package into the quarantine folder of its library and records why in a JSON sidecar.
+// The move is a rename within the library, so it is cheap and never crosses drives.
+func (s *defaultLibraryService) Quarantine(pkgPath string, libraryPath string, reason string) (*models.QuarantineEntry, error) {
+	info, err := os.Stat(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("not a package file: %s", pkgPath)
+	}
+
+	dir := filepath.Join(libraryPath, QuarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entry := &models.QuarantineEntry{
+		FileName:      filepath.Base(pkgPath),
+		OriginalPath:  pkgPath,
+		LibraryPath:   libraryPath,
+		Reason:        reason,
+		Size:          info.Size(),
+		QuarantinedAt: time.Now().Format(time.RFC3339),
+	}
+	// Reuse what the scan already knows about the file (hash, integrity verdict)
+	if p, err := s.GetPackage(pkgPath); err == nil {
+		entry.Hash = p.Hash
+		entry.Integrity = p.Integrity
+	}
+
+	// Timestamp prefix keeps repeated quarantines of the same file name apart
+	base := time.Now().Format("20060102_150405") + "_" + entry.FileName
+	entry.ID = base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, entry.ID+quarantineExt)); os.IsNotExist(err) {
+			break
+		}
+		entry.ID = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	// The sidecar is written first so a quarantined file never lacks its record
+	sidecar := filepath.Join(dir, entry.ID+sidecarExt)
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(sidecar, data, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(pkgPath, filepath.Join(dir, entry.ID+quarantineExt)); err != nil {
+		os.Remove(sidecar)
+		return nil, err
+	}
+
+	if s.index != nil {
+		s.index.Invalidate(pkgPath)
+	}
+	if s.text != nil {
+		s.text.Remove(pkgPath)
+	}
+	fmt.Printf("[Library] Quarantined %s: %s\n", entry.FileName, reason)
+	s.events.Publish(events.PackageQuarantined{Entry: *entry})
+	return entry, nil
+}
+
+// Restore moves a quarantined package back to its original path and removes the sidecar.
+// Fails if another file took the original path in the meantime.
+func (s *defaultLibraryService) Restore(libraryPath string, id string) (string, error) {
+	// IDs are plain file names, anything else could escape the quarantine folder
+	if id == "" || id != filepath.Base(id) || strings.Contains(id, "..") {
+		return "", fmt.Errorf("invalid quarantine id: %s", id)
+	}
+	dir := filepath.Join(libraryPath, QuarantineDir)
+	sidecar := filepath.Join(dir, id+sidecarExt)
+
+	entry, err := readSidecar(sidecar)
+	if err != nil {
+		return "", err
+	}
+
+	// Only restore into the library the file was quarantined from
+	target := filepath.Clean(entry.OriginalPath)
+	rel, err := filepath.Rel(libraryPath, target)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("original path %s is outside the library", entry.OriginalPath)
+	}
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("cannot restore: %s already exists", filepath.Base(target))
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(filepath.Join(dir, id+quarantineExt), target); err != nil {
+		return "", err
+	}
+	if err := os.Remove(sidecar); err != nil {
+		fmt.Printf("[Library] Failed to remove quarantine record %s: %v\n", sidecar, err)
+	}
+	fmt.Printf("[Library] Restored %s\n", entry.FileName)
+	s.events.Publish(events.PackageRestored{LibraryPath: libraryPath, ID: id, Path: target})
+	return target, nil
+}
+
+// ListQuarantine returns the quarantined packages of a library, newest first
+func (s *defaultLibraryService) ListQuarantine(libraryPath string) ([]models.QuarantineEntry, error) {
+	entries := []models.QuarantineEntry{}
+	files, err := os.ReadDir(filepath.Join(libraryPath, QuarantineDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), sidecarExt) {
+			continue
+		}
+		entry, err := readSidecar(filepath.Join(libraryPath, QuarantineDir, f.Name()))
+		if err != nil {
+			fmt.Printf("[Library] Skipping unreadable quarantine record %s: %v\n", f.Name(), err)
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].QuarantinedAt > entries[j].QuarantinedAt })
+	return entries, nil
+}
+
+func readSidecar(path string) (*models.QuarantineEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry models.QuarantineEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
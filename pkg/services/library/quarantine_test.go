@@ -0,0 +1,59 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuarantineAndRestore(t *testing.T) {
+	root := t.TempDir()
+	lib := NewLibraryService(&MockSystemService{}, nil)
+
+	pkgPath := filepath.Join(root, "Creator.Broken.1.var.disabled")
+	writeVar(t, pkgPath, map[string]string{"meta.json": `{"creatorName":"Creator","packageName":"Broken"}`})
+	writeVar(t, filepath.Join(root, "Creator.Fine.1.var"), map[string]string{"meta.json": "{}"})
+
+	entry, err := lib.Quarantine(pkgPath, root, "manual")
+	if err != nil {
+		t.Fatalf("Quarantine failed: %v", err)
+	}
+	if _, err := os.Stat(pkgPath); !os.IsNotExist(err) {
+		t.Error("Expected the package to leave the library")
+	}
+	if entry.OriginalPath != pkgPath || entry.Reason != "manual" || entry.Hash == "" {
+		t.Errorf("Unexpected record %+v", entry)
+	}
+
+	// Quarantined files are invisible to scans
+	if pkgs := scanAll(t, lib, root); len(pkgs) != 1 {
+		t.Errorf("Expected only the healthy package to be scanned, got %d", len(pkgs))
+	}
+
+	list, err := lib.ListQuarantine(root)
+	if err != nil || len(list) != 1 || list[0].ID != entry.ID {
+		t.Fatalf("Expected the record in the listing, got %v (%v)", list, err)
+	}
+
+	// Restore refuses to overwrite a file that took the original place
+	os.WriteFile(pkgPath, []byte("new download"), 0644)
+	if _, err := lib.Restore(root, entry.ID); err == nil {
+		t.Error("Expected restore to refuse overwriting")
+	}
+	os.Remove(pkgPath)
+
+	restored, err := lib.Restore(root, entry.ID)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored != pkgPath {
+		t.Errorf("Expected restore to %s, got %s", pkgPath, restored)
+	}
+	if list, _ := lib.ListQuarantine(root); len(list) != 0 {
+		t.Errorf("Expected the record to be removed, got %v", list)
+	}
+
+	if _, err := lib.Restore(root, "../../etc"); err == nil {
+		t.Error("Expected ids with path elements to be rejected")
+	}
+}
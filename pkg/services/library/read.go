@@ -5,10 +5,13 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"yavam/pkg/models"
+	"yavam/pkg/parser"
+	"yavam/pkg/thumbnails"
 )
 
 // GetPackageContents scans a .var file and returns a list of its displayable contents
@@ -46,39 +49,7 @@ func (s *defaultLibraryService) GetPackageContents(pkgPath string) ([]models.Pac
 		}
 
 		lowerName := strings.ToLower(f.Name)
-		contentType := ""
-
-		// Scenes
-		if strings.Contains(lowerName, "saves/scene/") && strings.HasSuffix(lowerName, ".json") {
-			contentType = "Scene"
-		} else if strings.Contains(lowerName, "saves/person/appearance/") && strings.HasSuffix(lowerName, ".vap") {
-			contentType = "Look"
-		} else if strings.Contains(lowerName, "custom/clothing/") && strings.HasSuffix(lowerName, ".vap") {
-			contentType = "Clothing"
-		} else if strings.Contains(lowerName, "custom/hair/") && strings.HasSuffix(lowerName, ".vap") {
-			contentType = "Hair"
-		} else if strings.Contains(lowerName, "custom/atom/person/morphs/") && (strings.HasSuffix(lowerName, ".vmi") || strings.HasSuffix(lowerName, ".vmb")) {
-			contentType = "Morph"
-		} else if strings.Contains(lowerName, "custom/assets/") && strings.HasSuffix(lowerName, ".assetbundle") {
-			// Only show asset bundles, not every texture
-			contentType = "Asset"
-		} else if strings.Contains(lowerName, "custom/") && strings.HasSuffix(lowerName, ".vap") {
-			// Generic VAP in custom folder (Shoes, etc)
-			// Try to infer type from folder name
-			parts := strings.Split(lowerName, "/")
-			for i, p := range parts {
-				if p == "custom" && i+1 < len(parts) {
-					cat := parts[i+1]
-					if cat != "clothing" && cat != "hair" && cat != "assets" && cat != "atom" {
-						contentType = titleCase(cat)
-					}
-					break
-				}
-			}
-			if contentType == "" {
-				contentType = "Preset"
-			}
-		}
+		contentType := parser.ContentType(lowerName)
 
 		if contentType != "" {
 			pc := models.PackageContent{
@@ -128,13 +99,57 @@ func (s *defaultLibraryService) GetPackageContents(pkgPath string) ([]models.Pac
 	return contents, nil
 }
 
+// GetPackageDetails returns the contents of a package together with an analysis of the
+// package references found in its content JSON (undeclared and unused dependencies)
+func (s *defaultLibraryService) GetPackageDetails(pkgPath string) (*models.PackageDetails, error) {
+	contents, err := s.GetPackageContents(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := parser.AnalyzeReferences(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if contents == nil {
+		contents = []models.PackageContent{}
+	}
+	return &models.PackageDetails{Contents: contents, References: refs}, nil
+}
+
+// GetThumbnail returns the original thumbnail image of a package
 func (s *defaultLibraryService) GetThumbnail(pkgPath string) ([]byte, error) {
-	// Not implemented in Manager originally?
-	// It was embedded in `ScanAndAnalyze` or `GetPackageContents`.
-	// For API, we might want it separate.
-	// Implementing basic extraction of "thumb.jpg" or similar if needed.
-	// For now, returning error/empty conforming to interface.
-	return nil, fmt.Errorf("not implemented")
+	if s.thumbs == nil {
+		_, thumbBytes, _, err := parser.ParseVarMetadata(pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(thumbBytes) == 0 {
+			return nil, fmt.Errorf("package has no thumbnail")
+		}
+		return thumbBytes, nil
+	}
+	path, err := s.GetThumbnailFile(pkgPath, thumbnails.SizeFull)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// GetThumbnailFile returns the cached thumbnail variant closest to size (thumbnails.SizeFull for the original),
+// extracting it first if the package was not cached yet
+func (s *defaultLibraryService) GetThumbnailFile(pkgPath string, size int) (string, error) {
+	if s.thumbs == nil {
+		return "", fmt.Errorf("thumbnail cache not configured")
+	}
+	// GetPackage re-analyzes (and caches the thumbnail) unless both the index and the cache are current
+	p, err := s.GetPackage(pkgPath)
+	if err != nil {
+		return "", err
+	}
+	if !p.HasThumbnail {
+		return "", fmt.Errorf("package has no thumbnail")
+	}
+	return s.thumbs.Path(thumbnails.Key(p), size)
 }
 
 func (s *defaultLibraryService) GetCounts(libraries []string) map[string]int {
@@ -148,10 +163,3 @@ func (s *defaultLibraryService) GetCounts(libraries []string) map[string]int {
 	}
 	return results
 }
-
-func titleCase(s string) string {
-	if len(s) == 0 {
-		return ""
-	}
-	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
-}
@@ -2,11 +2,20 @@ package library
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"time"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/index"
 	"yavam/pkg/models"
 	"yavam/pkg/parser"
+	"yavam/pkg/scanner"
+	"yavam/pkg/thumbnails"
+	"yavam/pkg/utils"
 )
 
 // Scan scans the directory and streams results via callbacks
@@ -63,39 +72,26 @@ func (s *defaultLibraryService) Scan(ctx context.Context, rootPath string, onPac
 			default:
 			}
 
-			meta, thumbBytes, categories, err := parser.ParseVarMetadata(p.FilePath)
-			if err == nil {
-				p.Meta = meta
-
-				// Sort Categories for stability and primary type selection
-				sortCategories(categories)
-				p.Categories = categories
-				if len(categories) > 0 {
-					p.Type = categories[0]
-				} else {
-					p.Type = "Unknown"
+			modTime := packageModTime(p)
+			if cached, ok := s.lookupIndex(p, modTime); ok {
+				p = cached
+				// Cached packages only need text indexing once (e.g. after the index file was deleted)
+				if s.text != nil && !s.text.Has(p.FilePath) {
+					s.indexText(p)
 				}
-
-				p.Tags = meta.Tags
-				p.HasThumbnail = len(thumbBytes) > 0
 			} else {
-				p.Type = "Unknown"
-				p.IsCorrupt = true
+				p = s.analyzePackage(p)
+				if s.index != nil {
+					s.index.Put(p.FilePath, p.Size, modTime, p)
+				}
+				s.indexText(p)
 			}
 
-			// Fix empty fields from filename
-			ensureMetaFromFilename(&p)
-
-			// Normalize Tags
-			var normalizedTags []string
 			tagMu.Lock()
 			for _, t := range p.Tags {
-				lowerT := strings.ToLower(t)
-				normalizedTags = append(normalizedTags, lowerT)
-				tagSet[lowerT] = true
+				tagSet[t] = true
 			}
 			tagMu.Unlock()
-			p.Tags = normalizedTags
 
 			// TODO: Status Logic (Duplicate, Obsolete) - Requires global view?
 			// Manager.resolveDuplicates processed the WHOLE list *after* scan in GetPackages?
@@ -138,9 +134,150 @@ func (s *defaultLibraryService) Scan(ctx context.Context, rootPath string, onPac
 	}
 
 	wg.Wait()
+
+	// Only prune after a complete walk, a cancelled scan has not seen every file
+	var seen map[string]bool
+	if ctx.Err() == nil {
+		seen = make(map[string]bool, len(rawPkgs))
+		for _, p := range rawPkgs {
+			seen[index.Key(p.FilePath)] = true
+		}
+	}
+	if s.index != nil {
+		if seen != nil {
+			s.index.Prune(rootPath, seen)
+		}
+		if err := s.index.Save(); err != nil {
+			fmt.Printf("[Library] Failed to save package index: %v\n", err)
+		}
+	}
+	if s.text != nil {
+		if seen != nil {
+			s.text.Prune(rootPath, seen)
+		}
+		if err := s.text.Save(); err != nil {
+			fmt.Printf("[Library] Failed to save full-text index: %v\n", err)
+		}
+	}
 	return nil
 }
 
+// GetPackage analyzes a single package file, using the index when the file is unchanged
+func (s *defaultLibraryService) GetPackage(pkgPath string) (models.VarPackage, error) {
+	info, err := os.Stat(pkgPath)
+	if err != nil {
+		return models.VarPackage{}, err
+	}
+	p, ok := scanner.PackageFromFileInfo(pkgPath, info)
+	if !ok {
+		return models.VarPackage{}, fmt.Errorf("not a package file: %s", filepath.Base(pkgPath))
+	}
+
+	modTime := packageModTime(p)
+	if cached, ok := s.lookupIndex(p, modTime); ok {
+		return cached, nil
+	}
+	p = s.analyzePackage(p)
+	if s.index != nil {
+		s.index.Put(p.FilePath, p.Size, modTime, p)
+	}
+	// Scans only re-index text on index misses, and this one was a miss
+	s.indexText(p)
+	return p, nil
+}
+
+// lookupIndex returns the cached analysis of p if the file is unchanged since it was indexed.
+// Fields that come from the directory walk (path, enabled state, dates) are always taken from p.
+func (s *defaultLibraryService) lookupIndex(p models.VarPackage, modTime time.Time) (models.VarPackage, bool) {
+	if s.index == nil {
+		return p, false
+	}
+	cached, ok := s.index.Lookup(p.FilePath, p.Size, modTime)
+	if !ok {
+		return p, false
+	}
+	// A cleared thumbnail cache means the package has to be opened again anyway
+	if cached.HasThumbnail && s.thumbs != nil && !s.thumbs.Has(thumbnails.Key(cached)) {
+		return p, false
+	}
+	cached.FilePath = p.FilePath
+	cached.FileName = p.FileName
+	cached.Size = p.Size
+	cached.IsEnabled = p.IsEnabled
+	cached.CreationDate = p.CreationDate
+	cached.ModTime = p.ModTime
+	return cached, true
+}
+
+// analyzePackage hashes and opens the .var and fills in metadata, categories and tags.
+// The thumbnail is written to the thumbnail cache (with its resized variants) when one is configured.
+func (s *defaultLibraryService) analyzePackage(p models.VarPackage) models.VarPackage {
+	// Hashing reads the whole file, which is why analysis results are cached in the index
+	if hash, err := utils.HashFile(p.FilePath); err == nil {
+		p.Hash = hash
+	} else {
+		fmt.Printf("[Library] Failed to hash %s: %v\n", p.FileName, err)
+	}
+
+	meta, thumbBytes, categories, err := parser.ParseVarMetadata(p.FilePath)
+	if err == nil {
+		p.Meta = meta
+
+		// Sort Categories for stability and primary type selection
+		sortCategories(categories)
+		p.Categories = categories
+		if len(categories) > 0 {
+			p.Type = categories[0]
+		} else {
+			p.Type = "Unknown"
+		}
+
+		p.Tags = meta.Tags
+		p.HasThumbnail = len(thumbBytes) > 0
+		if p.HasThumbnail && s.thumbs != nil {
+			key := thumbnails.Key(p)
+			if err := s.thumbs.Store(key, thumbBytes); err != nil {
+				fmt.Printf("[Library] Failed to cache thumbnail for %s: %v\n", p.FileName, err)
+			} else {
+				p.ThumbnailPath, _ = s.thumbs.Path(key, thumbnails.DefaultSize)
+			}
+		}
+	} else {
+		p.Type = "Unknown"
+		p.IsCorrupt = true
+	}
+
+	// Fix empty fields from filename
+	ensureMetaFromFilename(&p)
+
+	// Normalize Tags
+	var normalizedTags []string
+	for _, t := range p.Tags {
+		normalizedTags = append(normalizedTags, strings.ToLower(t))
+	}
+	p.Tags = normalizedTags
+	return p
+}
+
+// indexText adds the package and its content items to the full-text index.
+// Unreadable archives are still indexed by their metadata so they remain findable.
+func (s *defaultLibraryService) indexText(p models.VarPackage) {
+	if s.text == nil {
+		return
+	}
+	items, err := parser.ContentItems(p.FilePath)
+	if err != nil && !p.IsCorrupt {
+		fmt.Printf("[Library] Failed to list contents of %s: %v\n", p.FileName, err)
+	}
+	s.text.Put(p.FilePath, fulltext.Documents(p, items))
+}
+
+// packageModTime is the modification time recorded by the scanner, at full file system precision.
+// CreationDate only keeps seconds, which misses a file rewritten within the same second.
+func packageModTime(p models.VarPackage) time.Time {
+	return time.Unix(0, p.ModTime)
+}
+
 func sortCategories(categories []string) {
 	sort.Slice(categories, func(i, j int) bool {
 		prio := func(s string) int {
@@ -0,0 +1,287 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/index"
+	"yavam/pkg/models"
+	"yavam/pkg/thumbnails"
+)
+
+// writeVar creates a minimal .var archive with the given files
+func writeVar(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	w.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func scanAll(t *testing.T, lib LibraryService, root string) map[string]models.VarPackage {
+	t.Helper()
+	result := make(map[string]models.VarPackage)
+	err := lib.Scan(context.Background(), root, func(p models.VarPackage) {
+		result[p.FileName] = p
+	}, nil)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	return result
+}
+
+func TestScan_UsesIndexForUnchangedFiles(t *testing.T) {
+	root := t.TempDir()
+	idx := index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(idx)
+
+	pkgPath := filepath.Join(root, "Creator.Hair.1.var")
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json":                     `{"creatorName":"Creator","packageName":"Hair","version":"1"}`,
+		"Custom/Hair/Female/Braid.vam":  "{}",
+		"Custom/Hair/Female/Braid.vaj":  "{}",
+		"Custom/Hair/Female/Braid2.vam": "{}",
+	})
+
+	// 1. Add: first scan parses the file
+	pkgs := scanAll(t, lib, root)
+	if pkgs["Creator.Hair.1.var"].Type != "Hair" {
+		t.Fatalf("Expected Hair, got %q", pkgs["Creator.Hair.1.var"].Type)
+	}
+	if idx.Len() != 1 {
+		t.Fatalf("Expected 1 indexed package, got %d", idx.Len())
+	}
+	hash := pkgs["Creator.Hair.1.var"].Hash
+	if len(hash) != 64 {
+		t.Fatalf("Expected SHA-256 hash, got %q", hash)
+	}
+
+	// Corrupt the file but keep size and mtime: an unchanged file must not be re-opened
+	info, _ := os.Stat(pkgPath)
+	garbage := bytes.Repeat([]byte{0}, int(info.Size()))
+	os.WriteFile(pkgPath, garbage, 0644)
+	os.Chtimes(pkgPath, info.ModTime(), info.ModTime())
+
+	pkgs = scanAll(t, lib, root)
+	if p := pkgs["Creator.Hair.1.var"]; p.IsCorrupt || p.Type != "Hair" {
+		t.Errorf("Expected cached result, got corrupt=%v type=%q", p.IsCorrupt, p.Type)
+	}
+	if pkgs["Creator.Hair.1.var"].Hash != hash {
+		t.Error("Expected cached hash for unchanged file")
+	}
+
+	// 2. Modify: a new mtime forces a re-parse
+	later := info.ModTime().Add(time.Minute)
+	os.Chtimes(pkgPath, later, later)
+
+	pkgs = scanAll(t, lib, root)
+	if !pkgs["Creator.Hair.1.var"].IsCorrupt {
+		t.Error("Expected modified file to be re-parsed (and flagged corrupt)")
+	}
+	if pkgs["Creator.Hair.1.var"].Hash == hash {
+		t.Error("Expected modified file to be re-hashed")
+	}
+}
+
+func TestScan_DetectsRewriteWithinSameSecond(t *testing.T) {
+	root := t.TempDir()
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+
+	pkgPath := filepath.Join(root, "Creator.Hair.1.var")
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json": `{"creatorName":"Creator","packageName":"Hair","version":"1"}`,
+	})
+	first := time.Now().Truncate(time.Second).Add(100 * time.Millisecond)
+	os.Chtimes(pkgPath, first, first)
+	if p := scanAll(t, lib, root)["Creator.Hair.1.var"]; p.IsCorrupt {
+		t.Fatal("Expected a valid package")
+	}
+
+	// Same size, and the new mtime only differs below one second
+	info, _ := os.Stat(pkgPath)
+	os.WriteFile(pkgPath, bytes.Repeat([]byte{0}, int(info.Size())), 0644)
+	second := first.Add(400 * time.Millisecond)
+	os.Chtimes(pkgPath, second, second)
+
+	if p := scanAll(t, lib, root)["Creator.Hair.1.var"]; !p.IsCorrupt {
+		t.Error("Expected the rewritten file to be re-parsed (and flagged corrupt)")
+	}
+}
+
+func TestScan_IndexFollowsRenameAndDelete(t *testing.T) {
+	root := t.TempDir()
+	idx := index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json"))
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(idx)
+
+	keepPath := filepath.Join(root, "Creator.Look.1.var")
+	dropPath := filepath.Join(root, "Creator.Scene.1.var")
+	writeVar(t, keepPath, map[string]string{
+		"meta.json":                        `{"creatorName":"Creator","packageName":"Look","version":"1"}`,
+		"Saves/Person/Appearance/Look.vap": "{}",
+	})
+	writeVar(t, dropPath, map[string]string{
+		"meta.json":             `{"creatorName":"Creator","packageName":"Scene","version":"1"}`,
+		"Saves/scene/Test.json": "{}",
+	})
+	scanAll(t, lib, root)
+
+	// 3. Rename: toggling carries the entry over
+	disabledPath, err := lib.Toggle(keepPath, false)
+	if err != nil {
+		t.Fatalf("Toggle failed: %v", err)
+	}
+	info, _ := os.Stat(disabledPath)
+	cached, ok := idx.Lookup(disabledPath, info.Size(), info.ModTime())
+	if !ok || cached.Type != "Look" {
+		t.Errorf("Expected index entry under renamed path, ok=%v type=%q", ok, cached.Type)
+	}
+
+	// 4. Delete: removed files are pruned on the next scan
+	os.Remove(dropPath)
+	pkgs := scanAll(t, lib, root)
+	if len(pkgs) != 1 {
+		t.Fatalf("Expected 1 package after delete, got %d", len(pkgs))
+	}
+	if p := pkgs[filepath.Base(disabledPath)]; p.IsEnabled || p.Type != "Look" {
+		t.Errorf("Unexpected state for renamed package: enabled=%v type=%q", p.IsEnabled, p.Type)
+	}
+	if idx.Len() != 1 {
+		t.Errorf("Expected deleted package to be pruned, index has %d entries", idx.Len())
+	}
+}
+
+func TestScan_PopulatesThumbnailCache(t *testing.T) {
+	root := t.TempDir()
+	thumbs := thumbnails.NewCache(t.TempDir())
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	lib.SetThumbnailCache(thumbs)
+
+	img := image.NewRGBA(image.Rect(0, 0, 640, 640))
+	var thumb bytes.Buffer
+	jpeg.Encode(&thumb, img, nil)
+
+	pkgPath := filepath.Join(root, "Creator.Look.1.var")
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json":                        `{"creatorName":"Creator","packageName":"Look","version":"1"}`,
+		"Saves/Person/Appearance/Look.vap": "{}",
+		"Saves/Person/Appearance/Look.jpg": thumb.String(),
+	})
+
+	p := scanAll(t, lib, root)["Creator.Look.1.var"]
+	if !p.HasThumbnail || p.ThumbnailPath == "" {
+		t.Fatalf("Expected cached thumbnail, got has=%v path=%q", p.HasThumbnail, p.ThumbnailPath)
+	}
+	if _, err := os.Stat(p.ThumbnailPath); err != nil {
+		t.Errorf("ThumbnailPath does not exist: %v", err)
+	}
+
+	small, err := lib.GetThumbnailFile(pkgPath, 160)
+	if err != nil {
+		t.Fatalf("GetThumbnailFile failed: %v", err)
+	}
+	if small == p.ThumbnailPath {
+		t.Error("Expected a smaller variant than the default")
+	}
+
+	full, err := lib.GetThumbnail(pkgPath)
+	if err != nil || !bytes.Equal(full, thumb.Bytes()) {
+		t.Errorf("Expected original thumbnail bytes, err=%v", err)
+	}
+}
+
+func TestScan_BuildsFullTextIndex(t *testing.T) {
+	root := t.TempDir()
+	text := fulltext.NewIndex(filepath.Join(t.TempDir(), "fulltext.json"))
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	lib.SetFullTextIndex(text)
+
+	pkgPath := filepath.Join(root, "Creator.Wardrobe.1.var")
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json": `{"creatorName":"Creator","packageName":"Wardrobe","version":"1","description":"Summer outfits"}`,
+		"Custom/Clothing/Female/Creator/SilkKimono/SilkKimono.vam": `{"tags":"robe, silk"}`,
+		"Custom/Clothing/Female/Creator/SilkKimono/SilkKimono.vaj": "{}",
+	})
+	scanAll(t, lib, root)
+
+	hits := text.Search("kimono", 0, nil)
+	if len(hits) != 1 || hits[0].ItemPath != "Custom/Clothing/Female/Creator/SilkKimono/SilkKimono.vam" || hits[0].ItemType != "Clothing" {
+		t.Fatalf("Expected the kimono item, got %+v", hits)
+	}
+	foundItem := false
+	for _, h := range text.Search("robe", 0, nil) {
+		foundItem = foundItem || h.ItemName == "SilkKimono"
+	}
+	if !foundItem {
+		t.Error("Expected .vam tags to be indexed on the item")
+	}
+	if hits := text.Search("summer", 0, nil); len(hits) != 1 || hits[0].ItemPath != "" {
+		t.Errorf("Expected the description to match the package, got %+v", hits)
+	}
+
+	// Toggling keeps the documents, deleting prunes them on the next scan
+	disabled, err := lib.Toggle(pkgPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := text.Search("kimono", 0, nil); len(hits) != 1 || hits[0].PackagePath != disabled {
+		t.Errorf("Expected hit to follow the rename, got %+v", hits)
+	}
+	os.Remove(disabled)
+	scanAll(t, lib, root)
+	if text.Len() != 0 {
+		t.Errorf("Expected deleted package to be pruned, %d left", text.Len())
+	}
+}
+
+func TestGetPackage_UpdatesFullTextIndex(t *testing.T) {
+	root := t.TempDir()
+	text := fulltext.NewIndex(filepath.Join(t.TempDir(), "fulltext.json"))
+	lib := NewLibraryService(&MockSystemService{}, nil)
+	lib.SetIndex(index.NewPackageIndex(filepath.Join(t.TempDir(), "index.json")))
+	lib.SetFullTextIndex(text)
+
+	pkgPath := filepath.Join(root, "Creator.Wardrobe.1.var")
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json": `{"creatorName":"Creator","packageName":"Wardrobe","version":"1","description":"Summer outfits"}`,
+	})
+	scanAll(t, lib, root)
+
+	// Changed on disk and refreshed through GetPackage (watcher), then scanned again
+	writeVar(t, pkgPath, map[string]string{
+		"meta.json": `{"creatorName":"Creator","packageName":"Wardrobe","version":"1","description":"Winter coats"}`,
+	})
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(pkgPath, later, later)
+	if _, err := lib.GetPackage(pkgPath); err != nil {
+		t.Fatal(err)
+	}
+	scanAll(t, lib, root)
+
+	if hits := text.Search("winter", 0, nil); len(hits) != 1 {
+		t.Errorf("Expected the new description to be indexed, got %+v", hits)
+	}
+	if hits := text.Search("summer", 0, nil); len(hits) != 0 {
+		t.Errorf("Expected the old description to be gone, got %+v", hits)
+	}
+}
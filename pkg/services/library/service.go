@@ -2,7 +2,12 @@ package library
 
 import (
 	"context"
+	"yavam/pkg/events"
+	"yavam/pkg/fulltext"
+	"yavam/pkg/graph"
+	"yavam/pkg/index"
 	"yavam/pkg/models"
+	"yavam/pkg/thumbnails"
 )
 
 // LibraryService defines the core operations for VAM Package Management
@@ -10,13 +15,33 @@ type LibraryService interface {
 	// Indexing & Read Operations
 	Scan(ctx context.Context, libraryPath string, onPackage func(models.VarPackage), onProgress func(int, int)) error
 	GetCounts(libraries []string) map[string]int
+	GetPackage(pkgPath string) (models.VarPackage, error)
 	GetPackageContents(pkgPath string) ([]models.PackageContent, error)
+	GetPackageDetails(pkgPath string) (*models.PackageDetails, error)
 	GetThumbnail(pkgPath string) ([]byte, error)
+	GetThumbnailFile(pkgPath string, size int) (string, error)
+	SetIndex(idx *index.PackageIndex)
+	SetThumbnailCache(c *thumbnails.Cache)
+	SetFullTextIndex(idx *fulltext.Index)
+	SetEventBus(bus *events.Bus)
 
-	Install(files []string, targetLib string, overwrite bool, onProgress func(int, int, string)) ([]string, error)
+	Install(ctx context.Context, files []string, targetLib string, overwrite bool, onProgress func(int, int, string)) ([]string, error)
 	CheckCollisions(filePaths []string, destLibPath string) ([]string, error)
 	CheckDependencies(pkgs []models.VarPackage) []models.VarPackage
 	Toggle(pkgPath string, enable bool) (string, error)
 	DisableOldVersions(creator string, pkgName string, libraryPath string) error
 	ResolveConflicts(keepPath string, others []string, libraryPath string) (*models.ResolveConflictResult, error)
+	FindDuplicates(ctx context.Context, libraries []string) ([]models.DuplicateGroup, error)
+	Quarantine(pkgPath string, libraryPath string, reason string) (*models.QuarantineEntry, error)
+	Restore(libraryPath string, id string) (string, error)
+	ListQuarantine(libraryPath string) ([]models.QuarantineEntry, error)
+	VerifyIntegrity(ctx context.Context, libraryPath string, onProgress func(current, total int, p models.VarPackage)) ([]models.VarPackage, error)
+
+	// Dependency Graph
+	DependencyGraph(ctx context.Context, libraryPath string) (*graph.Graph, error)
+	UsedBy(ctx context.Context, libraryPath string, pkgPath string) ([]graph.NodeRef, error)
+	GraphRoots(ctx context.Context, libraryPath string) ([]graph.NodeRef, error)
+	GraphOrphans(ctx context.Context, libraryPath string) ([]graph.NodeRef, error)
+	DependencyClosure(ctx context.Context, libraryPath string, pkgPath string) (*graph.Closure, error)
+	DependencyCycles(ctx context.Context, libraryPath string) ([][]graph.NodeRef, error)
 }
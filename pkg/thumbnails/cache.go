@@ -0,0 +1,205 @@
+package thumbnails
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Registered for image.Decode
+	"image/jpeg"
+	_ "image/png" // Registered for image.Decode
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"yavam/pkg/models"
+)
+
+// SizeFull requests the original image as stored in the package
+const SizeFull = 0
+
+// Sizes are the pre-resized variants (longest edge in pixels) generated for every thumbnail
+var Sizes = []int{160, 320}
+
+// DefaultSize is the variant recorded in VarPackage.ThumbnailPath
+const DefaultSize = 320
+
+// Cache stores extracted thumbnails and their resized variants on disk.
+// Entries are keyed by package identity, so renaming or toggling a package keeps its thumbnails.
+type Cache struct {
+	dir string
+}
+
+// NewCache creates a cache rooted at dir (created lazily on first store)
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Key identifies the content of a package, by its content hash when known.
+// Otherwise name and size are used; the enabled state is ignored because toggling only renames the file.
+func Key(p models.VarPackage) string {
+	if len(p.Hash) >= 32 {
+		return p.Hash[:32]
+	}
+	name := strings.ToLower(strings.TrimSuffix(p.FileName, ".disabled"))
+	sum := sha256.Sum256([]byte(name + "|" + strconv.FormatInt(p.Size, 10)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ParseSize converts a "size" query value ("160", "320", "full" or empty) to a pixel size
+func ParseSize(s string) (int, error) {
+	if s == "" || s == "full" {
+		return SizeFull, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid thumbnail size: %s", s)
+	}
+	return n, nil
+}
+
+func (c *Cache) variantPath(key string, size int) string {
+	name := key + "_full"
+	if size != SizeFull {
+		name = fmt.Sprintf("%s_%d.jpg", key, size)
+	}
+	// Shard by prefix to keep directories small on large libraries
+	return filepath.Join(c.dir, key[:2], name)
+}
+
+// Has reports whether the original image for key is cached
+func (c *Cache) Has(key string) bool {
+	_, err := os.Stat(c.variantPath(key, SizeFull))
+	return err == nil
+}
+
+// Store writes the original image and every resized variant.
+// Images that cannot be decoded are kept as the original only.
+func (c *Cache) Store(key string, original []byte) error {
+	if len(original) == 0 {
+		return fmt.Errorf("empty thumbnail")
+	}
+	if err := os.MkdirAll(filepath.Dir(c.variantPath(key, SizeFull)), 0755); err != nil {
+		return err
+	}
+
+	img, _, decodeErr := image.Decode(bytes.NewReader(original))
+	if decodeErr == nil {
+		for _, size := range Sizes {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, resize(img, size), &jpeg.Options{Quality: 85}); err != nil {
+				return err
+			}
+			if err := writeFileAtomic(c.variantPath(key, size), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Written last: Has() only reports complete entries
+	return writeFileAtomic(c.variantPath(key, SizeFull), original)
+}
+
+// Path returns the file of the smallest cached variant that is at least size pixels,
+// falling back to the original when no variant is large enough (or none could be generated).
+func (c *Cache) Path(key string, size int) (string, error) {
+	if size != SizeFull {
+		for _, s := range Sizes {
+			if s < size {
+				continue
+			}
+			p := c.variantPath(key, s)
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			}
+			break
+		}
+	}
+	p := c.variantPath(key, SizeFull)
+	if _, err := os.Stat(p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// Remove deletes every cached variant of key
+func (c *Cache) Remove(key string) {
+	os.Remove(c.variantPath(key, SizeFull))
+	for _, size := range Sizes {
+		os.Remove(c.variantPath(key, size))
+	}
+}
+
+// resize scales img down so its longest edge is at most size, averaging the source pixels of each
+// destination pixel. Transparency is flattened onto white since variants are stored as JPEG.
+func resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Over)
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					bl += uint32(src.Pix[off+2])
+					a += uint32(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			off := dst.PixOffset(x, y)
+			dst.Pix[off] = uint8(r / n)
+			dst.Pix[off+1] = uint8(g / n)
+			dst.Pix[off+2] = uint8(bl / n)
+			dst.Pix[off+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// writeFileAtomic uses a unique temp file since a scan and an API request may store the same key concurrently
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
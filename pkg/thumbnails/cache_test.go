@@ -0,0 +1,100 @@
+package thumbnails
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"yavam/pkg/models"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeSize(t *testing.T, path string) (int, int) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Variant is not a JPEG: %v", err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+func TestCache_StoreVariants(t *testing.T) {
+	c := NewCache(t.TempDir())
+	key := Key(models.VarPackage{FileName: "Creator.Pkg.1.var", Size: 100})
+	original := testPNG(t, 800, 400)
+
+	if c.Has(key) {
+		t.Fatal("Expected empty cache")
+	}
+	if err := c.Store(key, original); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !c.Has(key) {
+		t.Fatal("Expected cached entry after Store")
+	}
+
+	small, _ := c.Path(key, 160)
+	if w, h := decodeSize(t, small); w != 160 || h != 80 {
+		t.Errorf("Expected 160x80, got %dx%d", w, h)
+	}
+	// Requests between variants get the next larger one
+	medium, _ := c.Path(key, 200)
+	if w, h := decodeSize(t, medium); w != 320 || h != 160 {
+		t.Errorf("Expected 320x160, got %dx%d", w, h)
+	}
+	// Larger than every variant serves the original
+	full, _ := c.Path(key, 1024)
+	data, _ := os.ReadFile(full)
+	if !bytes.Equal(data, original) {
+		t.Error("Expected original bytes for oversized request")
+	}
+}
+
+func TestCache_UndecodableKeepsOriginal(t *testing.T) {
+	c := NewCache(t.TempDir())
+	key := Key(models.VarPackage{FileName: "Creator.Pkg.1.var", Size: 100})
+
+	if err := c.Store(key, []byte("not an image")); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	p, err := c.Path(key, 160)
+	if err != nil {
+		t.Fatalf("Expected fallback to original: %v", err)
+	}
+	if filepath.Ext(p) == ".jpg" {
+		t.Errorf("Expected original, got variant %s", p)
+	}
+}
+
+func TestKey_IgnoresEnabledState(t *testing.T) {
+	enabled := Key(models.VarPackage{FileName: "Creator.Pkg.1.var", Size: 100})
+	disabled := Key(models.VarPackage{FileName: "Creator.Pkg.1.var.disabled", Size: 100})
+	if enabled != disabled {
+		t.Error("Toggling a package must keep its thumbnail key")
+	}
+	if enabled == Key(models.VarPackage{FileName: "Creator.Pkg.1.var", Size: 101}) {
+		t.Error("Different content must produce a different key")
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader fails once ctx is done, so a long copy stops between two reads
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// CopyContext is io.Copy that gives up with ctx.Err() once ctx is cancelled
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, contextReader{ctx: ctx, r: src})
+}
@@ -0,0 +1,381 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SalvageLoss is an entry SalvageZip could not recover
+type SalvageLoss struct {
+	Name   string `json:"name"` // Empty when even the header was unreadable
+	Offset int64  `json:"offset"`
+	Reason string `json:"reason"`
+}
+
+// SalvageReport lists what SalvageZip recovered and what was lost
+type SalvageReport struct {
+	Source               string        `json:"source"`
+	Output               string        `json:"output,omitempty"`
+	UsedCentralDirectory bool          `json:"usedCentralDirectory"` // false: entries were found by scanning local headers
+	Recovered            []string      `json:"recovered"`
+	Lost                 []SalvageLoss `json:"lost"`
+}
+
+var localHeaderSignature = []byte("PK\x03\x04")
+
+const (
+	localHeaderLen      = 30
+	flagDataDescriptor  = 0x8
+	zip64ExtraID        = 0x0001
+	signatureScanWindow = 1024 * 1024
+)
+
+// SalvageZip rebuilds a valid zip at dst from the entries of src that still pass their CRC check.
+// A readable central directory is used when present; otherwise (truncated download, damaged end record)
+// the file is scanned for local file headers. Entries are copied without recompression.
+// src is only read. dst must not exist; nothing is written if no entry can be recovered.
+func SalvageZip(src, dst string) (*SalvageReport, error) {
+	if strings.EqualFold(filepath.Clean(src), filepath.Clean(dst)) {
+		return nil, fmt.Errorf("salvage output must not replace the original")
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil, fmt.Errorf("salvage output already exists: %s", dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".salvage-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	out := zip.NewWriter(tmp)
+
+	report := &SalvageReport{Source: src, Recovered: []string{}, Lost: []SalvageLoss{}}
+	if r, err := zip.NewReader(in, info.Size()); err == nil {
+		report.UsedCentralDirectory = true
+		err = salvageFromDirectory(r, out, report)
+	} else {
+		err = salvageFromLocalHeaders(in, info.Size(), out, report)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && len(report.Recovered) == 0 {
+		err = fmt.Errorf("no entries could be recovered from %s", filepath.Base(src))
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return report, err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return report, err
+	}
+	report.Output = dst
+	return report, nil
+}
+
+// salvageFromDirectory copies every entry listed in the central directory whose data verifies.
+// Errors returned are write errors on the output; damaged entries are recorded as lost.
+func salvageFromDirectory(r *zip.Reader, out *zip.Writer, report *SalvageReport) error {
+	seen := make(map[string]bool)
+	for _, f := range r.File {
+		offset, _ := f.DataOffset()
+		if seen[f.Name] {
+			report.Lost = append(report.Lost, SalvageLoss{Name: f.Name, Offset: offset, Reason: "duplicate entry"})
+			continue
+		}
+		if err := readEntry(f); err != nil {
+			report.Lost = append(report.Lost, SalvageLoss{Name: f.Name, Offset: offset, Reason: err.Error()})
+			continue
+		}
+
+		raw, err := f.OpenRaw()
+		if err != nil {
+			report.Lost = append(report.Lost, SalvageLoss{Name: f.Name, Offset: offset, Reason: err.Error()})
+			continue
+		}
+		header := f.FileHeader
+		w, err := out.CreateRaw(&header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return err
+		}
+		seen[f.Name] = true
+		report.Recovered = append(report.Recovered, f.Name)
+	}
+	return nil
+}
+
+// readEntry reads an entry to the end, which makes archive/zip verify its CRC32
+func readEntry(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(io.Discard, rc)
+	return err
+}
+
+type localHeader struct {
+	name       string
+	flags      uint16
+	method     uint16
+	modTime    uint16
+	modDate    uint16
+	crc        uint32
+	compSize   uint64
+	uncompSize uint64
+	dataStart  int64
+}
+
+// salvageFromLocalHeaders walks the file from signature to signature. After a verified entry the scan
+// continues behind its data, after a damaged one right behind its signature.
+func salvageFromLocalHeaders(in io.ReaderAt, size int64, out *zip.Writer, report *SalvageReport) error {
+	seen := make(map[string]bool)
+	offset := int64(0)
+	for {
+		pos, ok := findSignature(in, offset, size, localHeaderSignature)
+		if !ok {
+			return nil
+		}
+		offset = pos + int64(len(localHeaderSignature))
+
+		h, err := readLocalHeader(in, pos, size)
+		if err != nil {
+			report.Lost = append(report.Lost, SalvageLoss{Offset: pos, Reason: err.Error()})
+			continue
+		}
+		if seen[h.name] {
+			report.Lost = append(report.Lost, SalvageLoss{Name: h.name, Offset: pos, Reason: "duplicate entry"})
+			continue
+		}
+
+		if err := verifyLocalEntry(in, size, h); err != nil {
+			report.Lost = append(report.Lost, SalvageLoss{Name: h.name, Offset: pos, Reason: err.Error()})
+			continue
+		}
+
+		header := &zip.FileHeader{
+			Name:               h.name,
+			Method:             h.method,
+			CRC32:              h.crc,
+			CompressedSize64:   h.compSize,
+			UncompressedSize64: h.uncompSize,
+			ModifiedTime:       h.modTime,
+			ModifiedDate:       h.modDate,
+		}
+		w, err := out.CreateRaw(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, io.NewSectionReader(in, h.dataStart, int64(h.compSize))); err != nil {
+			return err
+		}
+		seen[h.name] = true
+		report.Recovered = append(report.Recovered, h.name)
+		offset = h.dataStart + int64(h.compSize)
+	}
+}
+
+// readLocalHeader parses the local file header at pos, including zip64 sizes
+func readLocalHeader(in io.ReaderAt, pos, size int64) (*localHeader, error) {
+	buf := make([]byte, localHeaderLen)
+	if _, err := in.ReadAt(buf, pos); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	le := binary.LittleEndian
+	h := &localHeader{
+		flags:      le.Uint16(buf[6:]),
+		method:     le.Uint16(buf[8:]),
+		modTime:    le.Uint16(buf[10:]),
+		modDate:    le.Uint16(buf[12:]),
+		crc:        le.Uint32(buf[14:]),
+		compSize:   uint64(le.Uint32(buf[18:])),
+		uncompSize: uint64(le.Uint32(buf[22:])),
+	}
+	nameLen := int64(le.Uint16(buf[26:]))
+	extraLen := int64(le.Uint16(buf[28:]))
+
+	h.dataStart = pos + localHeaderLen + nameLen + extraLen
+	if h.dataStart > size {
+		return nil, fmt.Errorf("truncated header")
+	}
+	nameAndExtra := make([]byte, nameLen+extraLen)
+	if _, err := in.ReadAt(nameAndExtra, pos+localHeaderLen); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	h.name = string(nameAndExtra[:nameLen])
+	if h.name == "" {
+		return nil, fmt.Errorf("bad header: empty name")
+	}
+
+	if h.compSize == 0xFFFFFFFF || h.uncompSize == 0xFFFFFFFF {
+		readZip64Sizes(nameAndExtra[nameLen:], h)
+	}
+	return h, nil
+}
+
+// readZip64Sizes takes the real sizes from the zip64 extra field (uncompressed first, then compressed)
+func readZip64Sizes(extra []byte, h *localHeader) {
+	le := binary.LittleEndian
+	for len(extra) >= 4 {
+		id := le.Uint16(extra)
+		n := int(le.Uint16(extra[2:]))
+		extra = extra[4:]
+		if n > len(extra) {
+			return
+		}
+		if id == zip64ExtraID {
+			field := extra[:n]
+			if h.uncompSize == 0xFFFFFFFF && len(field) >= 8 {
+				h.uncompSize = le.Uint64(field)
+				field = field[8:]
+			}
+			if h.compSize == 0xFFFFFFFF && len(field) >= 8 {
+				h.compSize = le.Uint64(field)
+			}
+			return
+		}
+		extra = extra[n:]
+	}
+}
+
+// verifyLocalEntry decompresses an entry and checks size and CRC32.
+// Entries written with a data descriptor carry no sizes in the local header; for deflated data the end of
+// the stream is found by decompressing it, and the CRC is read from the descriptor that follows.
+func verifyLocalEntry(in io.ReaderAt, size int64, h *localHeader) error {
+	if strings.HasSuffix(h.name, "/") && h.compSize == 0 {
+		return nil // Directory
+	}
+	if h.method != zip.Store && h.method != zip.Deflate {
+		return fmt.Errorf("unsupported compression method %d", h.method)
+	}
+
+	if h.flags&flagDataDescriptor != 0 {
+		if h.method == zip.Store {
+			return fmt.Errorf("stored entry without sizes")
+		}
+		cr := &countingReader{r: bufio.NewReader(io.NewSectionReader(in, h.dataStart, size-h.dataStart))}
+		crc := crc32.NewIEEE()
+		n, err := io.Copy(crc, flate.NewReader(cr))
+		if err != nil {
+			return classifySalvageError(err)
+		}
+		h.compSize = uint64(cr.n)
+		h.uncompSize = uint64(n)
+
+		descriptor := make([]byte, 8)
+		if _, err := in.ReadAt(descriptor, h.dataStart+cr.n); err != nil {
+			return fmt.Errorf("truncated")
+		}
+		// The descriptor signature is optional
+		h.crc = binary.LittleEndian.Uint32(descriptor)
+		if bytes.Equal(descriptor[:4], []byte("PK\x07\x08")) {
+			h.crc = binary.LittleEndian.Uint32(descriptor[4:])
+		}
+		if crc.Sum32() != h.crc {
+			return fmt.Errorf("bad crc")
+		}
+		return nil
+	}
+
+	if h.dataStart+int64(h.compSize) > size {
+		return fmt.Errorf("truncated")
+	}
+	var r io.Reader = io.NewSectionReader(in, h.dataStart, int64(h.compSize))
+	if h.method == zip.Deflate {
+		r = flate.NewReader(r)
+	}
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(crc, r)
+	if err != nil {
+		return classifySalvageError(err)
+	}
+	if uint64(n) != h.uncompSize {
+		return fmt.Errorf("size mismatch")
+	}
+	if crc.Sum32() != h.crc {
+		return fmt.Errorf("bad crc")
+	}
+	return nil
+}
+
+func classifySalvageError(err error) error {
+	var corrupt flate.CorruptInputError
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("truncated")
+	case errors.As(err, &corrupt):
+		return fmt.Errorf("bad crc")
+	default:
+		return err
+	}
+}
+
+// countingReader counts the bytes flate consumes. It implements io.ByteReader, so flate reads exactly
+// up to the end of the compressed stream instead of buffering past it.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// findSignature returns the offset of the next occurrence of sig at or after from
+func findSignature(in io.ReaderAt, from, size int64, sig []byte) (int64, bool) {
+	buf := make([]byte, signatureScanWindow)
+	for from < size {
+		n, err := in.ReadAt(buf, from)
+		if n == 0 {
+			return 0, false
+		}
+		if i := bytes.Index(buf[:n], sig); i >= 0 {
+			return from + int64(i), true
+		}
+		if err != nil {
+			return 0, false
+		}
+		// Overlap so a signature split across windows is still found
+		from += int64(n - len(sig) + 1)
+	}
+	return 0, false
+}
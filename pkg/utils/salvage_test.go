@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildSalvageZip writes meta.json and two textures. Deflated entries are written with data descriptors
+// (as zip.Writer does), the stored one raw with its sizes in the local header.
+func buildSalvageZip(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	stored := bytes.Repeat([]byte("stored-texture-"), 2000)
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	meta, _ := w.Create("meta.json")
+	meta.Write([]byte(`{"creatorName":"Creator","packageName":"Pkg"}`))
+	first, _ := w.Create("Custom/Atom/Person/Textures/a.jpg")
+	first.Write(bytes.Repeat([]byte("deflated-texture-"), 2000))
+	second, _ := w.CreateRaw(&zip.FileHeader{
+		Name:               "Custom/Atom/Person/Textures/b.jpg",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(stored),
+		CompressedSize64:   uint64(len(stored)),
+		UncompressedSize64: uint64(len(stored)),
+	})
+	second.Write(stored)
+	w.Close()
+	return buf.Bytes(), stored
+}
+
+func readAllEntries(t *testing.T, path string) []string {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Salvaged zip is not valid: %v", err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, rc); err != nil {
+			t.Errorf("%s does not verify: %v", f.Name, err)
+		}
+		rc.Close()
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestSalvageZip_TruncatedDownload(t *testing.T) {
+	dir := t.TempDir()
+	data, stored := buildSalvageZip(t)
+
+	// Cut the file inside the stored entry: the central directory and the last entry are gone
+	cut := data[:bytes.Index(data, stored)+len(stored)/2]
+	src := filepath.Join(dir, "Creator.Pkg.1.var")
+	os.WriteFile(src, cut, 0644)
+
+	dst := filepath.Join(dir, "out", "Creator.Pkg.1.var")
+	os.Mkdir(filepath.Dir(dst), 0755)
+	report, err := SalvageZip(src, dst)
+	if err != nil {
+		t.Fatalf("SalvageZip failed: %v", err)
+	}
+
+	if report.UsedCentralDirectory {
+		t.Error("Expected a local header scan for a truncated file")
+	}
+	names := readAllEntries(t, dst)
+	if len(names) != 2 || names[0] != "meta.json" || names[1] != "Custom/Atom/Person/Textures/a.jpg" {
+		t.Errorf("Unexpected recovered entries %v", names)
+	}
+	if len(report.Lost) != 1 || report.Lost[0].Name != "Custom/Atom/Person/Textures/b.jpg" || report.Lost[0].Reason != "truncated" {
+		t.Errorf("Unexpected losses %+v", report.Lost)
+	}
+
+	// The original stays untouched
+	if after, _ := os.ReadFile(src); !bytes.Equal(after, cut) {
+		t.Error("Original file was modified")
+	}
+}
+
+func TestSalvageZip_BitRotWithDirectory(t *testing.T) {
+	dir := t.TempDir()
+	data, stored := buildSalvageZip(t)
+	data[bytes.Index(data, stored)+10] ^= 0xFF
+	src := filepath.Join(dir, "Creator.Pkg.1.var")
+	os.WriteFile(src, data, 0644)
+
+	dst := filepath.Join(dir, "Creator.Pkg.1.salvaged.zip")
+	report, err := SalvageZip(src, dst)
+	if err != nil {
+		t.Fatalf("SalvageZip failed: %v", err)
+	}
+	if !report.UsedCentralDirectory {
+		t.Error("Expected the intact central directory to be used")
+	}
+	if names := readAllEntries(t, dst); len(names) != 2 {
+		t.Errorf("Expected 2 recovered entries, got %v", names)
+	}
+	if len(report.Lost) != 1 || report.Lost[0].Name != "Custom/Atom/Person/Textures/b.jpg" {
+		t.Errorf("Unexpected losses %+v", report.Lost)
+	}
+}
+
+func TestSalvageZip_Refusals(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "Creator.Pkg.1.var")
+	os.WriteFile(src, []byte("nothing recoverable here"), 0644)
+
+	if _, err := SalvageZip(src, src); err == nil {
+		t.Error("Expected salvaging onto the original to be refused")
+	}
+	dst := filepath.Join(dir, "out.var")
+	if _, err := SalvageZip(src, dst); err == nil {
+		t.Error("Expected an error when nothing can be recovered")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("No output must be written when nothing was recovered")
+	}
+}
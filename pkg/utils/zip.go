@@ -2,6 +2,7 @@ package utils
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -48,6 +49,11 @@ func ZipDirectory(source, target string) error {
 			return nil
 		}
 
+		// Exclude rebuildable caches (package index, thumbnails)
+		if info.IsDir() && info.Name() == "cache" && filepath.Dir(path) == filepath.Clean(source) {
+			return filepath.SkipDir
+		}
+
 		header, err := zip.FileInfoHeader(info)
 		if err != nil {
 			return err
@@ -147,3 +153,66 @@ func UnzipDirectory(source, target string) error {
 	}
 	return nil
 }
+
+// StreamZip writes the given files into a zip on w without a temp file.
+// Entries are stored uncompressed: .var packages are zips already, deflating them again only costs CPU.
+// Files are placed at the archive root, so two files with the same name are an error (checked
+// before anything is written).
+// onProgress (optional) is called after each file has been added. Cancelling ctx stops the copy
+// and returns ctx.Err(), leaving a truncated archive on w.
+func StreamZip(ctx context.Context, w io.Writer, files []string, onProgress func(current, total int, name string)) error {
+	seen := make(map[string]string)
+	for _, path := range files {
+		key := strings.ToLower(filepath.Base(path))
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("%s and %s have the same name", other, path)
+		}
+		seen[key] = path
+	}
+
+	archive := zip.NewWriter(w)
+	for i, path := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name := filepath.Base(path)
+
+		if err := addStoredFile(ctx, archive, path, name); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			archive.Close()
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		if onProgress != nil {
+			onProgress(i+1, len(files), name)
+		}
+	}
+	return archive.Close()
+}
+
+func addStoredFile(ctx context.Context, archive *zip.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Store
+
+	writer, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = CopyContext(ctx, writer, f)
+	return err
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"archive/zip"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -100,3 +103,84 @@ func TestUnzipSecurity(t *testing.T) {
 		t.Error("Expected error for Zip Slip attempt, but got nil")
 	}
 }
+
+func TestStreamZip(t *testing.T) {
+	tempDir := t.TempDir()
+	libA := filepath.Join(tempDir, "A")
+	libB := filepath.Join(tempDir, "B")
+	os.Mkdir(libA, 0755)
+	os.Mkdir(libB, 0755)
+
+	first := filepath.Join(libA, "Creator.Pkg.1.var")
+	second := filepath.Join(libA, "Creator.Other.2.var")
+	dup := filepath.Join(libB, "Creator.Pkg.1.var")
+	os.WriteFile(first, []byte("first"), 0644)
+	os.WriteFile(second, []byte("second"), 0644)
+	os.WriteFile(dup, []byte("copy"), 0644)
+
+	out := filepath.Join(tempDir, "bundle.zip")
+	f, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries share the archive root, a second file with the same name is refused instead of dropped
+	if err := StreamZip(context.Background(), io.Discard, []string{first, second, dup}, nil); err == nil {
+		t.Fatal("Expected an error for two files with the same name")
+	}
+	if err := StreamZip(context.Background(), f, []string{first, second}, nil); err != nil {
+		t.Fatalf("StreamZip failed: %v", err)
+	}
+	f.Close()
+
+	r, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if len(r.File) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(r.File))
+	}
+	for _, zf := range r.File {
+		if zf.Method != zip.Store {
+			t.Errorf("%s: expected store method, got %d", zf.Name, zf.Method)
+		}
+	}
+	if r.File[0].Name != "Creator.Pkg.1.var" {
+		t.Errorf("Expected entry at zip root, got %s", r.File[0].Name)
+	}
+	rc, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, _ := rc.Read(buf)
+	rc.Close()
+	if string(buf[:n]) != "first" {
+		t.Errorf("Expected the first file's content, got %q", buf[:n])
+	}
+}
+
+func TestStreamZip_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"Creator.A.1.var", "Creator.B.1.var"} {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte(name), 0644)
+		files = append(files, p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var added int
+	err := StreamZip(ctx, io.Discard, files, func(current, _ int, _ string) {
+		added = current
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if added != 1 {
+		t.Errorf("Expected to stop after the first file, added %d", added)
+	}
+}
@@ -0,0 +1,430 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"yavam/pkg/models"
+	"yavam/pkg/scanner"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Event names, shared by the Wails runtime and the SSE stream
+const (
+	EventAdded   = "package:added"
+	EventRemoved = "package:removed"
+	EventChanged = "package:changed"
+)
+
+// Event describes a single package that appeared, vanished or changed on disk
+type Event struct {
+	Type        string `json:"type"`
+	Path        string `json:"path"`
+	LibraryPath string `json:"libraryPath"`
+	// Package is filled in by the receiver for added/changed events so clients don't have to rescan
+	Package *models.VarPackage `json:"package,omitempty"`
+}
+
+// Name and Paths let watcher events travel on the application event bus (see events.Change)
+func (e Event) Name() string { return e.Type }
+
+func (e Event) Paths() []string { return []string{e.Path} }
+
+// Options tunes how libraries are watched
+type Options struct {
+	// Debounce is the quiet period after the last filesystem notification before changes are reported.
+	// Copying a large .var produces many writes; only the final state is reported.
+	Debounce time.Duration
+	// PollInterval is how often libraries without native notifications are re-walked
+	PollInterval time.Duration
+	// ForcePolling disables native notifications entirely (e.g. mapped network drives)
+	ForcePolling bool
+}
+
+// DefaultOptions returns the settings used by the application
+func DefaultOptions() Options {
+	return Options{
+		Debounce:     750 * time.Millisecond,
+		PollInterval: 30 * time.Second,
+	}
+}
+
+// Watcher keeps track of every configured library and reports package level changes
+type Watcher struct {
+	mu      sync.Mutex
+	opts    Options
+	onEvent func(Event)
+	libs    map[string]*libraryWatch
+}
+
+// New creates a watcher. onEvent is called from background goroutines.
+func New(opts Options, onEvent func(Event)) *Watcher {
+	defaults := DefaultOptions()
+	if opts.Debounce <= 0 {
+		opts.Debounce = defaults.Debounce
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaults.PollInterval
+	}
+	return &Watcher{
+		opts:    opts,
+		onEvent: onEvent,
+		libs:    make(map[string]*libraryWatch),
+	}
+}
+
+// SetLibraries starts watching new libraries and stops watching removed ones
+func (w *Watcher) SetLibraries(paths []string) {
+	w.mu.Lock()
+
+	wanted := make(map[string]bool)
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		clean := filepath.Clean(p)
+		wanted[clean] = true
+		if _, ok := w.libs[clean]; ok {
+			continue
+		}
+		lw := newLibraryWatch(clean, w.opts, w.emit)
+		w.libs[clean] = lw
+		// The initial walk takes a while on large or network libraries, don't hold up the caller
+		go w.run(lw)
+	}
+
+	var removed []*libraryWatch
+	for path, lw := range w.libs {
+		if !wanted[path] {
+			removed = append(removed, lw)
+			delete(w.libs, path)
+		}
+	}
+	w.mu.Unlock()
+
+	// Waits for the watch goroutines, which must not hold up IsPolling or other callers
+	for _, lw := range removed {
+		lw.close()
+	}
+}
+
+// run watches one library until it is closed. A library that cannot be read is dropped again.
+func (w *Watcher) run(lw *libraryWatch) {
+	if err := lw.run(); err != nil {
+		fmt.Printf("[Watcher] Failed to watch %s: %v\n", lw.root, err)
+		w.mu.Lock()
+		if w.libs[lw.root] == lw {
+			delete(w.libs, lw.root)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// IsPolling reports whether a library is watched by polling instead of native notifications.
+// Libraries still taking their initial snapshot count as polling.
+func (w *Watcher) IsPolling(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lw, ok := w.libs[filepath.Clean(path)]
+	if !ok {
+		return false
+	}
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.fsw == nil
+}
+
+// Close stops watching every library
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	libs := w.libs
+	w.libs = make(map[string]*libraryWatch)
+	w.mu.Unlock()
+
+	for _, lw := range libs {
+		lw.close()
+	}
+}
+
+func (w *Watcher) emit(e Event) {
+	if w.onEvent != nil {
+		w.onEvent(e)
+	}
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// libraryWatch tracks one library root, either through fsnotify or by polling
+type libraryWatch struct {
+	root string
+	opts Options
+	emit func(Event)
+
+	mu      sync.Mutex
+	known   map[string]fileState
+	pending map[string]bool
+	timer   *time.Timer
+
+	fsw   *fsnotify.Watcher // nil when polling
+	ready chan struct{}     // Closed once the initial snapshot is taken (or failed)
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newLibraryWatch(root string, opts Options, emit func(Event)) *libraryWatch {
+	return &libraryWatch{
+		root:    root,
+		opts:    opts,
+		emit:    emit,
+		known:   make(map[string]fileState),
+		pending: make(map[string]bool),
+		ready:   make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// run takes the initial snapshot and then reports changes until the watch is closed
+func (lw *libraryWatch) run() error {
+	defer close(lw.done)
+
+	snapshot, err := walk(lw.root, lw.stop)
+	if err != nil {
+		close(lw.ready)
+		if err == errStopped {
+			return nil
+		}
+		return err
+	}
+
+	var fsw *fsnotify.Watcher
+	if !lw.opts.ForcePolling && !isNetworkPath(lw.root) {
+		if f, err := fsnotify.NewWatcher(); err == nil {
+			if err := addRecursive(f, lw.root, lw.stop); err == nil {
+				fsw = f
+			} else if err == errStopped {
+				f.Close()
+				close(lw.ready)
+				return nil
+			} else {
+				// Typically an SMB share or an unsupported filesystem
+				fmt.Printf("[Watcher] Native notifications unavailable for %s, polling instead: %v\n", lw.root, err)
+				f.Close()
+			}
+		}
+	}
+
+	lw.mu.Lock()
+	lw.known = snapshot
+	lw.fsw = fsw
+	lw.mu.Unlock()
+	close(lw.ready)
+
+	if fsw != nil {
+		lw.runNative(fsw)
+	} else {
+		lw.runPolling()
+	}
+	return nil
+}
+
+// close stops the watch, also while the initial snapshot is still being taken
+func (lw *libraryWatch) close() {
+	close(lw.stop)
+	<-lw.done
+
+	lw.mu.Lock()
+	if lw.timer != nil {
+		lw.timer.Stop()
+	}
+	lw.mu.Unlock()
+}
+
+func (lw *libraryWatch) runNative(fsw *fsnotify.Watcher) {
+	defer fsw.Close()
+	for {
+		select {
+		case <-lw.stop:
+			return
+		case ev, ok := <-fsw.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					addRecursive(fsw, ev.Name, lw.stop)
+				}
+			}
+			lw.markPending(ev.Name)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return
+			}
+			// Usually a queue overflow: we lost track of what happened, so compare the whole tree
+			fmt.Printf("[Watcher] Notification error on %s, resyncing: %v\n", lw.root, err)
+			lw.markPending(lw.root)
+		}
+	}
+}
+
+func (lw *libraryWatch) runPolling() {
+	ticker := time.NewTicker(lw.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lw.stop:
+			return
+		case <-ticker.C:
+			lw.reconcile(lw.root)
+		}
+	}
+}
+
+// markPending records a changed path and (re)starts the debounce timer
+func (lw *libraryWatch) markPending(path string) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	lw.pending[path] = true
+	if lw.timer == nil {
+		lw.timer = time.AfterFunc(lw.opts.Debounce, lw.flush)
+	} else {
+		lw.timer.Reset(lw.opts.Debounce)
+	}
+}
+
+func (lw *libraryWatch) flush() {
+	lw.mu.Lock()
+	paths := make([]string, 0, len(lw.pending))
+	for p := range lw.pending {
+		paths = append(paths, p)
+	}
+	lw.pending = make(map[string]bool)
+	lw.mu.Unlock()
+
+	select {
+	case <-lw.stop:
+		return
+	default:
+	}
+
+	for _, p := range paths {
+		lw.reconcile(p)
+	}
+}
+
+// reconcile compares the disk state below path with what we knew and emits the differences
+func (lw *libraryWatch) reconcile(path string) {
+	current := make(map[string]fileState)
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			if snapshot, err := walk(path, lw.stop); err == nil {
+				current = snapshot
+			}
+		} else if scanner.IsPackageFile(info.Name()) {
+			current[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		} else {
+			return // Not a package, nothing to report
+		}
+	}
+
+	var events []Event
+	lw.mu.Lock()
+	for p, old := range lw.known {
+		if !within(p, path) {
+			continue
+		}
+		now, exists := current[p]
+		if !exists {
+			delete(lw.known, p)
+			events = append(events, Event{Type: EventRemoved, Path: p, LibraryPath: lw.root})
+		} else if now != old {
+			lw.known[p] = now
+			events = append(events, Event{Type: EventChanged, Path: p, LibraryPath: lw.root})
+		}
+	}
+	for p, now := range current {
+		if _, exists := lw.known[p]; !exists {
+			lw.known[p] = now
+			events = append(events, Event{Type: EventAdded, Path: p, LibraryPath: lw.root})
+		}
+	}
+	lw.mu.Unlock()
+
+	for _, e := range events {
+		lw.emit(e)
+	}
+}
+
+// errStopped ends a walk because the library watch was closed
+var errStopped = errors.New("watch closed")
+
+// stopped reports whether stop was closed
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// walk collects the state of every package file below root. Closing stop aborts with errStopped,
+// a slow network library must not delay closing its watch until the walk is done.
+func walk(root string, stop <-chan struct{}) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if stopped(stop) {
+			return errStopped
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // Skip errors for sub-items
+		}
+		if !info.IsDir() && scanner.IsPackageFile(info.Name()) {
+			states[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return states, err
+}
+
+// addRecursive registers root and every directory below it (fsnotify does not recurse on its own).
+// Closing stop aborts with errStopped.
+func addRecursive(fsw *fsnotify.Watcher, root string, stop <-chan struct{}) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if stopped(stop) {
+			return errStopped
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return fsw.Add(path)
+		}
+		return nil
+	})
+}
+
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// isNetworkPath detects UNC paths (\\server\share), where native notifications are unreliable
+func isNetworkPath(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
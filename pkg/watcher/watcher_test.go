@@ -0,0 +1,176 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func collect(t *testing.T, opts Options, root string) (*Watcher, chan Event) {
+	t.Helper()
+	events := make(chan Event, 100)
+	w := New(opts, func(e Event) { events <- e })
+	w.SetLibraries([]string{root})
+	t.Cleanup(w.Close)
+	waitReady(w)
+	return w, events
+}
+
+// waitReady blocks until every library took its initial snapshot
+func waitReady(w *Watcher) {
+	w.mu.Lock()
+	libs := make([]*libraryWatch, 0, len(w.libs))
+	for _, lw := range w.libs {
+		libs = append(libs, lw)
+	}
+	w.mu.Unlock()
+	for _, lw := range libs {
+		<-lw.ready
+	}
+}
+
+// expectEvents waits until every expected (type, path) pair was reported, in any order
+func expectEvents(t *testing.T, events chan Event, expected ...Event) {
+	t.Helper()
+	missing := make(map[Event]bool)
+	for _, e := range expected {
+		missing[e] = true
+	}
+	deadline := time.After(5 * time.Second)
+	for len(missing) > 0 {
+		select {
+		case e := <-events:
+			delete(missing, Event{Type: e.Type, Path: e.Path})
+		case <-deadline:
+			t.Fatalf("Timed out waiting for events: %v", missing)
+		}
+	}
+}
+
+func runLifecycle(t *testing.T, opts Options) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "Creator.Old.1.var")
+	os.WriteFile(existing, []byte("old"), 0644)
+
+	_, events := collect(t, opts, root)
+
+	// Added (including in a new sub folder)
+	sub := filepath.Join(root, "Sub")
+	os.Mkdir(sub, 0755)
+	added := filepath.Join(sub, "Creator.New.1.var")
+	os.WriteFile(added, []byte("new"), 0644)
+	expectEvents(t, events, Event{Type: EventAdded, Path: added})
+
+	// Changed
+	os.WriteFile(existing, []byte("modified content"), 0644)
+	expectEvents(t, events, Event{Type: EventChanged, Path: existing})
+
+	// Renamed (removed + added)
+	disabled := existing + ".disabled"
+	os.Rename(existing, disabled)
+	expectEvents(t, events,
+		Event{Type: EventRemoved, Path: existing},
+		Event{Type: EventAdded, Path: disabled},
+	)
+
+	// Removed
+	os.Remove(added)
+	expectEvents(t, events, Event{Type: EventRemoved, Path: added})
+}
+
+func TestWatcher_Native(t *testing.T) {
+	runLifecycle(t, Options{Debounce: 50 * time.Millisecond})
+}
+
+func TestWatcher_Polling(t *testing.T) {
+	runLifecycle(t, Options{Debounce: 50 * time.Millisecond, PollInterval: 100 * time.Millisecond, ForcePolling: true})
+}
+
+func TestWatcher_IgnoresNonPackages(t *testing.T) {
+	root := t.TempDir()
+	_, events := collect(t, Options{Debounce: 50 * time.Millisecond}, root)
+
+	os.WriteFile(filepath.Join(root, "readme.txt"), []byte("hello"), 0644)
+	pkg := filepath.Join(root, "Creator.Pkg.1.var")
+	os.WriteFile(pkg, []byte("pkg"), 0644)
+
+	select {
+	case e := <-events:
+		if e.Path != pkg {
+			t.Errorf("Expected only package events, got %s for %s", e.Type, e.Path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for package event")
+	}
+}
+
+func TestWatcher_SetLibrariesStopsRemoved(t *testing.T) {
+	root := t.TempDir()
+	w, events := collect(t, Options{Debounce: 50 * time.Millisecond}, root)
+
+	w.SetLibraries(nil)
+	os.WriteFile(filepath.Join(root, "Creator.Pkg.1.var"), []byte("pkg"), 0644)
+
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event after library was removed: %s %s", e.Type, e.Path)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatcher_DropsUnreadableLibraries(t *testing.T) {
+	w := New(Options{Debounce: 50 * time.Millisecond}, nil)
+	t.Cleanup(w.Close)
+	missing := filepath.Join(t.TempDir(), "missing")
+	w.SetLibraries([]string{missing})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.mu.Lock()
+		_, watched := w.libs[missing]
+		w.mu.Unlock()
+		if !watched {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Missing library was not dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher_CloseDuringInitialWalk(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		os.WriteFile(filepath.Join(root, fmt.Sprintf("Creator.Pkg%d.1.var", i)), []byte("pkg"), 0644)
+	}
+
+	w := New(Options{Debounce: 50 * time.Millisecond}, nil)
+	w.SetLibraries([]string{root})
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close hung while the initial snapshot was being taken")
+	}
+}
+
+func TestWalk_AbortsWhenStopped(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "Creator.Pkg.1.var"), []byte("pkg"), 0644)
+	stop := make(chan struct{})
+	close(stop)
+
+	if _, err := walk(root, stop); err != errStopped {
+		t.Errorf("Expected walk to abort, got %v", err)
+	}
+	if err := addRecursive(nil, root, stop); err != errStopped {
+		t.Errorf("Expected addRecursive to abort, got %v", err)
+	}
+}